@@ -3,9 +3,12 @@ package ai
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 	"sync"
@@ -14,12 +17,15 @@ import (
 	"idongivaflyinfa/cache"
 	"idongivaflyinfa/config"
 	"idongivaflyinfa/models"
+	"idongivaflyinfa/validation"
 )
 
 type AIService struct {
 	apiKey               string
 	modelName            string
 	cache                *cache.Cache
+	inflight             inflightGroup
+	cacheStats           cacheStats
 	httpClient           *http.Client
 	httpClientLongTimeout *http.Client // For operations that may take longer (HTML generation)
 	apiURL               string
@@ -124,8 +130,12 @@ func (a *AIService) callDashScopeAPIWithClient(ctx context.Context, messages []D
 		if attempt > 0 {
 			// Exponential backoff: 2s, 4s, 8s
 			delay := baseDelay * time.Duration(1<<uint(attempt-1))
-			fmt.Printf("Rate limit hit, retrying after %v (attempt %d/%d)\n", delay, attempt, maxRetries)
-			time.Sleep(delay)
+			log.Printf("[AI] Rate limit hit, retrying after %v (attempt %d/%d)", delay, attempt, maxRetries)
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(delay):
+			}
 			// Re-apply rate limiting after backoff
 			a.rateLimit()
 		}
@@ -138,11 +148,8 @@ func (a *AIService) callDashScopeAPIWithClient(ctx context.Context, messages []D
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.apiKey))
 		req.Header.Set("Content-Type", "application/json")
 		
-		// Debug: Print request details (remove in production)
 		if attempt == 0 {
-			fmt.Printf("Request URL: %s\n", a.apiURL)
-			fmt.Printf("Request Model: %s\n", a.modelName)
-			fmt.Printf("Request Body: %s\n", string(jsonData))
+			log.Printf("[AI] Request to %s, model %s, body %d bytes", a.apiURL, a.modelName, len(jsonData))
 		}
 
 		resp, err := client.Do(req)
@@ -162,10 +169,8 @@ func (a *AIService) callDashScopeAPIWithClient(ctx context.Context, messages []D
 			return "", fmt.Errorf("failed to read response: %w", err)
 		}
 
-		// Debug: Print response details
-		fmt.Printf("Response Status: %d\n", resp.StatusCode)
 		if resp.StatusCode != http.StatusOK {
-			fmt.Printf("Response Body: %s\n", string(body))
+			log.Printf("[AI] Response status %d: %s", resp.StatusCode, string(body))
 		}
 
 		// Handle rate limiting (429) with retry
@@ -178,7 +183,7 @@ func (a *AIService) callDashScopeAPIWithClient(ctx context.Context, messages []D
 					RequestID string `json:"request_id"`
 				}
 				if err := json.Unmarshal(body, &errorResp); err == nil {
-					fmt.Printf("Rate limit error: %s - %s (request_id: %s)\n", 
+					log.Printf("[AI] Rate limit error: %s - %s (request_id: %s)",
 						errorResp.Code, errorResp.Message, errorResp.RequestID)
 				}
 				continue // Retry with backoff
@@ -229,104 +234,153 @@ func (a *AIService) callDashScopeAPIWithClient(ctx context.Context, messages []D
 	return "", fmt.Errorf("max retries exceeded")
 }
 
-func (a *AIService) GenerateSQL(userPrompt string, sqlFiles []models.SQLFile) (string, error) {
-	// Check cache first
-	cacheKey := fmt.Sprintf("prompt:%s", userPrompt)
-	if cached, found := a.cache.Get(cacheKey); found {
-		return cached.(string), nil
+// codeFenceLanguages are the language hints the model puts after an opening fence. Any other
+// word on that line (e.g. "SELECT" or "WITH") is the start of the code and is kept.
+var codeFenceLanguages = map[string]bool{
+	"sql": true, "tsql": true, "mssql": true, "json": true, "html": true, "xml": true,
+	"css": true, "javascript": true, "js": true, "text": true, "plaintext": true,
+}
+
+// stripCodeFences unwraps the first markdown code block in s, dropping its language hint
+// (e.g. "```sql"). Prose before or after the block is discarded. If s contains no fence it
+// is returned trimmed.
+func stripCodeFences(s string, lang string) string {
+	s = strings.TrimSpace(s)
+	start := strings.Index(s, "```")
+	if start < 0 {
+		return s
+	}
+	body := s[start+3:]
+
+	// Drop the info string on the opening line ("sql", "JSON", ...)
+	if nl := strings.Index(body, "\n"); nl >= 0 {
+		hint := strings.TrimSpace(body[:nl])
+		if hint == "" || strings.EqualFold(hint, lang) || codeFenceLanguages[strings.ToLower(hint)] {
+			body = body[nl+1:]
+		}
+	} else if lang != "" && len(body) >= len(lang) && strings.EqualFold(body[:len(lang)], lang) {
+		body = body[len(lang):]
 	}
 
-	ctx := context.Background()
+	if end := strings.Index(body, "```"); end >= 0 {
+		body = body[:end]
+	}
+	return strings.TrimSpace(body)
+}
 
-	// Build prompt using helper
-	prompt := BuildSQLPrompt(userPrompt, sqlFiles)
+// ModelName returns the model used for generation calls
+func (a *AIService) ModelName() string {
+	return a.modelName
+}
 
-	messages := []DashScopeMessage{
-		{
-			Role:    "user",
-			Content: prompt,
-		},
+func (a *AIService) GenerateSQL(ctx context.Context, userPrompt string, sqlFiles []models.SQLFile) (string, error) {
+	// Check cache first
+	cacheKey := fmt.Sprintf("prompt:%s", userPrompt)
+	if cached, found := a.cachedResponse("GenerateSQL", cacheKey); found {
+		return cached, nil
 	}
 
-	fmt.Println("prompt:", prompt)
+	// Concurrent identical prompts share one upstream call
+	return a.inflight.do(ctx, cacheKey, func(ctx context.Context) (string, error) {
+		// Build prompt using helper
+		prompt := BuildSQLPrompt(userPrompt, sqlFiles)
 
-	response, err := a.callDashScopeAPI(ctx, messages)
-	if err != nil {
-		fmt.Println("error:", err)
-		return "", fmt.Errorf("failed to generate content: %w", err)
-	}
+		messages := []DashScopeMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		}
 
-	sql := strings.TrimSpace(response)
-	// Remove markdown code blocks if present
-	sql = strings.TrimPrefix(sql, "```sql")
-	sql = strings.TrimPrefix(sql, "```SQL")
-	sql = strings.TrimPrefix(sql, "```")
-	sql = strings.TrimSuffix(sql, "```")
-	sql = strings.TrimSpace(sql)
+		response, err := a.callDashScopeAPI(ctx, messages)
+		if err != nil {
+			log.Printf("[AI] GenerateSQL failed: %v", err)
+			return "", fmt.Errorf("failed to generate content: %w", err)
+		}
+
+		// Remove markdown code blocks if present
+		sql := stripCodeFences(response, "sql")
 
-	// Cache the result
-	a.cache.SetDefault(cacheKey, sql)
+		// Cache the result
+		a.cache.SetDefault(cacheKey, sql)
 
-	return sql, nil
+		return sql, nil
+	})
 }
 
-func (a *AIService) GenerateForm(userPrompt string) (string, error) {
+func (a *AIService) GenerateForm(ctx context.Context, userPrompt string) (string, error) {
 	// Check cache first
 	cacheKey := fmt.Sprintf("form_prompt:%s", userPrompt)
-	if cached, found := a.cache.Get(cacheKey); found {
-		return cached.(string), nil
+	if cached, found := a.cachedResponse("GenerateForm", cacheKey); found {
+		return cached, nil
 	}
 
-	ctx := context.Background()
+	// Concurrent identical prompts share one upstream call
+	return a.inflight.do(ctx, cacheKey, func(ctx context.Context) (string, error) {
+		// Sample JSON form structure - loaded from config
+		sampleJSON := config.FormSampleJSON
 
-	// Sample JSON form structure - loaded from config
-	sampleJSON := config.FormSampleJSON
+		// Build prompt using helper
+		prompt := BuildFormPrompt(userPrompt, sampleJSON)
 
-	// Build prompt using helper
-	prompt := BuildFormPrompt(userPrompt, sampleJSON)
-
-	messages := []DashScopeMessage{
-		{
-			Role:    "user",
-			Content: prompt,
-		},
-	}
+		messages := []DashScopeMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		}
 
-	response, err := a.callDashScopeAPI(ctx, messages)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate form: %w", err)
-	}
+		response, err := a.callDashScopeAPI(ctx, messages)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate form: %w", err)
+		}
 
-	// Clean up the response - remove markdown code blocks if present
-	formJSON := strings.TrimSpace(response)
-	formJSON = strings.TrimPrefix(formJSON, "```json")
-	formJSON = strings.TrimPrefix(formJSON, "```JSON")
-	formJSON = strings.TrimPrefix(formJSON, "```")
-	formJSON = strings.TrimSuffix(formJSON, "```")
-	formJSON = strings.TrimSpace(formJSON)
+		// Clean up the response - remove markdown code blocks if present
+		formJSON := stripCodeFences(response, "json")
 
-	// Validate JSON
-	var testJSON interface{}
-	if err := json.Unmarshal([]byte(formJSON), &testJSON); err != nil {
-		return "", fmt.Errorf("generated JSON is invalid: %w", err)
-	}
+		// Validate JSON
+		var testJSON interface{}
+		if err := json.Unmarshal([]byte(formJSON), &testJSON); err != nil {
+			return "", fmt.Errorf("generated JSON is invalid: %w", err)
+		}
 
-	// Cache the result
-	a.cache.SetDefault(cacheKey, formJSON)
+		// Cache the result
+		a.cache.SetDefault(cacheKey, formJSON)
 
-	return formJSON, nil
+		return formJSON, nil
+	})
 }
 
 // ClassifyDocumentIntent returns "FORM", "RESEARCH", or "SUMMARY" based on user message and document content.
-func (a *AIService) ClassifyDocumentIntent(userMessage, extractedText, aiResult string) (string, error) {
-	ctx := context.Background()
+// The call is canceled when ctx is done.
+func (a *AIService) ClassifyDocumentIntent(ctx context.Context, userMessage, extractedText, aiResult string) (string, error) {
 	prompt := BuildDocumentIntentPrompt(userMessage, extractedText, aiResult)
 	messages := []DashScopeMessage{{Role: "user", Content: prompt}}
 	reply, err := a.callDashScopeAPI(ctx, messages)
 	if err != nil {
 		return "SUMMARY", err
 	}
-	s := strings.TrimSpace(strings.ToUpper(reply))
+	if intent, ok := parseDocumentIntent(reply); ok {
+		return intent, nil
+	}
+
+	// Off-format reply: ask once more for the bare label before falling back to keyword matching
+	messages = append(messages,
+		DashScopeMessage{Role: "assistant", Content: reply},
+		DashScopeMessage{Role: "user", Content: "Reply with exactly one word and nothing else: FORM, RESEARCH or SUMMARY."},
+	)
+	retry, err := a.callDashScopeAPI(ctx, messages)
+	if ctx.Err() != nil {
+		return "SUMMARY", ctx.Err()
+	}
+	if err == nil {
+		if intent, ok := parseDocumentIntent(retry); ok {
+			return intent, nil
+		}
+		reply = retry
+	}
+
+	s := strings.ToUpper(reply)
 	if strings.Contains(s, "FORM") {
 		return "FORM", nil
 	}
@@ -336,20 +390,29 @@ func (a *AIService) ClassifyDocumentIntent(userMessage, extractedText, aiResult
 	return "SUMMARY", nil
 }
 
+// parseDocumentIntent accepts a reply that is exactly one of FORM, RESEARCH or SUMMARY
+// (ignoring case, whitespace, quotes and trailing punctuation).
+func parseDocumentIntent(reply string) (string, bool) {
+	s := strings.ToUpper(strings.Trim(strings.TrimSpace(reply), "\"'`*.!: \t\r\n"))
+	switch s {
+	case "FORM", "RESEARCH", "SUMMARY":
+		return s, true
+	}
+	return "", false
+}
+
 // GenerateFormTemplateFromContent generates a FormTemplate (name, description, user_type, fields) from document content.
-func (a *AIService) GenerateFormTemplateFromContent(content string, userContext string) (*models.FormTemplate, error) {
-	ctx := context.Background()
+func (a *AIService) GenerateFormTemplateFromContent(ctx context.Context, content string, userContext string) (*models.FormTemplate, error) {
 	prompt := BuildFormTemplateFromContentPrompt(content, userContext)
 	messages := []DashScopeMessage{{Role: "user", Content: prompt}}
 	reply, err := a.callDashScopeAPI(ctx, messages)
 	if err != nil {
 		return nil, err
 	}
-	raw := strings.TrimSpace(reply)
-	raw = strings.TrimPrefix(raw, "```json")
-	raw = strings.TrimPrefix(raw, "```")
-	raw = strings.TrimSuffix(raw, "```")
-	raw = strings.TrimSpace(raw)
+	raw := stripCodeFences(reply, "json")
+	if errs := validation.ValidateFormTemplateJSON([]byte(raw)); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid form template: %s", strings.Join(errs, "; "))
+	}
 	var t models.FormTemplate
 	if err := json.Unmarshal([]byte(raw), &t); err != nil {
 		return nil, fmt.Errorf("invalid form template JSON: %w", err)
@@ -363,13 +426,31 @@ func (a *AIService) GenerateFormTemplateFromContent(content string, userContext
 	return &t, nil
 }
 
-func (a *AIService) GenerateHTMLPage(resultFile *models.ResultFile, title string) (string, error) {
+// RefineForm edits an existing form template JSON per a natural-language instruction
+// (e.g. "add a phone field") and returns the updated JSON.
+func (a *AIService) RefineForm(ctx context.Context, existingJSON, instruction string) (string, error) {
+	prompt := BuildRefineFormPrompt(existingJSON, instruction)
+	messages := []DashScopeMessage{{Role: "user", Content: prompt}}
+	reply, err := a.callDashScopeAPI(ctx, messages)
+	if err != nil {
+		return "", fmt.Errorf("failed to refine form: %w", err)
+	}
+	refined := stripCodeFences(reply, "json")
+	if errs := validation.ValidateFormTemplateJSON([]byte(refined)); len(errs) > 0 {
+		return "", fmt.Errorf("refined form is invalid: %s", strings.Join(errs, "; "))
+	}
+	return refined, nil
+}
+
+// GenerateHTMLPage generates a page showing a result file. With a dataURL the page doesn't embed the
+// rows; it fetches them from dataURL (a ?shape=objects result file endpoint) when it loads.
+func (a *AIService) GenerateHTMLPage(ctx context.Context, resultFile *models.ResultFile, title string, theme models.HTMLTheme, dataURL string) (string, error) {
 	// Use context with longer timeout for HTML generation (5 minutes)
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 300*time.Second)
 	defer cancel()
 
 	// Build prompt using helper
-	prompt := BuildHTMLPagePrompt(resultFile, title)
+	prompt := BuildHTMLPagePrompt(resultFile, title, theme, dataURL)
 
 	messages := []DashScopeMessage{
 		{
@@ -384,20 +465,49 @@ func (a *AIService) GenerateHTMLPage(resultFile *models.ResultFile, title string
 		return "", fmt.Errorf("failed to generate HTML: %w", err)
 	}
 
-	html := strings.TrimSpace(response)
 	// Remove markdown code blocks if present
-	html = strings.TrimPrefix(html, "```html")
-	html = strings.TrimPrefix(html, "```HTML")
-	html = strings.TrimPrefix(html, "```")
-	html = strings.TrimSuffix(html, "```")
-	html = strings.TrimSpace(html)
+	html := stripCodeFences(response, "html")
 
 	return html, nil
 }
 
-func (a *AIService) GenerateFormHTMLPage(formJSON string) (string, error) {
+// canonicalFormKeys maps lowercase form JSON keys to the casing the HTML prompt expects
+var canonicalFormKeys = map[string]string{
+	"name":           "Name",
+	"description":    "Description",
+	"udgridsections": "UDGridSections",
+	"udgridfields":   "UDGridFields",
+	"displayname":    "DisplayName",
+	"typename":       "TypeName",
+	"required":       "Required",
+}
+
+// normalizeFormKeys rewrites known form JSON keys to their canonical casing, recursively
+func normalizeFormKeys(v interface{}) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for key, val := range node {
+			normalizeFormKeys(val)
+			canonical, ok := canonicalFormKeys[strings.ToLower(key)]
+			if !ok || canonical == key {
+				continue
+			}
+			// Keep an existing correctly-cased value rather than overwriting it
+			if _, exists := node[canonical]; !exists {
+				node[canonical] = val
+			}
+			delete(node, key)
+		}
+	case []interface{}:
+		for _, item := range node {
+			normalizeFormKeys(item)
+		}
+	}
+}
+
+func (a *AIService) GenerateFormHTMLPage(ctx context.Context, formJSON string) (string, error) {
 	// Use context with longer timeout for HTML generation (5 minutes)
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 300*time.Second)
 	defer cancel()
 
 	// Parse form JSON to extract form name and description
@@ -406,6 +516,12 @@ func (a *AIService) GenerateFormHTMLPage(formJSON string) (string, error) {
 		return "", fmt.Errorf("failed to parse form JSON: %w", err)
 	}
 
+	// Model output casing drifts (e.g. "name", "udgridsections"); normalize before reading keys
+	normalizeFormKeys(formData)
+	if normalized, err := json.Marshal(formData); err == nil {
+		formJSON = string(normalized)
+	}
+
 	formName := ""
 	formDescription := ""
 	if name, ok := formData["Name"].(string); ok {
@@ -431,36 +547,63 @@ func (a *AIService) GenerateFormHTMLPage(formJSON string) (string, error) {
 		return "", fmt.Errorf("failed to generate form HTML: %w", err)
 	}
 
-	html := strings.TrimSpace(response)
 	// Remove markdown code blocks if present
-	html = strings.TrimPrefix(html, "```html")
-	html = strings.TrimPrefix(html, "```HTML")
-	html = strings.TrimPrefix(html, "```")
-	html = strings.TrimSuffix(html, "```")
-	html = strings.TrimSpace(html)
+	html := stripCodeFences(response, "html")
 
 	return html, nil
 }
 
 // GenerateChatResponse generates a plain chat response for general prompts
-func (a *AIService) GenerateChatResponse(userPrompt string) (string, error) {
+func (a *AIService) GenerateChatResponse(ctx context.Context, userPrompt string) (string, error) {
 	// Check cache first
 	cacheKey := fmt.Sprintf("chat_prompt:%s", userPrompt)
-	if cached, found := a.cache.Get(cacheKey); found {
-		return cached.(string), nil
+	if cached, found := a.cachedResponse("GenerateChatResponse", cacheKey); found {
+		return cached, nil
 	}
 
-	ctx := context.Background()
+	// Concurrent identical prompts share one upstream call
+	return a.inflight.do(ctx, cacheKey, func(ctx context.Context) (string, error) {
+		// Build a simple chat prompt
+		prompt := fmt.Sprintf("You are a helpful assistant. Please respond to the following user message in a helpful and informative way:\n\n%s", userPrompt)
 
-	// Build a simple chat prompt
-	prompt := fmt.Sprintf("You are a helpful assistant. Please respond to the following user message in a helpful and informative way:\n\n%s", userPrompt)
+		messages := []DashScopeMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		}
+
+		response, err := a.callDashScopeAPI(ctx, messages)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate chat response: %w", err)
+		}
+
+		// Clean up the response - remove markdown code blocks if present
+		chatResponse := strings.TrimSpace(response)
+		chatResponse = strings.TrimPrefix(chatResponse, "```")
+		chatResponse = strings.TrimSuffix(chatResponse, "```")
+		chatResponse = strings.TrimSpace(chatResponse)
+
+		// Cache the result
+		a.cache.SetDefault(cacheKey, chatResponse)
+
+		return chatResponse, nil
+	})
+}
 
+// GenerateChatResponseWithContext answers a general chat message given earlier turns of the session.
+// summary, if set, condenses turns older than history. Not cached, since the context differs per call.
+func (a *AIService) GenerateChatResponseWithContext(ctx context.Context, summary string, history []models.StoredChatMessage, userPrompt string) (string, error) {
 	messages := []DashScopeMessage{
-		{
-			Role:    "user",
-			Content: prompt,
-		},
+		{Role: "system", Content: "You are a helpful assistant. Respond to the user's latest message in a helpful and informative way, using the earlier conversation for context."},
+	}
+	if summary != "" {
+		messages = append(messages, DashScopeMessage{Role: "system", Content: "Summary of the earlier conversation:\n" + summary})
+	}
+	for _, m := range history {
+		messages = append(messages, DashScopeMessage{Role: m.Role, Content: m.Content})
 	}
+	messages = append(messages, DashScopeMessage{Role: "user", Content: userPrompt})
 
 	response, err := a.callDashScopeAPI(ctx, messages)
 	if err != nil {
@@ -471,17 +614,35 @@ func (a *AIService) GenerateChatResponse(userPrompt string) (string, error) {
 	chatResponse := strings.TrimSpace(response)
 	chatResponse = strings.TrimPrefix(chatResponse, "```")
 	chatResponse = strings.TrimSuffix(chatResponse, "```")
-	chatResponse = strings.TrimSpace(chatResponse)
+	return strings.TrimSpace(chatResponse), nil
+}
 
-	// Cache the result
-	a.cache.SetDefault(cacheKey, chatResponse)
+// SummarizeConversation condenses chat turns, together with the summary of the turns before them
+// (previous, may be empty), into a short summary. Summaries are cached by prompt content.
+func (a *AIService) SummarizeConversation(ctx context.Context, previous string, turns []models.StoredChatMessage) (string, error) {
+	prompt := BuildConversationSummaryPrompt(previous, turns)
+	sum := sha256.Sum256([]byte(prompt))
+	cacheKey := "chat_summary:" + hex.EncodeToString(sum[:])
+	if cached, found := a.cachedResponse("SummarizeConversation", cacheKey); found {
+		return cached, nil
+	}
 
-	return chatResponse, nil
+	// Concurrent identical prompts share one upstream call
+	return a.inflight.do(ctx, cacheKey, func(ctx context.Context) (string, error) {
+		response, err := a.callDashScopeAPI(ctx, []DashScopeMessage{{Role: "user", Content: prompt}})
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize conversation: %w", err)
+		}
+		summary := strings.TrimSpace(response)
+		a.cache.SetDefault(cacheKey, summary)
+		return summary, nil
+	})
 }
 
 // CorrectSpelling corrects spelling errors in user input using AI
-// It preserves the user's intent while fixing typos and misspellings
-func (a *AIService) CorrectSpelling(userInput string) (string, error) {
+// It preserves the user's intent while fixing typos and misspellings.
+// The call is canceled when ctx is done; ctx.Err() is returned along with the original input.
+func (a *AIService) CorrectSpelling(ctx context.Context, userInput string) (string, error) {
 	// Skip correction for very short inputs or if input seems fine
 	if len(userInput) < 3 {
 		return userInput, nil
@@ -489,58 +650,62 @@ func (a *AIService) CorrectSpelling(userInput string) (string, error) {
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("spell_correct:%s", userInput)
-	if cached, found := a.cache.Get(cacheKey); found {
-		return cached.(string), nil
-	}
-
-	ctx := context.Background()
-
-	// Build prompt for spelling correction
-	prompt := fmt.Sprintf(`You are a spelling and grammar correction assistant. Your task is to correct spelling errors and typos in the user's message while preserving their exact meaning and intent. 
-
-IMPORTANT RULES:
-1. Only correct actual spelling mistakes and typos
-2. Preserve the user's original meaning and intent completely
-3. Keep the same tone and style
-4. Do NOT change words that are intentionally informal (like "wanna", "gonna", "yeah")
-5. Do NOT add or remove words unless they are clearly typos
-6. Fix spacing issues (e.g., "iwanna" -> "i wanna")
-7. Return ONLY the corrected text, nothing else - no explanations, no markdown, just the corrected message
-
-User's message to correct:
-"%s"
-
-Corrected message:`, userInput)
-
-	messages := []DashScopeMessage{
-		{
-			Role:    "user",
-			Content: prompt,
-		},
-	}
+	if cached, found := a.cachedResponse("CorrectSpelling", cacheKey); found {
+		return cached, nil
+	}
+
+	// Concurrent identical prompts share one upstream call
+	return a.inflight.do(ctx, cacheKey, func(ctx context.Context) (string, error) {
+		// Build prompt for spelling correction
+		prompt := fmt.Sprintf(`You are a spelling and grammar correction assistant. Your task is to correct spelling errors and typos in the user's message while preserving their exact meaning and intent. 
+
+	IMPORTANT RULES:
+	1. Only correct actual spelling mistakes and typos
+	2. Preserve the user's original meaning and intent completely
+	3. Keep the same tone and style
+	4. Do NOT change words that are intentionally informal (like "wanna", "gonna", "yeah")
+	5. Do NOT add or remove words unless they are clearly typos
+	6. Fix spacing issues (e.g., "iwanna" -> "i wanna")
+	7. Return ONLY the corrected text, nothing else - no explanations, no markdown, just the corrected message
+
+	User's message to correct:
+	"%s"
+
+	Corrected message:`, userInput)
+
+		messages := []DashScopeMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		}
 
-	response, err := a.callDashScopeAPI(ctx, messages)
-	if err != nil {
-		// If AI correction fails, return original input
-		return userInput, nil
-	}
+		response, err := a.callDashScopeAPI(ctx, messages)
+		if err != nil {
+			if ctx.Err() != nil {
+				return userInput, ctx.Err()
+			}
+			// If AI correction fails, return original input
+			return userInput, nil
+		}
 
-	// Clean up the response
-	corrected := strings.TrimSpace(response)
-	// Remove any markdown code blocks if present
-	corrected = strings.TrimPrefix(corrected, "```")
-	corrected = strings.TrimSuffix(corrected, "```")
-	corrected = strings.TrimSpace(corrected)
+		// Clean up the response
+		corrected := strings.TrimSpace(response)
+		// Remove any markdown code blocks if present
+		corrected = strings.TrimPrefix(corrected, "```")
+		corrected = strings.TrimSuffix(corrected, "```")
+		corrected = strings.TrimSpace(corrected)
 
-	// If correction is empty or same as input, return original
-	if corrected == "" || corrected == userInput {
-		return userInput, nil
-	}
+		// If correction is empty or same as input, return original
+		if corrected == "" || corrected == userInput {
+			return userInput, nil
+		}
 
-	// Cache the result
-	a.cache.SetDefault(cacheKey, corrected)
+		// Cache the result
+		a.cache.SetDefault(cacheKey, corrected)
 
-	return corrected, nil
+		return corrected, nil
+	})
 }
 
 // GenerateFromMessages calls the model with the given message list (e.g. system + user + assistant + user).
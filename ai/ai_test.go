@@ -0,0 +1,36 @@
+package ai
+
+import "testing"
+
+func TestStripCodeFences(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		lang string
+		want string
+	}{
+		{"json fence", "```json\n{\"a\": 1}\n```", "json", `{"a": 1}`},
+		{"language hint in other case", "```JSON\n{\"a\": 1}\n```", "json", `{"a": 1}`},
+		{"bare fence", "```\nSELECT 1\n```", "sql", "SELECT 1"},
+		{"other language hint", "```sql\nSELECT 1\n```", "json", "SELECT 1"},
+		{"unclosed fence", "```sql\nSELECT name\nFROM students", "sql", "SELECT name\nFROM students"},
+		{"leading and trailing prose", "Here is the query:\n```sql\nSELECT 1\n```\nLet me know if it helps.", "sql", "SELECT 1"},
+		{"crlf input", "```json\r\n{\"a\": 1}\r\n```\r\n", "json", `{"a": 1}`},
+		{"one line with hint", "```json{\"a\": 1}```", "json", `{"a": 1}`},
+		{"bare fence starting with a keyword line", "```\nSELECT\n  name\nFROM students\n```", "sql", "SELECT\n  name\nFROM students"},
+		{"bare fence starting with WITH", "```\nWITH\n  s AS (SELECT id FROM students)\nSELECT * FROM s\n```", "sql", "WITH\n  s AS (SELECT id FROM students)\nSELECT * FROM s"},
+		{"html fence", "```html\n<html>\n<body>Hi</body>\n</html>\n```", "html", "<html>\n<body>Hi</body>\n</html>"},
+		{"bare html fence", "```\n<!DOCTYPE html>\n<html></html>\n```", "html", "<!DOCTYPE html>\n<html></html>"},
+		{"code on the opening line is kept", "```SELECT * FROM t;\nWHERE 1 = 1\n```", "sql", "SELECT * FROM t;\nWHERE 1 = 1"},
+		{"first of two blocks", "```sql\nSELECT 1\n```\nor\n```sql\nSELECT 2\n```", "sql", "SELECT 1"},
+		{"no fence", "  SELECT 1  \n", "sql", "SELECT 1"},
+		{"empty", "", "sql", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripCodeFences(tt.in, tt.lang); got != tt.want {
+				t.Errorf("stripCodeFences(%q, %q) = %q, want %q", tt.in, tt.lang, got, tt.want)
+			}
+		})
+	}
+}
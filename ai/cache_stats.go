@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"sort"
+	"sync"
+)
+
+// CacheMethodStats counts response cache lookups for one AI method
+type CacheMethodStats struct {
+	Method  string  `json:"method"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // hits / (hits + misses), 0 before the first lookup
+}
+
+// cacheStats tracks hits and misses per method since startup
+type cacheStats struct {
+	mu     sync.Mutex
+	counts map[string]*CacheMethodStats
+}
+
+func (s *cacheStats) record(method string, hit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts == nil {
+		s.counts = make(map[string]*CacheMethodStats)
+	}
+	c := s.counts[method]
+	if c == nil {
+		c = &CacheMethodStats{Method: method}
+		s.counts[method] = c
+	}
+	if hit {
+		c.Hits++
+	} else {
+		c.Misses++
+	}
+}
+
+// snapshot returns a copy of the counters, sorted by method
+func (s *cacheStats) snapshot() []CacheMethodStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make([]CacheMethodStats, 0, len(s.counts))
+	for _, c := range s.counts {
+		stat := *c
+		if total := stat.Hits + stat.Misses; total > 0 {
+			stat.HitRate = float64(stat.Hits) / float64(total)
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Method < stats[j].Method })
+	return stats
+}
+
+// cachedResponse looks up key in the response cache, counting the hit or miss against method
+func (a *AIService) cachedResponse(method, key string) (string, bool) {
+	cached, found := a.cache.Get(key)
+	a.cacheStats.record(method, found)
+	if !found {
+		return "", false
+	}
+	return cached.(string), true
+}
+
+// CacheStats returns response cache hits and misses per method since startup
+func (a *AIService) CacheStats() []CacheMethodStats {
+	return a.cacheStats.snapshot()
+}
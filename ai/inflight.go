@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// inflightGroup shares one model call between concurrent identical prompts, so N simultaneous
+// requests for the same prompt make a single upstream call (the cache only helps once it finishes).
+type inflightGroup struct {
+	group singleflight.Group
+}
+
+// do runs fn for key, or joins the call already running for it. The shared call runs detached
+// from the caller that started it, so one caller giving up neither fails the others nor makes
+// them call upstream again; each caller stops waiting when its own ctx is done. A panic in fn
+// is returned to every caller as an error.
+func (g *inflightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (string, error)) (string, error) {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+
+	shared := context.WithoutCancel(ctx)
+	ch := g.group.DoChan(hash, func() (val interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("model call panicked: %v", r)
+			}
+		}()
+		return fn(shared)
+	})
+	select {
+	case res := <-ch:
+		val, _ := res.Val.(string)
+		return val, res.Err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startCalls runs n callers of g.do for the same key; wait blocks until all of them have returned
+func startCalls(g *inflightGroup, n int, fn func(ctx context.Context) (string, error)) (vals []string, errs []error, wait func()) {
+	vals = make([]string, n)
+	errs = make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vals[i], errs[i] = g.do(context.Background(), "prompt", fn)
+		}(i)
+	}
+	return vals, errs, wg.Wait
+}
+
+func TestInflightSharesOneCall(t *testing.T) {
+	var g inflightGroup
+	var calls int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "answer", nil
+	}
+
+	vals, errs, wait := startCalls(&g, 5, fn)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wait()
+
+	if calls != 1 {
+		t.Errorf("fn ran %d times, want 1", calls)
+	}
+	for i := range vals {
+		if vals[i] != "answer" || errs[i] != nil {
+			t.Errorf("caller %d = %q, %v; want answer", i, vals[i], errs[i])
+		}
+	}
+}
+
+func TestInflightLeaderCancelDoesNotFailFollowers(t *testing.T) {
+	var g inflightGroup
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		select {
+		case <-release:
+			return "answer", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	var leaderErr error
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		_, leaderErr = g.do(leaderCtx, "prompt", fn)
+	}()
+	<-started
+
+	vals, errs, wait := startCalls(&g, 3, fn)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-leaderDone
+	close(release)
+	wait()
+
+	if !errors.Is(leaderErr, context.Canceled) {
+		t.Errorf("leader error = %v, want context.Canceled", leaderErr)
+	}
+	if calls != 1 {
+		t.Errorf("fn ran %d times, want 1", calls)
+	}
+	for i := range vals {
+		if vals[i] != "answer" || errs[i] != nil {
+			t.Errorf("follower %d = %q, %v; want answer", i, vals[i], errs[i])
+		}
+	}
+}
+
+func TestInflightPanicIsAnError(t *testing.T) {
+	var g inflightGroup
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		<-release
+		panic("boom")
+	}
+
+	vals, errs, wait := startCalls(&g, 3, fn)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wait()
+
+	for i := range vals {
+		if vals[i] != "" || errs[i] == nil {
+			t.Errorf("caller %d = %q, %v; want an error", i, vals[i], errs[i])
+		}
+	}
+}
@@ -28,3 +28,8 @@ func (c *Cache) SetDefault(key string, value interface{}) {
 	c.cache.Set(key, value, cache.DefaultExpiration)
 }
 
+// Close flushes pending writes before shutdown. The in-memory backend has nothing to
+// flush, so this is a no-op; persistent backends must flush any write-behind buffers here.
+func (c *Cache) Close() error {
+	return nil
+}
@@ -3,6 +3,9 @@ package config
 import (
 	_ "embed"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 //go:embed form_sample.json
@@ -19,6 +22,88 @@ type Config struct {
 	VoiceSamplesDir  string
 	ExternalAPIBase  string // Image reader, PDF reader, Gathering (e.g. http://localhost:8000)
 	SQLServer        SQLServerConfig
+
+	// Form answer submissions allowed per client IP per form within FormAnswerRateWindow (0 disables)
+	FormAnswerRateLimit  int
+	FormAnswerRateWindow time.Duration
+	// Proxies (IPs or CIDRs) allowed to set the client IP via X-Forwarded-For/X-Real-IP; empty trusts
+	// none, so per-IP limits key on the connection's address and can't be dodged with a forged header
+	TrustedProxies []string
+
+	// Most recent registration conversation turns (a user message and its reply) kept in stored state (0 keeps all)
+	RegistrationHistoryMaxTurns int
+
+	// Product HTML files older than this many days are removed at startup (0 keeps them forever)
+	ProductsRetentionDays int
+
+	// Largest document (image/PDF) accepted by chat file uploads, in bytes
+	MaxUploadSize int64
+
+	// Store document extraction results keyed by content hash so identical uploads skip the reader
+	CacheDocumentExtractions bool
+
+	// System prompt sent to the document readers when the user doesn't ask for something specific
+	SummarizePrompt string
+
+	// Log output format: "text" (default) or "json"
+	LogFormat string
+	// Number of recent error-level log entries kept for GET /api/admin/errors
+	ErrorBufferSize int
+
+	// Token required (Authorization: Bearer or X-Admin-Token) for /api/admin/*; empty disables the admin API
+	AdminToken string
+
+	// Read-only demo: no SQL execution, file writes, saved forms/answers or voice registration
+	DemoMode bool
+
+	// Shown to users when the complaint service fails in an unrecognized way (details stay in the logs)
+	ComplaintErrorMessage string
+
+	// How requests without X-User-ID are identified: "cookie" (signed per-client cookie), "shared" or "reject"
+	UserIDFallback string
+	// Key used to sign anonymous user cookies; random per process when empty
+	UserIDCookieSecret string
+
+	// Request deadline for most routes, and for AI generation routes (chat, HTML generation); 0 disables
+	RequestTimeout    time.Duration
+	GenerationTimeout time.Duration
+
+	// User type ("student" or "staff") for forms saved from chat when neither the form nor the request indicates one
+	DefaultFormUserType string
+
+	// Prior turns (user message + reply) of the session sent with general chat messages; older turns
+	// are condensed into one summary message (0 sends no history)
+	ChatContextTurns int
+
+	// Identical SELECT-only SQL run within this window reuses the earlier result file instead of querying again (0 disables)
+	SQLResultCacheTTL time.Duration
+
+	// AI spelling correction of chat messages; messages with code or identifiers are never corrected
+	SpellCheck bool
+
+	// Number of candidate forms (n_results) the complaint dialogue considers when it starts
+	ComplaintNResults int
+
+	// Generate an HTML page (an AI call) for every chat report result; when off, only the JSON result
+	// is saved and pages are made on request via /api/results/generate-html
+	ReportAutoHTML bool
+
+	// Generated report SQL runs automatically only when its confidence (0-100, the share of the tables
+	// it reads that the reference SQL also uses) is at least this; otherwise it is returned for review (0 disables)
+	SQLAutoExecuteMinConfidence int
+
+	// Result pages for results with more rows than this don't embed the data; they load it from
+	// /api/results/file/:filename?shape=objects and paginate it in the browser (0 always embeds)
+	HTMLEmbedMaxRows int
+
+	// File extensions /products/:filename serves (e.g. ".html", ".css", ".png"); others are rejected
+	ProductExtensions []string
+
+	// Report SQL heads (CTE blocks put in front of generated report SQL) are <name>.sql files here;
+	// "student" falls back to the built-in StudentReportSqlHead
+	ReportHeadsDir string
+	// Head used when a chat request doesn't name one
+	DefaultReportHead string
 }
 
 type SQLServerConfig struct {
@@ -28,6 +113,19 @@ type SQLServerConfig struct {
 	UserID   string
 	Password string
 	Encrypt  bool
+	// Return rows scanned before a mid-query error alongside the error
+	PartialResults bool
+	// Max rows kept in memory per query; further rows are dropped and the result is flagged truncated (0 = unlimited)
+	MaxRows int
+	// Statement types (e.g. SELECT, INSERT) queries may use; anything else is rejected before execution
+	AllowedStatements []string
+	// Rows returned by a preview run of /api/sql/execute
+	PreviewRows int
+	// Isolation level for queries so reports don't block (or get blocked by) writers:
+	// "" (server default, READ COMMITTED), "snapshot" (consistent committed data; the database
+	// needs ALLOW_SNAPSHOT_ISOLATION ON) or "read_uncommitted" (same as WITH (NOLOCK) on every
+	// table: dirty reads, so rows may be missing, duplicated or later rolled back)
+	ReadIsolation string
 }
 
 func GetConfig() Config {
@@ -43,6 +141,34 @@ func GetConfig() Config {
 		SitesDir:       getEnv("SITES_DIR", "./sites"),
 		VoiceSamplesDir: getEnv("VOICE_SAMPLES_DIR", "./voice_samples"),
 		ExternalAPIBase:  getEnv("EXTERNAL_API_BASE", "http://localhost:8000"),
+		FormAnswerRateLimit:  getEnvInt("FORM_ANSWER_RATE_LIMIT", 10),
+		FormAnswerRateWindow: time.Duration(getEnvInt("FORM_ANSWER_RATE_WINDOW_SECONDS", 60)) * time.Second,
+		TrustedProxies:       getEnvList("TRUSTED_PROXIES", nil),
+		RegistrationHistoryMaxTurns: getEnvInt("REGISTRATION_HISTORY_MAX_TURNS", 20),
+		ProductsRetentionDays:       getEnvInt("PRODUCTS_RETENTION_DAYS", 0),
+		MaxUploadSize:               int64(getEnvInt("MAX_UPLOAD_SIZE_MB", 25)) << 20,
+		CacheDocumentExtractions:    getEnv("CACHE_DOCUMENT_EXTRACTIONS", "true") == "true",
+		SummarizePrompt:             getEnv("SUMMARIZE_PROMPT", "Summarize the following content clearly and concisely."),
+		LogFormat:                   getEnv("LOG_FORMAT", "text"),
+		ErrorBufferSize:             getEnvInt("ERROR_BUFFER_SIZE", 100),
+		AdminToken:                  getEnv("ADMIN_API_TOKEN", ""),
+		DemoMode:                    getEnv("DEMO_MODE", "false") == "true",
+		ComplaintErrorMessage:       getEnv("COMPLAINT_ERROR_MESSAGE", "Sorry, something went wrong while processing your complaint. Please try again in a few minutes."),
+		UserIDFallback:              getEnv("USER_ID_FALLBACK", "cookie"),
+		UserIDCookieSecret:          getEnv("USER_ID_COOKIE_SECRET", ""),
+		RequestTimeout:              time.Duration(getEnvInt("REQUEST_TIMEOUT_SECONDS", 120)) * time.Second,
+		GenerationTimeout:           time.Duration(getEnvInt("GENERATION_TIMEOUT_SECONDS", 600)) * time.Second,
+		DefaultFormUserType:         getEnv("DEFAULT_FORM_USER_TYPE", "student"),
+		ChatContextTurns:            getEnvInt("CHAT_CONTEXT_TURNS", 10),
+		SQLResultCacheTTL:           time.Duration(getEnvInt("SQL_RESULT_CACHE_TTL_SECONDS", 300)) * time.Second,
+		SpellCheck:                  getEnv("SPELL_CHECK", "true") == "true",
+		ComplaintNResults:           getEnvInt("COMPLAINT_N_RESULTS", 3),
+		ReportAutoHTML:              getEnv("REPORT_AUTO_HTML", "true") == "true",
+		SQLAutoExecuteMinConfidence: getEnvInt("SQL_AUTO_EXECUTE_MIN_CONFIDENCE", 100),
+		HTMLEmbedMaxRows:            getEnvInt("HTML_EMBED_MAX_ROWS", 200),
+		ProductExtensions:           getEnvList("PRODUCT_EXTENSIONS", []string{".html"}),
+		ReportHeadsDir:              getEnv("REPORT_HEADS_DIR", "./report_heads"),
+		DefaultReportHead:           getEnv("DEFAULT_REPORT_HEAD", "student"),
 		SQLServer: SQLServerConfig{
 			Server:   getEnv("SQL_SERVER", "192.168.9.9"),
 			Port:     getEnv("SQL_PORT", "1433"),
@@ -50,6 +176,11 @@ func GetConfig() Config {
 			UserID:   getEnv("SQL_USER", "tfuser"),
 			Password: getEnv("SQL_PASSWORD", "$transfinder2006"),
 			Encrypt:  getEnv("SQL_ENCRYPT", "true") == "true",
+			PartialResults: getEnv("SQL_PARTIAL_RESULTS", "true") == "true",
+			MaxRows:        getEnvInt("SQL_MAX_ROWS", 100000),
+			AllowedStatements: getEnvList("SQL_ALLOWED_STATEMENTS", []string{"SELECT"}),
+			PreviewRows:       getEnvInt("SQL_PREVIEW_ROWS", 20),
+			ReadIsolation:     getEnv("SQL_READ_ISOLATION", ""),
 		},
 	}
 }
@@ -61,3 +192,26 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList reads a comma-separated list, dropping empty entries
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -16,28 +17,126 @@ import (
 
 type DB struct {
 	badgerDB *badger.DB
+	stopGC   chan struct{}
+	gcDone   chan struct{}
 }
 
+// Value-log GC reclaims space left by overwritten and deleted keys (chat history, flow states)
+const (
+	valueLogGCInterval     = 10 * time.Minute
+	valueLogGCDiscardRatio = 0.5
+)
+
+// ErrLocked is returned by New when another process holds the database directory lock
+var ErrLocked = errors.New("database directory is locked by another process")
+
+// MaxValueSize bounds a stored value. Badger fails a transaction larger than ~15% of its memtable
+// (about 10 MB by default) with a bare "Txn is too big" error, so oversized values are refused
+// before they reach Badger with an error that says what was too large.
+const MaxValueSize = 4 << 20
+
+// ErrValueTooLarge is returned (wrapped) by store methods for values over MaxValueSize
+var ErrValueTooLarge = errors.New("value too large to store")
+
+// setValue stores data under key, refusing values over MaxValueSize
+func setValue(txn *badger.Txn, key, data []byte) error {
+	if err := checkValueSize(key, data); err != nil {
+		return err
+	}
+	return txn.Set(key, data)
+}
+
+// checkValueSize returns an ErrValueTooLarge error naming the kind of record (the key prefix)
+func checkValueSize(key, data []byte) error {
+	if len(data) <= MaxValueSize {
+		return nil
+	}
+	kind := string(key)
+	if i := strings.IndexByte(kind, ':'); i >= 0 {
+		kind = kind[:i]
+	}
+	log.Printf("[DB] Refusing to store %s: %d bytes exceeds the %d byte limit", key, len(data), MaxValueSize)
+	return fmt.Errorf("%w: %s record is %d KB (limit %d KB)", ErrValueTooLarge, kind, len(data)>>10, MaxValueSize>>10)
+}
+
+// Retries while the directory lock is held, e.g. by a previous instance that is still shutting down
+const (
+	lockRetries    = 5
+	lockRetryDelay = time.Second
+)
+
 func New(dbPath string) (*DB, error) {
 	opts := badger.DefaultOptions(dbPath)
 	opts.Logger = nil // Disable badger logging for cleaner output
 
-	badgerDB, err := badger.Open(opts)
+	var badgerDB *badger.DB
+	var err error
+	for attempt := 0; attempt <= lockRetries; attempt++ {
+		if attempt > 0 {
+			log.Printf("[DB] Database directory %s is locked, retrying (%d/%d)", dbPath, attempt, lockRetries)
+			time.Sleep(lockRetryDelay)
+		}
+		badgerDB, err = badger.Open(opts)
+		if err == nil || !isLockError(err) {
+			break
+		}
+	}
 	if err != nil {
+		if isLockError(err) {
+			return nil, fmt.Errorf("%w: %s (is another instance running?)", ErrLocked, dbPath)
+		}
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	return &DB{badgerDB: badgerDB}, nil
+	d := &DB{badgerDB: badgerDB, stopGC: make(chan struct{}), gcDone: make(chan struct{})}
+	go d.runValueLogGC()
+	return d, nil
+}
+
+// runValueLogGC runs Badger value-log GC every valueLogGCInterval until Close
+func (d *DB) runValueLogGC() {
+	defer close(d.gcDone)
+	ticker := time.NewTicker(valueLogGCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stopGC:
+			return
+		case <-ticker.C:
+			// One call rewrites at most one file; repeat until there is nothing left to reclaim
+			rewritten := 0
+			for {
+				err := d.badgerDB.RunValueLogGC(valueLogGCDiscardRatio)
+				if err != nil {
+					if !errors.Is(err, badger.ErrNoRewrite) {
+						log.Printf("[DB] Value log GC error: %v", err)
+					}
+					break
+				}
+				rewritten++
+			}
+			if rewritten > 0 {
+				log.Printf("[DB] Value log GC rewrote %d file(s)", rewritten)
+			}
+		}
+	}
+}
+
+// isLockError reports whether err is Badger failing to acquire the directory lock
+func isLockError(err error) bool {
+	return strings.Contains(err.Error(), "Another process is using this Badger database")
 }
 
 func (d *DB) Close() error {
+	close(d.stopGC)
+	<-d.gcDone
 	return d.badgerDB.Close()
 }
 
 func (d *DB) StoreSQLFile(name string, content string) error {
 	return d.badgerDB.Update(func(txn *badger.Txn) error {
 		key := []byte(fmt.Sprintf("sql_file:%s", name))
-		return txn.Set(key, []byte(content))
+		return setValue(txn, key, []byte(content))
 	})
 }
 
@@ -74,13 +173,13 @@ func (d *DB) GetSQLFiles() ([]models.SQLFile, error) {
 
 func (d *DB) StoreChatHistory(userID string, message string, response string) error {
 	return d.badgerDB.Update(func(txn *badger.Txn) error {
-		timestamp := time.Now().Unix()
-		key := []byte(fmt.Sprintf("chat:%s:%d", userID, timestamp))
+		now := time.Now()
+		key := []byte(fmt.Sprintf("chat:%s:%s", userID, models.OrderKey(now)))
 
 		history := models.ChatHistory{
 			Message:   message,
 			Response:  response,
-			Timestamp: fmt.Sprintf("%d", timestamp),
+			Timestamp: models.Timestamp(now),
 		}
 
 		data, err := json.Marshal(history)
@@ -88,7 +187,7 @@ func (d *DB) StoreChatHistory(userID string, message string, response string) er
 			return err
 		}
 
-		return txn.Set(key, data)
+		return setValue(txn, key, data)
 	})
 }
 
@@ -111,9 +210,13 @@ func (d *DB) LoadSQLFilesFromDir(sqlFilesDir string) ([]models.SQLFile, error) {
 				return err
 			}
 
+			text, encoding := DecodeSQLText(content)
+			if encoding != "utf-8" {
+				log.Printf("[DB] Converted SQL file %s from %s to UTF-8", info.Name(), encoding)
+			}
 			sqlFiles = append(sqlFiles, models.SQLFile{
 				Name:    info.Name(),
-				Content: string(content),
+				Content: text,
 			})
 		}
 		return nil
@@ -138,7 +241,7 @@ func (d *DB) StoreComplaintState(userID string, state *models.ComplaintState) er
 		}
 		
 		log.Printf("[DB] Setting key in transaction, data size: %d bytes", len(data))
-		if err := txn.Set(key, data); err != nil {
+		if err := setValue(txn, key, data); err != nil {
 			log.Printf("[DB] Error setting key in transaction: %v", err)
 			return err
 		}
@@ -246,8 +349,8 @@ func (d *DB) GetComplaintStateByUserID(userID string) (*models.ComplaintState, e
 				if err := json.Unmarshal(val, &tempState); err != nil {
 					return err
 				}
-				// If this state is not complete, it's an active session
-				if tempState.Step != "complete" && tempState.ConversationID != "" {
+				// If this state is not complete (or given up as failed), it's an active session
+				if tempState.Step != "complete" && tempState.Step != "failed" && tempState.ConversationID != "" {
 					activeCount++
 					lastActiveKey = key
 					lastActiveItem = item
@@ -320,7 +423,7 @@ func (d *DB) StoreVoiceProfile(profile *models.VoiceProfile) error {
 			return err
 		}
 		
-		return txn.Set(key, data)
+		return setValue(txn, key, data)
 	})
 }
 
@@ -392,6 +495,65 @@ func (d *DB) DeleteVoiceProfile(userID string) error {
 	})
 }
 
+// ErrVoiceProfileNotFound is returned by MergeVoiceProfiles when either profile does not exist
+var ErrVoiceProfileNotFound = errors.New("voice profile not found")
+
+// MergeVoiceProfiles adds the samples of the source profile to the target profile (skipping
+// ones it already has) and deletes the source, in one transaction. The merged profile keeps the
+// target's user ID and name and the earlier of the two creation times.
+func (d *DB) MergeVoiceProfiles(targetUserID, sourceUserID string) (*models.VoiceProfile, error) {
+	var merged models.VoiceProfile
+	err := d.badgerDB.Update(func(txn *badger.Txn) error {
+		load := func(userID string, p *models.VoiceProfile) error {
+			item, err := txn.Get([]byte(fmt.Sprintf("voice_profile:%s", userID)))
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return fmt.Errorf("%w: %s", ErrVoiceProfileNotFound, userID)
+			}
+			if err != nil {
+				return err
+			}
+			return item.Value(func(val []byte) error {
+				return json.Unmarshal(val, p)
+			})
+		}
+		var source models.VoiceProfile
+		if err := load(targetUserID, &merged); err != nil {
+			return err
+		}
+		if err := load(sourceUserID, &source); err != nil {
+			return err
+		}
+
+		have := make(map[string]bool, len(merged.VoiceSamples))
+		for _, s := range merged.VoiceSamples {
+			have[s] = true
+		}
+		for _, s := range source.VoiceSamples {
+			if !have[s] {
+				have[s] = true
+				merged.VoiceSamples = append(merged.VoiceSamples, s)
+			}
+		}
+		if source.CreatedAt != "" && models.TimestampAfter(merged.CreatedAt, source.CreatedAt) {
+			merged.CreatedAt = source.CreatedAt
+		}
+		merged.UpdatedAt = models.NowTimestamp()
+
+		data, err := json.Marshal(&merged)
+		if err != nil {
+			return err
+		}
+		if err := setValue(txn, []byte(fmt.Sprintf("voice_profile:%s", targetUserID)), data); err != nil {
+			return err
+		}
+		return txn.Delete([]byte(fmt.Sprintf("voice_profile:%s", sourceUserID)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merged, nil
+}
+
 // Form Template CRUD operations
 
 // StoreFormTemplate stores a form template
@@ -404,7 +566,7 @@ func (d *DB) StoreFormTemplate(template *models.FormTemplate) error {
 			return err
 		}
 		
-		return txn.Set(key, data)
+		return setValue(txn, key, data)
 	})
 }
 
@@ -488,7 +650,7 @@ func (d *DB) StoreFormAnswer(answer *models.FormAnswer) error {
 			return err
 		}
 		
-		return txn.Set(key, data)
+		return setValue(txn, key, data)
 	})
 }
 
@@ -643,7 +805,7 @@ func (d *DB) StoreRegistrationState(userID string, state *models.RegistrationSta
 		if err != nil {
 			return err
 		}
-		return txn.Set(key, data)
+		return setValue(txn, key, data)
 	})
 }
 
@@ -678,29 +840,44 @@ func (d *DB) DeleteRegistrationState(userID string) error {
 const (
 	chatSessionPrefix = "chat_sess:"
 	chatMessagePrefix = "chat_msg:"
+	chatSummaryPrefix = "chat_summary:"
 )
 
 // EnsureDefaultChatSession creates the default session for user if it does not exist.
+// The check and the write happen in one transaction. When concurrent first requests race,
+// Badger rejects all but one commit with ErrConflict. The losers retry, find the session
+// and leave it as is, so exactly one default session is ever created.
 func (d *DB) EnsureDefaultChatSession(userID string) error {
 	key := []byte(fmt.Sprintf("%s%s:%s", chatSessionPrefix, userID, models.DefaultChatSessionID))
-	var exists bool
-	err := d.badgerDB.View(func(txn *badger.Txn) error {
+	create := func(txn *badger.Txn) error {
 		_, err := txn.Get(key)
-		exists = (err == nil)
-		return nil
-	})
-	if err != nil || exists {
-		return err
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		now := models.NowTimestamp()
+		data, err := json.Marshal(&models.ChatSession{
+			ID:        models.DefaultChatSessionID,
+			UserID:    userID,
+			Title:     "Default",
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+		return setValue(txn, key, data)
 	}
-	now := time.Now().Format(time.RFC3339)
-	sess := &models.ChatSession{
-		ID:        models.DefaultChatSessionID,
-		UserID:    userID,
-		Title:     "Default",
-		CreatedAt: now,
-		UpdatedAt: now,
+
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		if err = d.badgerDB.Update(create); !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
 	}
-	return d.StoreChatSession(sess)
+	return err
 }
 
 // StoreChatSession saves or updates a chat session.
@@ -711,7 +888,7 @@ func (d *DB) StoreChatSession(s *models.ChatSession) error {
 		return err
 	}
 	return d.badgerDB.Update(func(txn *badger.Txn) error {
-		return txn.Set(key, data)
+		return setValue(txn, key, data)
 	})
 }
 
@@ -761,7 +938,7 @@ func (d *DB) ListChatSessions(userID string) ([]models.ChatSession, error) {
 	}
 	// Sort by UpdatedAt desc (newest first)
 	sort.Slice(list, func(i, j int) bool {
-		return list[i].UpdatedAt > list[j].UpdatedAt
+		return models.TimestampAfter(list[i].UpdatedAt, list[j].UpdatedAt)
 	})
 	return list, nil
 }
@@ -770,17 +947,16 @@ func (d *DB) ListChatSessions(userID string) ([]models.ChatSession, error) {
 // If the session does not exist, it is created (so any session_id from the client is valid).
 func (d *DB) AppendChatMessage(userID, sessionID string, msg *models.StoredChatMessage) error {
 	if msg.Timestamp == "" {
-		msg.Timestamp = time.Now().Format(time.RFC3339)
+		msg.Timestamp = models.NowTimestamp()
 	}
-	ts := time.Now().UnixNano()
-	msgKey := []byte(fmt.Sprintf("%s%s:%s:%020d", chatMessagePrefix, userID, sessionID, ts))
+	msgKey := []byte(fmt.Sprintf("%s%s:%s:%s", chatMessagePrefix, userID, sessionID, models.OrderKey(time.Now())))
 	msgData, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
-	now := time.Now().Format(time.RFC3339)
+	now := models.NowTimestamp()
 	return d.badgerDB.Update(func(txn *badger.Txn) error {
-		if err := txn.Set(msgKey, msgData); err != nil {
+		if err := setValue(txn, msgKey, msgData); err != nil {
 			return err
 		}
 		sessKey := []byte(fmt.Sprintf("%s%s:%s", chatSessionPrefix, userID, sessionID))
@@ -802,7 +978,7 @@ func (d *DB) AppendChatMessage(userID, sessionID string, msg *models.StoredChatM
 			s.UpdatedAt = now
 		}
 		sessData, _ := json.Marshal(&s)
-		return txn.Set(sessKey, sessData)
+		return setValue(txn, sessKey, sessData)
 	})
 }
 
@@ -833,6 +1009,40 @@ func (d *DB) GetChatSessionMessages(userID, sessionID string) ([]models.StoredCh
 	return list, nil
 }
 
+// StoreChatSummary saves the running summary of a session's older messages.
+func (d *DB) StoreChatSummary(userID, sessionID string, s *models.ChatSummary) error {
+	key := []byte(fmt.Sprintf("%s%s:%s", chatSummaryPrefix, userID, sessionID))
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		return setValue(txn, key, data)
+	})
+}
+
+// GetChatSummary returns the running summary of a session, or nil if it has none
+func (d *DB) GetChatSummary(userID, sessionID string) (*models.ChatSummary, error) {
+	key := []byte(fmt.Sprintf("%s%s:%s", chatSummaryPrefix, userID, sessionID))
+	var s models.ChatSummary
+	err := d.badgerDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &s)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
 // UpdateChatSessionTitle updates session title and UpdatedAt.
 func (d *DB) UpdateChatSessionTitle(userID, sessionID, title string) error {
 	sess, err := d.GetChatSession(userID, sessionID)
@@ -840,7 +1050,7 @@ func (d *DB) UpdateChatSessionTitle(userID, sessionID, title string) error {
 		return fmt.Errorf("session not found")
 	}
 	sess.Title = title
-	sess.UpdatedAt = time.Now().Format(time.RFC3339)
+	sess.UpdatedAt = models.NowTimestamp()
 	return d.StoreChatSession(sess)
 }
 
@@ -851,12 +1061,23 @@ func (d *DB) DeleteChatSession(userID, sessionID string) error {
 		if err := txn.Delete(sessKey); err != nil {
 			return err
 		}
+		if err := txn.Delete([]byte(fmt.Sprintf("%s%s:%s", chatSummaryPrefix, userID, sessionID))); err != nil {
+			return err
+		}
 		prefix := []byte(fmt.Sprintf("%s%s:%s:", chatMessagePrefix, userID, sessionID))
 		opts := badger.DefaultIteratorOptions
 		opts.Prefix = prefix
 		it := txn.NewIterator(opts)
 		defer it.Close()
 		for it.Rewind(); it.Valid(); it.Next() {
+			var msg models.StoredChatMessage
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &msg)
+			}); err == nil && msg.ID != "" {
+				if err := txn.Delete([]byte(messageSQLPrefix + msg.ID)); err != nil {
+					return err
+				}
+			}
 			if err := txn.Delete(it.Item().KeyCopy(nil)); err != nil {
 				return err
 			}
@@ -865,3 +1086,341 @@ func (d *DB) DeleteChatSession(userID, sessionID string) error {
 	})
 }
 
+// Products index (one entry per HTML file in the products folder)
+
+const productPrefix = "product:"
+
+// StoreProduct adds or updates a product index entry
+func (d *DB) StoreProduct(info *models.ProductFileInfo) error {
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(info)
+		if err != nil {
+			return err
+		}
+		return setValue(txn, []byte(productPrefix+info.Filename), data)
+	})
+}
+
+// ListProducts returns all indexed products
+func (d *DB) ListProducts() ([]models.ProductFileInfo, error) {
+	var products []models.ProductFileInfo
+	err := d.badgerDB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(productPrefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var p models.ProductFileInfo
+				if err := json.Unmarshal(val, &p); err != nil {
+					return err
+				}
+				products = append(products, p)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// DeleteProduct removes a product index entry
+func (d *DB) DeleteProduct(filename string) error {
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(productPrefix + filename))
+	})
+}
+
+// Form proposal history (key: form_proposal:userID:proposalID)
+
+const formProposalPrefix = "form_proposal:"
+
+// StoreFormProposal saves or updates a form proposal
+func (d *DB) StoreFormProposal(p *models.FormProposal) error {
+	key := []byte(fmt.Sprintf("%s%s:%s", formProposalPrefix, p.UserID, p.ID))
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		return setValue(txn, key, data)
+	})
+}
+
+// GetFormProposal returns a form proposal by user and proposal ID
+func (d *DB) GetFormProposal(userID, proposalID string) (*models.FormProposal, error) {
+	key := []byte(fmt.Sprintf("%s%s:%s", formProposalPrefix, userID, proposalID))
+	var p *models.FormProposal
+	err := d.badgerDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			p = &models.FormProposal{}
+			return json.Unmarshal(val, p)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListFormProposals returns all form proposals for a user, newest first
+func (d *DB) ListFormProposals(userID string) ([]models.FormProposal, error) {
+	prefix := []byte(fmt.Sprintf("%s%s:", formProposalPrefix, userID))
+	var list []models.FormProposal
+	err := d.badgerDB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			var p models.FormProposal
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &p)
+			}); err != nil {
+				return err
+			}
+			list = append(list, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return models.TimestampAfter(list[i].UpdatedAt, list[j].UpdatedAt)
+	})
+	return list, nil
+}
+
+// Document extraction cache (key: doc_extract:sha256)
+
+const documentExtractionPrefix = "doc_extract:"
+
+// StoreDocumentExtraction saves the reader output for a document hash
+func (d *DB) StoreDocumentExtraction(e *models.DocumentExtraction) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		return setValue(txn, []byte(documentExtractionPrefix+e.Hash), data)
+	})
+}
+
+// GetDocumentExtraction returns the stored reader output for a document hash
+func (d *DB) GetDocumentExtraction(hash string) (*models.DocumentExtraction, error) {
+	var e *models.DocumentExtraction
+	err := d.badgerDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(documentExtractionPrefix + hash))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			e = &models.DocumentExtraction{}
+			return json.Unmarshal(val, e)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// SQL result cache (key: sql_result:sha256); entries expire via Badger TTL
+
+const sqlResultCachePrefix = "sql_result:"
+
+// StoreSQLResultCache saves a cache entry that expires after ttl
+func (d *DB) StoreSQLResultCache(e *models.SQLResultCacheEntry, ttl time.Duration) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		key := []byte(sqlResultCachePrefix + e.Hash)
+		if err := checkValueSize(key, data); err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(key, data).WithTTL(ttl))
+	})
+}
+
+// GetSQLResultCache returns the unexpired cache entry for a SQL hash
+func (d *DB) GetSQLResultCache(hash string) (*models.SQLResultCacheEntry, error) {
+	var e *models.SQLResultCacheEntry
+	err := d.badgerDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(sqlResultCachePrefix + hash))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			e = &models.SQLResultCacheEntry{}
+			return json.Unmarshal(val, e)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Attendance records (key: attendance:YYYY-MM-DD:orderkey:user_id), so a day's check-ins are one prefix scan
+
+const attendancePrefix = "attendance:"
+
+// StoreAttendance saves a voice check-in under its date
+func (d *DB) StoreAttendance(r *models.AttendanceRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	key := []byte(fmt.Sprintf("%s%s:%s:%s", attendancePrefix, r.Date, models.OrderKey(time.Now()), r.UserID))
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		return setValue(txn, key, data)
+	})
+}
+
+// ListAttendanceByDate returns the check-ins for a date (YYYY-MM-DD) in chronological order
+func (d *DB) ListAttendanceByDate(date string) ([]models.AttendanceRecord, error) {
+	var list []models.AttendanceRecord
+	err := d.badgerDB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(attendancePrefix + date + ":")
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			var r models.AttendanceRecord
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &r)
+			}); err != nil {
+				continue
+			}
+			list = append(list, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Result file tags (key: result_tags:filename); result files live on disk, so their tags are kept here
+
+const resultTagsPrefix = "result_tags:"
+
+// StoreResultTags sets the tags of a result file; an empty list removes the entry
+func (d *DB) StoreResultTags(filename string, tags []string) error {
+	key := []byte(resultTagsPrefix + filename)
+	if len(tags) == 0 {
+		return d.DeleteResultTags(filename)
+	}
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		return setValue(txn, key, data)
+	})
+}
+
+// GetAllResultTags returns the tags of every tagged result file, keyed by filename
+func (d *DB) GetAllResultTags() (map[string][]string, error) {
+	tags := make(map[string][]string)
+	err := d.badgerDB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(resultTagsPrefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			var t []string
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &t)
+			}); err != nil {
+				continue
+			}
+			tags[strings.TrimPrefix(string(it.Item().Key()), resultTagsPrefix)] = t
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// DeleteResultTags removes the tags of a result file
+func (d *DB) DeleteResultTags(filename string) error {
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(resultTagsPrefix + filename))
+	})
+}
+
+// SQL behind report chat messages (key: sql:messageID)
+
+const messageSQLPrefix = "sql:"
+
+// StoreMessageSQL saves the SQL record of a chat message
+func (d *DB) StoreMessageSQL(m *models.MessageSQL) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		return setValue(txn, []byte(messageSQLPrefix+m.MessageID), data)
+	})
+}
+
+// UpdateMessageSQL applies update to the SQL record of a chat message in one transaction
+func (d *DB) UpdateMessageSQL(messageID string, update func(m *models.MessageSQL)) error {
+	key := []byte(messageSQLPrefix + messageID)
+	return d.badgerDB.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		var m models.MessageSQL
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &m)
+		}); err != nil {
+			return err
+		}
+		update(&m)
+		data, err := json.Marshal(&m)
+		if err != nil {
+			return err
+		}
+		return setValue(txn, key, data)
+	})
+}
+
+// GetMessageSQL returns the SQL record of a chat message, or nil if it has none
+func (d *DB) GetMessageSQL(messageID string) (*models.MessageSQL, error) {
+	var m models.MessageSQL
+	err := d.badgerDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(messageSQLPrefix + messageID))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &m)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// cp1252 maps the 0x80-0x9F range of Windows-1252, where it differs from Latin-1
+// (0 marks bytes Windows-1252 leaves undefined; they decode as Latin-1).
+var cp1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// DecodeSQLText converts a reference SQL file to UTF-8. SSMS often saves scripts as UTF-16
+// (with or without a BOM) or in the Windows ANSI code page; reading those as raw bytes garbles
+// the prompt. Returns the text and the detected encoding ("utf-8", "utf-16le", "utf-16be" or "windows-1252").
+func DecodeSQLText(data []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		data = data[3:]
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false), "utf-16le"
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true), "utf-16be"
+	default:
+		if bigEndian, ok := looksLikeUTF16(data); ok {
+			if bigEndian {
+				return decodeUTF16(data, true), "utf-16be"
+			}
+			return decodeUTF16(data, false), "utf-16le"
+		}
+	}
+
+	if utf8.Valid(data) {
+		return string(data), "utf-8"
+	}
+
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+		if b >= 0x80 && b <= 0x9F && cp1252[b-0x80] != 0 {
+			runes[i] = cp1252[b-0x80]
+		}
+	}
+	return string(runes), "windows-1252"
+}
+
+// looksLikeUTF16 detects BOM-less UTF-16 from NUL bytes: mostly-ASCII SQL in UTF-16 has a zero
+// in every other byte, which never happens in UTF-8 or single-byte text.
+func looksLikeUTF16(data []byte) (bigEndian bool, ok bool) {
+	if len(data) < 4 || len(data)%2 != 0 {
+		return false, false
+	}
+	var evenZeros, oddZeros int
+	for i := 0; i < len(data); i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	pairs := len(data) / 2
+	switch {
+	case oddZeros*10 >= pairs*7 && evenZeros*10 < pairs:
+		return false, true
+	case evenZeros*10 >= pairs*7 && oddZeros*10 < pairs:
+		return true, true
+	}
+	return false, false
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+const encodingSQL = "SELECT name FROM students -- café 学生\r\nWHERE grade = 'A'"
+
+// encodeUTF16 encodes s as UTF-16 in the given byte order, prefixed with bom
+func encodeUTF16(s string, order binary.AppendByteOrder, bom []byte) []byte {
+	data := append([]byte{}, bom...)
+	for _, u := range utf16.Encode([]rune(s)) {
+		data = order.AppendUint16(data, u)
+	}
+	return data
+}
+
+func TestDecodeSQLText(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         []byte
+		wantText     string
+		wantEncoding string
+	}{
+		{"utf-16le with bom", encodeUTF16(encodingSQL, binary.LittleEndian, []byte{0xFF, 0xFE}), encodingSQL, "utf-16le"},
+		{"utf-16be with bom", encodeUTF16(encodingSQL, binary.BigEndian, []byte{0xFE, 0xFF}), encodingSQL, "utf-16be"},
+		{"utf-16le without bom", encodeUTF16(encodingSQL, binary.LittleEndian, nil), encodingSQL, "utf-16le"},
+		{"utf-16be without bom", encodeUTF16(encodingSQL, binary.BigEndian, nil), encodingSQL, "utf-16be"},
+		{"utf-8 with bom", append([]byte{0xEF, 0xBB, 0xBF}, encodingSQL...), encodingSQL, "utf-8"},
+		{"utf-8", []byte(encodingSQL), encodingSQL, "utf-8"},
+		{"windows-1252", []byte("SELECT '\x93hi\x94 caf\xe9 \x80 5'"), "SELECT '“hi” café € 5'", "windows-1252"},
+		{"empty", []byte{}, "", "utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, encoding := DecodeSQLText(tt.data)
+			if text != tt.wantText || encoding != tt.wantEncoding {
+				t.Errorf("DecodeSQLText(% x) = %q, %q; want %q, %q", tt.data, text, encoding, tt.wantText, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestLoadSQLFilesFromDirDecodesUTF16(t *testing.T) {
+	dir := t.TempDir()
+	data := encodeUTF16(encodingSQL, binary.LittleEndian, []byte{0xFF, 0xFE})
+	if err := os.WriteFile(filepath.Join(dir, "students.sql"), data, 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	var d DB
+	files, err := d.LoadSQLFilesFromDir(dir)
+	if err != nil {
+		t.Fatalf("LoadSQLFilesFromDir: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "students.sql" || files[0].Content != encodingSQL {
+		t.Errorf("LoadSQLFilesFromDir = %+v, want students.sql with %q", files, encodingSQL)
+	}
+}
@@ -1,36 +1,3446 @@
-// Package docs Code generated by swaggo/swag. DO NOT EDIT
-package docs
-
-import "github.com/swaggo/swag"
-
-const docTemplate = `{
-    "swagger": "2.0",
-    "info": {
-        "description": "Transfinder Form/Report Assistant API - Generate SQL queries using AI and execute them against SQL Server",
-        "title": "Transfinder Form/Report Assistant API",
-        "contact": {},
-        "version": "1.0"
-    },
-    "host": "localhost:9090",
-    "basePath": "/",
-    "paths": {}
-}`
-
-// SwaggerInfo holds exported Swagger Info so clients can modify it
-var SwaggerInfo = &swag.Spec{
-	Version:          "1.0",
-	Host:             "localhost:9090",
-	BasePath:         "/",
-	Schemes:          []string{},
-	Title:            "Transfinder Form/Report Assistant API",
-	Description:      "Transfinder Form/Report Assistant API - Generate SQL queries using AI and execute them against SQL Server",
-	InfoInstanceName: "swagger",
-	SwaggerTemplate:  docTemplate,
-	LeftDelim:        "{{",
-	RightDelim:       "}}",
-}
-
-func init() {
-	swag.Register(SwaggerInfo.InstanceName(), SwaggerInfo)
-}
-
+// Package docs Code generated by swaggo/swag. DO NOT EDIT
+package docs
+
+import "github.com/swaggo/swag"
+
+const docTemplate = `{
+    "schemes": {{ marshal .Schemes }},
+    "swagger": "2.0",
+    "info": {
+        "description": "{{escape .Description}}",
+        "title": "{{.Title}}",
+        "termsOfService": "http://swagger.io/terms/",
+        "contact": {
+            "name": "API Support",
+            "url": "http://www.swagger.io/support",
+            "email": "support@swagger.io"
+        },
+        "license": {
+            "name": "Apache 2.0",
+            "url": "http://www.apache.org/licenses/LICENSE-2.0.html"
+        },
+        "version": "{{.Version}}"
+    },
+    "host": "{{.Host}}",
+    "basePath": "{{.BasePath}}",
+    "paths": {
+        "/api/admin/cache-stats": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Get response cache hits, misses and hit rate per AI method (GenerateSQL, GenerateForm, GenerateChatResponse, ...) since startup",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "AI cache stats",
+                "responses": {
+                    "200": {
+                        "description": "Cache stats per method",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/ai.CacheMethodStats"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Admin token required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/errors": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Get the last N error-level log entries (newest first), including request IDs for failed requests",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Recent errors",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum entries to return (default: all buffered)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Recent errors",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/logging.Entry"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Admin token required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/attendance/summary": {
+            "get": {
+                "description": "Who checked in by voice on a day (default today), who with a voice profile did not, and counts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Voice Recognition"
+                ],
+                "summary": "Daily attendance summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Day as YYYY-MM-DD (default: today)",
+                        "name": "date",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Attendance roll",
+                        "schema": {
+                            "$ref": "#/definitions/models.AttendanceSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid date",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to load attendance",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/chat": {
+            "post": {
+                "description": "Send a message describing what SQL query you need, and the AI will generate it based on reference SQL files",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Generate SQL query from natural language",
+                "parameters": [
+                    {
+                        "description": "Chat request with message",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Generated SQL query",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChatResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "X-User-ID required (USER_ID_FALLBACK=reject)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Uploaded file too large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported file type",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/chat/export": {
+            "get": {
+                "description": "Download every chat session of the current user, oldest first, as a JSON document or a Markdown transcript",
+                "produces": [
+                    "application/json",
+                    "text/markdown"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Export chat history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "json (default) or md",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChatExport"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to load history",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/chat/messages/{id}/sql": {
+            "get": {
+                "description": "Returns the generated and executed SQL of a past report message, by the message_id from the chat response or the id of a stored message, with the report's status and, once it has run, its result file and HTML page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Get the SQL of a chat message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MessageSQL"
+                        }
+                    },
+                    "404": {
+                        "description": "No SQL for this message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/chat/sessions": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "List chat sessions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum sessions to return (default: all)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Sessions to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_ChatSession"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Create a new chat session",
+                "parameters": [
+                    {
+                        "description": "Optional: { \\",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChatSession"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/chat/sessions/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Get a chat session with messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ \\\"session\\\": ChatSession, \\\"messages\\\": StoredChatMessage[] }",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Update chat session title",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ \\",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChatSession"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Delete a chat session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/forms/answers": {
+            "get": {
+                "description": "Get a page of form answers, optionally filtered by form ID or user ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Form Answers"
+                ],
+                "summary": "List form answers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by form ID",
+                        "name": "form_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by user ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum answers to return (default: all)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Answers to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_FormAnswer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Submit a new form answer (public survey forms accept anonymous answers)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Form Answers"
+                ],
+                "summary": "Create form answer",
+                "parameters": [
+                    {
+                        "description": "Form answer",
+                        "name": "answer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.FormAnswer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FormAnswer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/forms/answers/{id}": {
+            "get": {
+                "description": "Get a form answer by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Form Answers"
+                ],
+                "summary": "Get form answer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form answer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FormAnswer"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an existing form answer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Form Answers"
+                ],
+                "summary": "Update form answer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form answer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated form answer",
+                        "name": "answer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.FormAnswer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FormAnswer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a form answer by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Form Answers"
+                ],
+                "summary": "Delete form answer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form answer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/forms/proposals": {
+            "get": {
+                "description": "List forms proposed in chat, including ones that were never confirmed. Filter with ?status=proposed|saved|discarded",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Forms"
+                ],
+                "summary": "List form proposals",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/models.FormProposal"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/forms/templates": {
+            "get": {
+                "description": "Get all form templates, optionally filtered by user type and tag",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Forms"
+                ],
+                "summary": "List form templates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by user type (student or staff)",
+                        "name": "user_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only templates with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.FormTemplate"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new form template for students or staff",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Forms"
+                ],
+                "summary": "Create form template",
+                "parameters": [
+                    {
+                        "description": "Form template",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.FormTemplate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FormTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/forms/templates/{id}": {
+            "get": {
+                "description": "Get a form template by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Forms"
+                ],
+                "summary": "Get form template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FormTemplate"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an existing form template",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Forms"
+                ],
+                "summary": "Update form template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated form template",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.FormTemplate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FormTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a form template by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Forms"
+                ],
+                "summary": "Delete form template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/forms/templates/{id}/tags": {
+            "put": {
+                "description": "Replace the tags of a form template. Tags are lower-cased and de-duplicated; an empty list clears them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Forms"
+                ],
+                "summary": "Set form template tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Form template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TagsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FormTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/forms/validate": {
+            "post": {
+                "description": "Check form template JSON for structural problems (missing keys, invalid field types) before saving",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Forms"
+                ],
+                "summary": "Validate form template",
+                "parameters": [
+                    {
+                        "description": "Form template JSON",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.FormTemplate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "valid flag and list of errors",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/openapi.json": {
+            "get": {
+                "description": "Get the generated Swagger/OpenAPI document so tooling can import the API",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "OpenAPI spec",
+                "responses": {
+                    "200": {
+                        "description": "OpenAPI document",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Spec not available",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/products/files": {
+            "get": {
+                "description": "Get a page of the HTML files in the products folder (newest first), served from the products index",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "List product files",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum files to return (default: all)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Files to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of product files",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_ProductFileInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list files",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/products/files/{filename}": {
+            "delete": {
+                "description": "Delete a product HTML file and its index entry",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Delete product file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product file name",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product deleted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filename",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete file",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/report-heads": {
+            "get": {
+                "description": "List the SQL heads (CTE blocks) that can be put in front of generated report SQL, selected per chat request with report_head. Heads are \u003cname\u003e.sql files in REPORT_HEADS_DIR plus the built-in \"student\" head; a file overrides the built-in head of the same name.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SQL"
+                ],
+                "summary": "List report heads",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReportHeadsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/results/delete": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Delete multiple result files (JSON/CSV) along with the HTML pages generated from them. Returns a status per file. Requires the admin token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Results"
+                ],
+                "summary": "Delete result files",
+                "parameters": [
+                    {
+                        "description": "Filenames to delete",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DeleteResultFilesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Per-file results",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/models.DeleteResultFileStatus"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Admin token required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "SQL Server not configured",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/results/diff": {
+            "post": {
+                "description": "Compare two result files (e.g. last week's and this week's report), matching rows by a key column that is unique in both. Returns added, removed and changed rows; columns present in only one file are listed but not compared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Results"
+                ],
+                "summary": "Diff result files",
+                "parameters": [
+                    {
+                        "description": "Files to compare",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ResultDiffRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ResultDiff"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, or key column missing or not unique",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "SQL Server not configured",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/results/file/{filename}": {
+            "get": {
+                "description": "Get the complete content of a specific result file by filename. Rows are arrays aligned\nwith columns; shape=objects returns each row as a {column: value} object instead\n(repeated column names get _2, _3, ... suffixes).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Results"
+                ],
+                "summary": "Get result file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Result file name",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Row shape: arrays (default) or objects",
+                        "name": "shape",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Result file content (models.ResultFileObjects for shape=objects)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ResultFile"
+                        }
+                    },
+                    "400": {
+                        "description": "Filename required or invalid shape",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "SQL Server not configured",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/results/file/{filename}/tags": {
+            "put": {
+                "description": "Replace the tags of a saved result file. Tags are lower-cased and de-duplicated; an empty list clears them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Results"
+                ],
+                "summary": "Set result file tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Result file name",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TagsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Filename and its tags",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to store tags",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "SQL Server not configured",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/results/files": {
+            "get": {
+                "description": "Get a page of the saved SQL query result files (JSON/CSV) with their tags, optionally only those with a tag",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Results"
+                ],
+                "summary": "List result files",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only files with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum files to return (default: all)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Files to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of result files",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_ResultFileInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list files",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "SQL Server not configured",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/results/generate-html": {
+            "post": {
+                "description": "Use AI to generate a professional HTML page displaying the content of a result file (theme: light, dark or branded with theme_colors)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Results"
+                ],
+                "summary": "Generate HTML page",
+                "parameters": [
+                    {
+                        "description": "HTML generation request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.GenerateHTMLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML page generated successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Result file not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to generate HTML",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "SQL Server not configured",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/results/html/{filename}": {
+            "get": {
+                "description": "Serve a previously generated HTML page from the sites directory",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Results"
+                ],
+                "summary": "Serve HTML page",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "HTML file name",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Filename required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "HTML file not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "SQL Server not configured",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sql/execute": {
+            "post": {
+                "description": "Execute a SQL query against the configured SQL Server and optionally save the results.\nWith preview (SELECT queries only), only the first few rows are fetched for a quick check and no result file is created.\nIdentical SELECT-only queries within SQL_RESULT_CACHE_TTL_SECONDS are served from the earlier result file (cached: true) unless fresh is set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SQL Execution"
+                ],
+                "summary": "Execute SQL query",
+                "parameters": [
+                    {
+                        "description": "SQL execution request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Query execution result",
+                        "schema": {
+                            "$ref": "#/definitions/models.SQLResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or statement type not allowed (SQL_ALLOWED_STATEMENTS)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Query execution error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "SQL Server not configured, or cannot log in / connect (the message says what to check)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sql/files": {
+            "get": {
+                "description": "Get a list of all SQL files stored as references",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SQL Files"
+                ],
+                "summary": "List SQL reference files",
+                "responses": {
+                    "200": {
+                        "description": "List of SQL file names",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to load files",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sql/upload": {
+            "post": {
+                "description": "Upload a SQL file that will be used as reference when generating SQL queries",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SQL Files"
+                ],
+                "summary": "Upload SQL reference file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "SQL file to upload",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File uploaded successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "No file provided",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to store file",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/version": {
+            "get": {
+                "description": "Get the version, git commit and build time injected via -ldflags (commit and time fall back to Go's embedded VCS info)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Build version",
+                "responses": {
+                    "200": {
+                        "description": "Build information",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/voice/profile/{user_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Delete a registered voice profile. Requires the admin token.",
+                "tags": [
+                    "Voice Recognition"
+                ],
+                "summary": "Delete voice profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Profile deleted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Admin token required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Profile not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete profile",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/voice/profiles": {
+            "get": {
+                "description": "Get registered voice profiles, ordered by name (default) or newest first, optionally filtered by name and paged",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Voice Recognition"
+                ],
+                "summary": "List voice profiles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive name filter (substring)",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order: name (default) or created (newest first)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum profiles to return (default: all)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Profiles to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of profiles (total counts matching profiles before paging)",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_VoiceProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list profiles",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/voice/profiles/merge": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Move all voice samples of source_user_id into target_user_id's profile and delete the source profile. For users registered twice. Requires the admin token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Voice Recognition"
+                ],
+                "summary": "Merge voice profiles",
+                "parameters": [
+                    {
+                        "description": "Profiles to merge",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.VoiceProfileMergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Merged profile",
+                        "schema": {
+                            "$ref": "#/definitions/models.VoiceProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Profile not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to merge profiles",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/voice/recognize": {
+            "post": {
+                "description": "Recognize a speaker and detect attendance intent from voice input. With dry_run, attendance is not logged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Voice Recognition"
+                ],
+                "summary": "Recognize voice",
+                "parameters": [
+                    {
+                        "description": "Voice recognition request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.VoiceRecognitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Recognition result",
+                        "schema": {
+                            "$ref": "#/definitions/models.VoiceRecognitionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to recognize voice",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/voice/register": {
+            "post": {
+                "description": "Register a user's voice sample for speaker recognition",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Voice Recognition"
+                ],
+                "summary": "Register voice profile",
+                "parameters": [
+                    {
+                        "description": "Voice registration request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.VoiceRegistrationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Voice profile created",
+                        "schema": {
+                            "$ref": "#/definitions/models.VoiceProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to register voice",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Check the health status of all services (database, AI service, SQL Server)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "Service health status",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{filename}": {
+            "get": {
+                "description": "Serve a file from the products folder. Only extensions listed in PRODUCT_EXTENSIONS are served (default: .html).",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Serve product file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product file name",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filename or file type not allowed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "ai.CacheMethodStats": {
+            "type": "object",
+            "properties": {
+                "hit_rate": {
+                    "description": "hits / (hits + misses), 0 before the first lookup",
+                    "type": "number"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "misses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "logging.Entry": {
+            "type": "object",
+            "properties": {
+                "component": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "level": {
+                    "type": "string"
+                },
+                "msg": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AttendanceSummary": {
+            "type": "object",
+            "properties": {
+                "absent": {
+                    "description": "Registered voice profiles with no check-in that day",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VoiceProfile"
+                    }
+                },
+                "absent_count": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "present": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AttendanceSummaryEntry"
+                    }
+                },
+                "present_count": {
+                    "type": "integer"
+                },
+                "registered_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AttendanceSummaryEntry": {
+            "type": "object",
+            "properties": {
+                "check_ins": {
+                    "type": "integer"
+                },
+                "first_seen": {
+                    "type": "string"
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChatExport": {
+            "type": "object",
+            "properties": {
+                "exported_at": {
+                    "type": "string"
+                },
+                "sessions": {
+                    "description": "Oldest session first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChatExportSession"
+                    }
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChatExportSession": {
+            "type": "object",
+            "properties": {
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StoredChatMessage"
+                    }
+                },
+                "session": {
+                    "$ref": "#/definitions/models.ChatSession"
+                }
+            }
+        },
+        "models.ChatRequest": {
+            "type": "object",
+            "properties": {
+                "audio_data": {
+                    "description": "Base64 encoded audio for voice input",
+                    "type": "string"
+                },
+                "audio_format": {
+                    "description": "\"wav\", \"mp3\", \"webm\", etc.",
+                    "type": "string"
+                },
+                "fresh": {
+                    "description": "Re-run report SQL even if a cached result exists",
+                    "type": "boolean"
+                },
+                "generate_html": {
+                    "description": "Generate an HTML page for a report result; unset uses REPORT_AUTO_HTML",
+                    "type": "boolean"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "report_head": {
+                    "description": "Named SQL head for report SQL (see GET /api/report-heads); empty uses DEFAULT_REPORT_HEAD",
+                    "type": "string"
+                },
+                "save_form": {
+                    "description": "Save a generated form as a template right away instead of proposing it",
+                    "type": "boolean"
+                },
+                "session_id": {
+                    "description": "Optional; empty means default session",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChatResponse": {
+            "type": "object",
+            "properties": {
+                "confirmation_card": {
+                    "$ref": "#/definitions/models.RegistrationConfirmationCard"
+                },
+                "form_json": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "description": "ID of the stored assistant message (GET /api/chat/messages/{id}/sql for reports)",
+                    "type": "string"
+                },
+                "proposed_form": {
+                    "$ref": "#/definitions/models.ProposedFormCard"
+                },
+                "report": {
+                    "description": "Set for report (SQL) requests",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ReportResponse"
+                        }
+                    ]
+                },
+                "research_content": {
+                    "type": "string"
+                },
+                "response": {
+                    "type": "string"
+                },
+                "sql": {
+                    "type": "string"
+                },
+                "warning": {
+                    "description": "Part of the request failed (e.g. the generated page could not be saved)",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChatSession": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeleteResultFileStatus": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeleteResultFilesRequest": {
+            "type": "object",
+            "required": [
+                "filenames"
+            ],
+            "properties": {
+                "filenames": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.FormAnswer": {
+            "type": "object",
+            "properties": {
+                "answers": {
+                    "description": "Field name -\u003e answer value",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "form_id": {
+                    "description": "Reference to FormTemplate",
+                    "type": "string"
+                },
+                "form_name": {
+                    "description": "Form name (denormalized for easy access)",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier",
+                    "type": "string"
+                },
+                "submitted_at": {
+                    "description": "Submission timestamp",
+                    "type": "string"
+                },
+                "submitted_by": {
+                    "description": "User who submitted",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "Student or staff ID",
+                    "type": "string"
+                },
+                "user_type": {
+                    "description": "\"student\" or \"staff\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.FormField": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "description": "Display label (e.g., \"Full Name\")",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Field identifier (e.g., \"name\", \"age\")",
+                    "type": "string"
+                },
+                "options": {
+                    "description": "Options for select/radio fields",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "placeholder": {
+                    "description": "Placeholder text",
+                    "type": "string"
+                },
+                "required": {
+                    "description": "Whether field is required",
+                    "type": "boolean"
+                },
+                "type": {
+                    "description": "Field type: \"text\", \"email\", \"number\", \"tel\", \"date\", \"select\", etc.",
+                    "type": "string"
+                }
+            }
+        },
+        "models.FormProposal": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "form": {
+                    "description": "latest version of the proposed form",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.FormTemplate"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "saved_form_id": {
+                    "description": "template ID once saved",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "\"proposed\" | \"saved\" | \"discarded\"",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FormTemplate": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Creation timestamp",
+                    "type": "string"
+                },
+                "created_by": {
+                    "description": "User who created the form",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Form description",
+                    "type": "string"
+                },
+                "fields": {
+                    "description": "Form fields",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FormField"
+                    }
+                },
+                "id": {
+                    "description": "Unique identifier",
+                    "type": "string"
+                },
+                "identity_field": {
+                    "description": "Field whose answer is the ID of the person registered (e.g. \"student_id\"); answers otherwise belong to the submitter",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Form name (e.g., \"Student Registration Form\")",
+                    "type": "string"
+                },
+                "public": {
+                    "description": "Public surveys accept anonymous answers",
+                    "type": "boolean"
+                },
+                "tags": {
+                    "description": "Labels for organizing templates",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated_at": {
+                    "description": "Last update timestamp",
+                    "type": "string"
+                },
+                "user_type": {
+                    "description": "\"student\" or \"staff\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.GenerateHTMLRequest": {
+            "type": "object",
+            "properties": {
+                "filename": {
+                    "type": "string"
+                },
+                "theme": {
+                    "description": "\"light\" (default), \"dark\" or \"branded\"",
+                    "type": "string",
+                    "example": "dark"
+                },
+                "theme_colors": {
+                    "description": "Colors for the \"branded\" theme",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ThemeColors"
+                        }
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MessageSQL": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Why the report failed, or why it has no HTML page",
+                    "type": "string"
+                },
+                "final_sql": {
+                    "description": "SQL actually executed (report head prepended when needed)",
+                    "type": "string"
+                },
+                "generated_sql": {
+                    "description": "SQL as generated by the model",
+                    "type": "string"
+                },
+                "html_path": {
+                    "description": "URL path of the HTML page generated from the result",
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "$ref": "#/definitions/models.SQLMetadata"
+                },
+                "prompt": {
+                    "description": "User request the SQL was generated for",
+                    "type": "string"
+                },
+                "result_filename": {
+                    "description": "Saved result file, once the query has run",
+                    "type": "string"
+                },
+                "row_count": {
+                    "type": "integer"
+                },
+                "session_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Report status (ReportStatus*)",
+                    "type": "string"
+                },
+                "truncated": {
+                    "description": "Rows beyond SQL_MAX_ROWS were dropped",
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Page-models_ChatSession": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChatSession"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Page-models_FormAnswer": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FormAnswer"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Page-models_ProductFileInfo": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProductFileInfo"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Page-models_ResultFileInfo": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ResultFileInfo"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Page-models_VoiceProfile": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VoiceProfile"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ProductFileInfo": {
+            "type": "object",
+            "properties": {
+                "filename": {
+                    "type": "string"
+                },
+                "modified": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "\"form\" or \"result\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ProposedFormCard": {
+            "type": "object",
+            "properties": {
+                "form_template": {
+                    "$ref": "#/definitions/models.FormTemplate"
+                }
+            }
+        },
+        "models.RegistrationConfirmationCard": {
+            "type": "object",
+            "properties": {
+                "answers": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "fields": {
+                    "description": "name + label for display",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FormField"
+                    }
+                },
+                "form_name": {
+                    "type": "string"
+                },
+                "user_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReportHeadInfo": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReportHeadsResponse": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "type": "string"
+                },
+                "heads": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReportHeadInfo"
+                    }
+                }
+            }
+        },
+        "models.ReportResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "$ref": "#/definitions/models.SQLMetadata"
+                },
+                "sql": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ResultDiff": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "additionalProperties": true
+                    }
+                },
+                "changed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ResultRowChange"
+                    }
+                },
+                "columns_added": {
+                    "description": "Columns only in New (not compared)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "columns_removed": {
+                    "description": "Columns only in Old (not compared)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "key_column": {
+                    "type": "string"
+                },
+                "new": {
+                    "type": "string"
+                },
+                "old": {
+                    "type": "string"
+                },
+                "removed": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "additionalProperties": true
+                    }
+                },
+                "unchanged": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ResultDiffRequest": {
+            "type": "object",
+            "required": [
+                "key_column",
+                "new",
+                "old"
+            ],
+            "properties": {
+                "key_column": {
+                    "description": "Column whose value identifies a row in both files; must be unique",
+                    "type": "string"
+                },
+                "new": {
+                    "description": "Later result file",
+                    "type": "string"
+                },
+                "old": {
+                    "description": "Earlier result file, e.g. last week's report",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ResultFile": {
+            "type": "object",
+            "properties": {
+                "columns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "row_count": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {}
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "truncated": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ResultFileInfo": {
+            "type": "object",
+            "properties": {
+                "filename": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "modified": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ResultRowChange": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "description": "Column -\u003e old and new value",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.ResultValueChange"
+                    }
+                },
+                "key": {}
+            }
+        },
+        "models.ResultValueChange": {
+            "type": "object",
+            "properties": {
+                "new": {},
+                "old": {}
+            }
+        },
+        "models.SQLMetadata": {
+            "type": "object",
+            "properties": {
+                "confidence": {
+                    "description": "0-100: share of the tables read that the reference SQL also uses",
+                    "type": "integer"
+                },
+                "generated_at": {
+                    "type": "string"
+                },
+                "head_prepended": {
+                    "description": "The report head (CTEs) was added in front of the generated SQL",
+                    "type": "boolean"
+                },
+                "model": {
+                    "description": "AI model that wrote the SQL",
+                    "type": "string"
+                },
+                "reference_files": {
+                    "description": "Reference SQL files included in the prompt",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "report_head": {
+                    "description": "Name of the report head used",
+                    "type": "string"
+                },
+                "unknown_tables": {
+                    "description": "Tables read that no reference SQL uses",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.SQLResult": {
+            "type": "object",
+            "properties": {
+                "cached": {
+                    "description": "Served from a previous run's result file",
+                    "type": "boolean"
+                },
+                "columns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "partial": {
+                    "description": "Rows were collected before the query failed",
+                    "type": "boolean"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {}
+                    }
+                },
+                "truncated": {
+                    "description": "Rows beyond the SQL_MAX_ROWS cap were dropped",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.StoredChatMessage": {
+            "type": "object",
+            "properties": {
+                "confirmation_card": {
+                    "$ref": "#/definitions/models.RegistrationConfirmationCard"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Set on assistant messages; keys the message's SQL record",
+                    "type": "string"
+                },
+                "proposed_form": {
+                    "$ref": "#/definitions/models.ProposedFormCard"
+                },
+                "research_content": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "\"user\" | \"assistant\" | \"error\"",
+                    "type": "string"
+                },
+                "sql": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TagsRequest": {
+            "type": "object",
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "finance",
+                        "monthly"
+                    ]
+                }
+            }
+        },
+        "models.ThemeColors": {
+            "type": "object",
+            "properties": {
+                "background": {
+                    "type": "string",
+                    "example": "#FFFFFF"
+                },
+                "primary": {
+                    "type": "string",
+                    "example": "#0055A4"
+                },
+                "text": {
+                    "type": "string",
+                    "example": "#222222"
+                }
+            }
+        },
+        "models.VoiceProfile": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "voice_samples": {
+                    "description": "Base64 encoded audio samples or file paths",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.VoiceProfileMergeRequest": {
+            "type": "object",
+            "required": [
+                "source_user_id",
+                "target_user_id"
+            ],
+            "properties": {
+                "source_user_id": {
+                    "description": "Profile that is merged in and deleted",
+                    "type": "string"
+                },
+                "target_user_id": {
+                    "description": "Profile that is kept",
+                    "type": "string"
+                }
+            }
+        },
+        "models.VoiceRecognitionRequest": {
+            "type": "object",
+            "required": [
+                "audio_data"
+            ],
+            "properties": {
+                "audio_data": {
+                    "description": "Base64 encoded audio",
+                    "type": "string"
+                },
+                "audio_format": {
+                    "description": "\"wav\", \"mp3\", \"webm\", etc.",
+                    "type": "string"
+                },
+                "dry_run": {
+                    "description": "Recognize only; don't log attendance (for UI testing and calibration)",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.VoiceRecognitionResponse": {
+            "type": "object",
+            "properties": {
+                "intent": {
+                    "description": "\"attendance\", \"punch_in\", etc.",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Why recognition failed (VoiceReason*)",
+                    "type": "string"
+                },
+                "recognized": {
+                    "type": "boolean"
+                },
+                "transcript": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.VoiceRegistrationRequest": {
+            "type": "object",
+            "required": [
+                "audio_data",
+                "name"
+            ],
+            "properties": {
+                "audio_data": {
+                    "description": "Base64 encoded audio",
+                    "type": "string"
+                },
+                "audio_format": {
+                    "description": "\"wav\", \"mp3\", \"webm\", etc.",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "type": "apiKey",
+            "name": "X-Admin-Token",
+            "in": "header"
+        }
+    }
+}`
+
+// SwaggerInfo holds exported Swagger Info so clients can modify it
+var SwaggerInfo = &swag.Spec{
+	Version:          "1.0",
+	Host:             "localhost:9090",
+	BasePath:         "/",
+	Schemes:          []string{"http", "https"},
+	Title:            "Transfinder Form/Report Assistant API",
+	Description:      "Transfinder Form/Report Assistant API - Generate SQL queries using AI and execute them against SQL Server",
+	InfoInstanceName: "swagger",
+	SwaggerTemplate:  docTemplate,
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
+}
+
+func init() {
+	swag.Register(SwaggerInfo.InstanceName(), SwaggerInfo)
+}
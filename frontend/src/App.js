@@ -84,7 +84,7 @@ function App() {
       const res = await axios.get(`${API_BASE_URL}/api/chat/sessions`, {
         headers: { 'X-User-ID': 'admin' }
       });
-      setSessions(Array.isArray(res.data) ? res.data : []);
+      setSessions(Array.isArray(res.data?.items) ? res.data.items : []);
     } catch (e) {
       console.error('Load sessions error:', e);
       setSessions([]);
@@ -40,7 +40,7 @@ export const getFormAnswers = async (formId = '', userId = '') => {
   if (params.length > 0) url += '?' + params.join('&');
   
   const response = await axios.get(url);
-  return response.data;
+  return response.data.items;
 };
 
 export const getFormAnswer = async (id) => {
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"idongivaflyinfa/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminTokenHeader is an alternative to "Authorization: Bearer <token>" for admin requests
+const AdminTokenHeader = "X-Admin-Token"
+
+// RequireAdmin protects admin routes with a shared token. Requests without the token get 401;
+// when no token is configured the admin API is disabled and every request gets 503.
+func RequireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Admin API is disabled (ADMIN_API_TOKEN not set)"})
+			return
+		}
+		provided := c.GetHeader(AdminTokenHeader)
+		if auth := c.GetHeader("Authorization"); provided == "" && strings.HasPrefix(auth, "Bearer ") {
+			provided = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Admin token required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RecentErrorsHandler returns the most recent error-level log entries
+// @Summary      Recent errors
+// @Description  Get the last N error-level log entries (newest first), including request IDs for failed requests
+// @Tags         Admin
+// @Produce      json
+// @Security     AdminToken
+// @Param        limit  query     int  false  "Maximum entries to return (default: all buffered)"
+// @Success      200    {object}  map[string][]logging.Entry  "Recent errors"
+// @Failure      400    {object}  map[string]string           "Invalid limit"
+// @Failure      401    {object}  map[string]string           "Admin token required"
+// @Router       /api/admin/errors [get]
+func (h *Handlers) RecentErrorsHandler(c *gin.Context) {
+	limit := 0
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative integer"})
+			return
+		}
+		limit = n
+	}
+
+	c.JSON(http.StatusOK, gin.H{"errors": logging.RecentErrors(limit)})
+}
+
+// CacheStatsHandler returns AI response cache effectiveness
+// @Summary      AI cache stats
+// @Description  Get response cache hits, misses and hit rate per AI method (GenerateSQL, GenerateForm, GenerateChatResponse, ...) since startup
+// @Tags         Admin
+// @Produce      json
+// @Security     AdminToken
+// @Success      200  {object}  map[string][]ai.CacheMethodStats  "Cache stats per method"
+// @Failure      401  {object}  map[string]string                 "Admin token required"
+// @Router       /api/admin/cache-stats [get]
+func (h *Handlers) CacheStatsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"methods": h.aiService.CacheStats()})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"idongivaflyinfa/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isAttendanceIntent reports whether a recognized voice intent is a check-in
+func isAttendanceIntent(intent string) bool {
+	return intent == "attendance" || intent == "punch_in" || intent == "here"
+}
+
+// logAttendance records a recognized check-in, both as a structured attendance record and,
+// as before, in the user's chat history
+func (h *Handlers) logAttendance(response *models.VoiceRecognitionResponse) {
+	log.Printf("[VOICE] Attendance logged for: %s (%s)", response.Name, response.UserID)
+	if h.cfg.DemoMode {
+		return
+	}
+	now := time.Now()
+	record := &models.AttendanceRecord{
+		UserID:    response.UserID,
+		Name:      response.Name,
+		Intent:    response.Intent,
+		Date:      now.Format("2006-01-02"),
+		Timestamp: models.Timestamp(now),
+	}
+	if err := h.db.StoreAttendance(record); err != nil {
+		log.Printf("[VOICE] Error storing attendance for %s: %v", response.UserID, err)
+	}
+	attendanceMsg := fmt.Sprintf("%s - %s", response.Name, now.Format("2006-01-02 15:04:05"))
+	h.db.StoreChatHistory(response.UserID, "Voice attendance", attendanceMsg)
+}
+
+// AttendanceSummaryHandler returns the attendance roll for one day
+// @Summary      Daily attendance summary
+// @Description  Who checked in by voice on a day (default today), who with a voice profile did not, and counts
+// @Tags         Voice Recognition
+// @Produce      json
+// @Param        date  query     string  false  "Day as YYYY-MM-DD (default: today)"
+// @Success      200   {object}  models.AttendanceSummary  "Attendance roll"
+// @Failure      400   {object}  map[string]string         "Invalid date"
+// @Failure      500   {object}  map[string]string         "Failed to load attendance"
+// @Router       /api/attendance/summary [get]
+func (h *Handlers) AttendanceSummaryHandler(c *gin.Context) {
+	date := c.Query("date")
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be in YYYY-MM-DD format"})
+		return
+	}
+
+	records, err := h.db.ListAttendanceByDate(date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load attendance: " + err.Error()})
+		return
+	}
+	profiles, err := h.db.GetAllVoiceProfiles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load voice profiles: " + err.Error()})
+		return
+	}
+
+	// Records are in chronological order, so the first one per user is the first check-in
+	byUser := make(map[string]*models.AttendanceSummaryEntry)
+	var order []string
+	for _, r := range records {
+		entry, ok := byUser[r.UserID]
+		if !ok {
+			entry = &models.AttendanceSummaryEntry{UserID: r.UserID, Name: r.Name, FirstSeen: r.Timestamp}
+			byUser[r.UserID] = entry
+			order = append(order, r.UserID)
+		}
+		entry.LastSeen = r.Timestamp
+		entry.CheckIns++
+	}
+
+	summary := models.AttendanceSummary{
+		Date:            date,
+		Present:         make([]models.AttendanceSummaryEntry, 0, len(order)),
+		Absent:          []models.VoiceProfile{},
+		RegisteredCount: len(profiles),
+	}
+	for _, userID := range order {
+		summary.Present = append(summary.Present, *byUser[userID])
+	}
+	for _, p := range profiles {
+		if _, ok := byUser[p.UserID]; !ok {
+			p.VoiceSamples = nil
+			summary.Absent = append(summary.Absent, p)
+		}
+	}
+	sort.Slice(summary.Absent, func(i, j int) bool {
+		return strings.ToLower(summary.Absent[i].Name) < strings.ToLower(summary.Absent[j].Name)
+	})
+	summary.PresentCount = len(summary.Present)
+	summary.AbsentCount = len(summary.Absent)
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -1,19 +1,19 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
-	"idongivaflyinfa/config"
 	"idongivaflyinfa/models"
 	"idongivaflyinfa/validation"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // ChatHandler handles chat requests to generate SQL queries
@@ -26,31 +26,66 @@ import (
 // @Header       200      {string}  X-User-ID          "Optional user ID for chat history"
 // @Success      200      {object}  models.ChatResponse "Generated SQL query"
 // @Failure      400      {object}  map[string]string   "Invalid request"
+// @Failure      401      {object}  map[string]string   "X-User-ID required (USER_ID_FALLBACK=reject)"
+// @Failure      413      {object}  map[string]string   "Uploaded file too large"
+// @Failure      415      {object}  map[string]string   "Unsupported file type"
 // @Failure      500      {object}  map[string]string   "Internal server error"
 // @Router       /api/chat [post]
 func (h *Handlers) ChatHandler(c *gin.Context) {
-	userID := c.GetHeader("X-User-ID")
-	if userID == "" {
-		userID = "admin"
+	userID, ok := h.requireUserID(c)
+	if !ok {
+		return
 	}
 
 	var req models.ChatRequest
 	contentType := c.GetHeader("Content-Type")
 	isMultipart := strings.Contains(contentType, "multipart/form-data")
 	if isMultipart {
+		// Bound the whole body before it is parsed (and spooled to disk): maxUploadFiles documents of
+		// MaxUploadSize each is the most a valid request can carry
+		if h.cfg.MaxUploadSize > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadFiles*h.cfg.MaxUploadSize)
+		}
+		form, err := c.MultipartForm()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request is too large (%d MB max)", tooLarge.Limit>>20)})
+			return
+		}
 		message := c.PostForm("message")
 		req.SessionID = c.PostForm("session_id")
-		file, err := c.FormFile("file") // returns *multipart.FileHeader
-		if err == nil && file != nil {
+		// One or more files under "file" (e.g. several scanned pages); they are processed as one document
+		var files []*multipart.FileHeader
+		if err == nil && form != nil {
+			files = form.File["file"]
+		}
+		if len(files) > 0 {
+			if len(files) > maxUploadFiles {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many files (%d max)", maxUploadFiles)})
+				return
+			}
+			// Reject oversized documents before any of them is read for extraction
+			for _, file := range files {
+				if h.cfg.MaxUploadSize > 0 && file.Size > h.cfg.MaxUploadSize {
+					c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File %s is too large (%d MB max)", file.Filename, h.cfg.MaxUploadSize>>20)})
+					return
+				}
+			}
 			// File upload flow: extract content, classify intent, form/research/summary
-			response, err := h.handleChatWithFile(c, userID, message, file)
+			response, err := h.handleChatWithFile(c, userID, message, files)
+			if errors.Is(err, errUnsupportedFileType) {
+				c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("%v. Upload a PDF or an image (JPEG, PNG, GIF, WebP, BMP).", err)})
+				return
+			}
 			if err != nil {
 				log.Printf("[CHAT HANDLER] File flow error: %v", err)
 				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process file: %v", err)})
 				return
 			}
 			sessionID := resolveSessionID(req.SessionID)
-			_ = h.db.EnsureDefaultChatSession(userID)
+			if !h.cfg.DemoMode {
+				_ = h.db.EnsureDefaultChatSession(userID)
+			}
 			persistChatExchange(h, userID, sessionID, message, response)
 			c.JSON(http.StatusOK, response)
 			return
@@ -63,11 +98,22 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 		}
 	}
 
+	// Nothing to act on: no text, no audio and (checked above) no file
+	if strings.TrimSpace(req.Message) == "" && req.AudioData == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A message, audio_data or an uploaded file is required"})
+		return
+	}
+
 	sessionID := resolveSessionID(req.SessionID)
-	_ = h.db.EnsureDefaultChatSession(userID)
+	if !h.cfg.DemoMode {
+		_ = h.db.EnsureDefaultChatSession(userID)
+	}
+
+	state := h.chatRouteState(userID)
+	route := routeChatIntent(req.Message, req.AudioData != "", state)
 
-	// PRIORITY 0.3: Pending proposed form — user confirming to save
-	if pending := getPendingForm(userID); pending != nil && isFormConfirmMessage(req.Message) {
+	switch route {
+	case routeSavePendingForm:
 		response, err := h.savePendingFormAndClear(c, userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -78,10 +124,16 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 			c.JSON(http.StatusOK, response)
 			return
 		}
-	}
 
-	// PRIORITY 0: Check if this is a voice input
-	if req.AudioData != "" {
+	case routeRefinePendingForm:
+		if pending := getPendingForm(userID); pending != nil {
+			response := h.refinePendingForm(c.Request.Context(), userID, pending, req.Message)
+			persistChatExchange(h, userID, sessionID, req.Message, response)
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+	case routeVoice:
 		log.Printf("[CHAT HANDLER] Voice input detected from user: %s", userID)
 		response, err := h.HandleVoiceChat(c, userID, req.AudioData)
 		if err != nil {
@@ -94,8 +146,12 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 		return
 	}
 
-	// PRIORITY 0.5: Correct spelling errors in user message
-	correctedMessage, err := h.aiService.CorrectSpelling(req.Message)
+	// Correct spelling errors in user message, then route on the corrected text
+	correctedMessage, err := h.correctSpelling(c.Request.Context(), req.Message)
+	if c.Request.Context().Err() != nil {
+		log.Printf("[CHAT HANDLER] Request canceled by client, stopping")
+		return
+	}
 	if err != nil {
 		log.Printf("[CHAT HANDLER] Error correcting spelling: %v, using original message", err)
 		correctedMessage = req.Message
@@ -104,66 +160,29 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 		req.Message = correctedMessage
 	}
 
-	log.Printf("[CHAT HANDLER] User: %s, Message: %s", userID, req.Message)
-
-	// PRIORITY 1: Check if user has an active complaint conversation (simplified check)
-	// Just check if there's any complaint state with a conversation_id - if yes, continue the session
-	complaintState, err := h.db.GetComplaintStateByUserID(userID)
-	if err == nil && complaintState != nil {
-		// If we have a conversation_id and it's not complete, continue the session
-		if complaintState.ConversationID != "" && complaintState.Step != "complete" {
-			log.Printf("[CHAT HANDLER] User %s has active complaint conversation (conversationID: %s, step: %s, exchanges: %d)",
-				userID, complaintState.ConversationID, complaintState.Step, complaintState.ExchangeCount)
-			response, err := h.handleComplaintFlow(c, userID, req.Message)
-			if err != nil {
-				log.Printf("[CHAT HANDLER] Error continuing complaint flow: %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process complaint: %v", err)})
-				return
-			}
-			persistChatExchange(h, userID, sessionID, req.Message, response)
-			c.JSON(http.StatusOK, response)
-			return
-		} else if complaintState.Step == "complete" {
-			log.Printf("[CHAT HANDLER] Complaint session is complete for user %s, starting new flow", userID)
-		}
-	} else {
-		log.Printf("[CHAT HANDLER] No complaint state found for user %s (error: %v)", userID, err)
+	route = routeTextIntent(req.Message, state)
+	log.Printf("[CHAT HANDLER] User: %s, Route: %s, Message: %s", userID, route, req.Message)
+
+	// A form proposal left unconfirmed when another conversation starts is dropped, so a later
+	// "yes" meant for that conversation can't save it
+	if state.HasPendingForm && (route == routeComplaint || route == routeRegistration) {
+		h.discardPendingForm(userID)
 	}
 
-	// PRIORITY 2: Check if this is a NEW complaint request
-	if isComplaintRequest(req.Message) {
-		log.Printf("[CHAT HANDLER] Detected NEW complaint request from user %s", userID)
+	switch route {
+	case routeComplaint:
 		response, err := h.handleComplaintFlow(c, userID, req.Message)
 		if err != nil {
 			log.Printf("[CHAT HANDLER] Error handling complaint flow: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process complaint: %v", err)})
+			status, message := h.complaintErrorResponse(err)
+			c.JSON(status, gin.H{"error": message})
 			return
 		}
 		persistChatExchange(h, userID, sessionID, req.Message, response)
 		c.JSON(http.StatusOK, response)
 		return
-	}
 
-	// PRIORITY 2.5: Registration flow (register student / similar) — active session first
-	regState, regErr := h.db.GetRegistrationStateByUserID(userID)
-	if regErr == nil && regState != nil && regState.Step != "complete" && regState.Step != "" {
-		log.Printf("[CHAT HANDLER] User %s has active registration session (form: %s)", userID, regState.FormName)
-		response, err := h.handleRegistrationFlow(c, userID, req.Message)
-		if err != nil {
-			log.Printf("[CHAT HANDLER] Error in registration flow: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process registration: %v", err)})
-			return
-		}
-		if response != nil {
-			persistChatExchange(h, userID, sessionID, req.Message, response)
-			c.JSON(http.StatusOK, response)
-			return
-		}
-	}
-
-	// PRIORITY 3: New registration intent (e.g. "I want to register a student")
-	if isRegisterStudentRequest(req.Message) {
-		log.Printf("[CHAT HANDLER] Detected register-student (or similar) request from user %s", userID)
+	case routeRegistration:
 		response, err := h.handleRegistrationFlow(c, userID, req.Message)
 		if err != nil {
 			log.Printf("[CHAT HANDLER] Error in registration flow: %v", err)
@@ -175,6 +194,8 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 			c.JSON(http.StatusOK, response)
 			return
 		}
+		// Registration was cancelled; treat the message as a normal request
+		route = routeGenerationIntent(req.Message)
 	}
 
 	// Load SQL files (only if not in complaint or registration flow)
@@ -189,22 +210,18 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 		}
 	}
 
-	// Check if this is a form generation request  TODO: change this to AI decision
-	lowerPrompt := strings.ToLower(req.Message)
-	isFormRequest := (strings.Contains(lowerPrompt, "create") && strings.Contains(lowerPrompt, "form")) ||
-		strings.Contains(lowerPrompt, "i want a new form") ||
-		strings.Contains(lowerPrompt, "generate a form") ||
-		strings.Contains(lowerPrompt, "make a form") ||
-		strings.Contains(lowerPrompt, "build a form") ||
-		(strings.Contains(lowerPrompt, "form") && (strings.Contains(lowerPrompt, "new") || strings.Contains(lowerPrompt, "create")))
-
 	var responseText string
 	var sql string
 	var formJSON string
+	var warning string
+	var sqlMeta *models.SQLMetadata
+	var finalSQL string
+	var proposedForm *models.ProposedFormCard
+	var startReport func(messageID string) // runs the report SQL once the message is stored
 
-	if isFormRequest {
+	if route == routeForm {
 		// Generate form JSON
-		formJSON, err = h.aiService.GenerateForm(req.Message)
+		formJSON, err = h.aiService.GenerateForm(c.Request.Context(), req.Message)
 		if err != nil {
 			log.Printf("Error generating form: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to generate form: %v", err)})
@@ -212,42 +229,42 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 		}
 
 		// Generate form HTML page
-		html, err := h.aiService.GenerateFormHTMLPage(formJSON)
+		html, err := h.aiService.GenerateFormHTMLPage(c.Request.Context(), formJSON)
 		if err != nil {
 			log.Printf("Error generating form HTML: %v", err)
 			// Continue even if HTML generation fails
+		} else if h.cfg.DemoMode {
+			log.Printf("Demo mode, form HTML not saved")
 		} else {
-			// Save HTML to products folder
-			productsDir := "products"
-			if err := os.MkdirAll(productsDir, 0755); err != nil {
-				log.Printf("Error creating products directory: %v", err)
+			// Save HTML to products folder, with a timestamped filename
+			timestamp := time.Now().Format("20060102_150405")
+			htmlPath, err := h.saveProduct(fmt.Sprintf("form_%s.html", timestamp), []byte(html))
+			if err != nil {
+				log.Printf("Error saving form HTML page: %v", err)
+				warning = "The form page could not be saved to products: " + err.Error()
 			} else {
-				// Generate HTML filename with timestamp
-				timestamp := time.Now().Format("20060102_150405")
-				htmlFilename := fmt.Sprintf("form_%s.html", timestamp)
-				htmlPath := filepath.Join(productsDir, htmlFilename)
-
-				if err := os.WriteFile(htmlPath, []byte(html), 0644); err != nil {
-					log.Printf("Error saving form HTML file: %v", err)
-				} else {
-					log.Printf("Form HTML page saved to: %s", htmlPath)
-				}
+				log.Printf("Form HTML page saved to: %s", htmlPath)
 			}
 		}
 
 		responseText = fmt.Sprintf("Here's the form JSON based on your request:\n\n%s", formJSON)
+
+		// Offer the form as a template: saved now with save_form, otherwise pending until the user says "save this form"
+		if template, err := formTemplateFromGeneratedJSON(formJSON); err != nil {
+			log.Printf("Generated form can't be saved as a template: %v", err)
+		} else {
+			template.UserType = h.resolveFormUserType(template, req.Message)
+			if req.SaveForm {
+				reply, _ := h.storeChatForm(userID, template)
+				responseText += "\n\n" + reply
+			} else {
+				h.proposeForm(userID, template)
+				proposedForm = &models.ProposedFormCard{FormTemplate: *template}
+				responseText += "\n\nReply **Save this form** to keep it as a form template, or tell me what to change."
+			}
+		}
 	} else {
-		// Check if the prompt contains report-related keywords
-		hasReportKeywords := strings.Contains(lowerPrompt, "generate") ||
-			strings.Contains(lowerPrompt, "create report") ||
-			strings.Contains(lowerPrompt, "i want a report") ||
-			strings.Contains(lowerPrompt, "i need to make") ||
-			strings.Contains(lowerPrompt, "i need a report") ||
-			strings.Contains(lowerPrompt, "make a report") ||
-			strings.Contains(lowerPrompt, "generate a report") ||
-			strings.Contains(lowerPrompt, "create a report")
-
-		if !hasReportKeywords {
+		if route == routeChat {
 			// Check if the prompt makes sense (not gibberish)
 			if !validation.IsValidPrompt(req.Message) {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "The request appears to be invalid or gibberish. Please provide a meaningful message."})
@@ -255,7 +272,13 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 			}
 
 			// If it's a valid prompt but not a report request, treat it as a general chat
-			chatResponse, err := h.aiService.GenerateChatResponse(req.Message)
+			var chatResponse string
+			summary, history := h.chatContext(c.Request.Context(), userID, sessionID)
+			if summary == "" && len(history) == 0 {
+				chatResponse, err = h.aiService.GenerateChatResponse(c.Request.Context(), req.Message)
+			} else {
+				chatResponse, err = h.aiService.GenerateChatResponseWithContext(c.Request.Context(), summary, history, req.Message)
+			}
 			if err != nil {
 				log.Printf("Error generating chat response: %v", err)
 				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to generate response: %v", err)})
@@ -274,8 +297,14 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 			return
 		}
 
+		headName, head, err := h.reportHead(req.ReportHead)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Generate SQL using AI
-		sql, err = h.aiService.GenerateSQL(req.Message, sqlFiles)
+		sql, err = h.aiService.GenerateSQL(c.Request.Context(), req.Message, sqlFiles)
 		if err != nil {
 			log.Printf("Error generating SQL: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to generate SQL: %v", err)})
@@ -293,94 +322,50 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 
 		// Check if SQL starts with "with" (case-insensitive)
 		sqlTrimmed := strings.TrimSpace(sql)
-		finalSQL := sql
-		if !strings.HasPrefix(strings.ToLower(sqlTrimmed), "with") {
-			// Prepend StudentReportSqlHead
-			finalSQL = config.StudentReportSqlHead + "\n" + sql
-			log.Printf("Prepended StudentReportSqlHead to SQL")
+		finalSQL = sql
+		headPrepended := !strings.HasPrefix(strings.ToLower(sqlTrimmed), "with")
+		if headPrepended {
+			finalSQL = head + "\n" + sql
+			log.Printf("Prepended report head %q to SQL", headName)
+		}
+
+		sqlMeta = &models.SQLMetadata{
+			Model:          h.aiService.ModelName(),
+			ReferenceFiles: make([]string, len(sqlFiles)),
+			HeadPrepended:  headPrepended,
+			ReportHead:     headName,
+			GeneratedAt:    models.NowTimestamp(),
+		}
+		for i, f := range sqlFiles {
+			sqlMeta.ReferenceFiles[i] = f.Name
 		}
+		sqlMeta.Confidence, sqlMeta.UnknownTables = sqlConfidence(finalSQL, head, sqlFiles)
+		needsReview := sqlMeta.Confidence < h.cfg.SQLAutoExecuteMinConfidence
 
 		responseText = fmt.Sprintf("Here's the SQL query based on your request:\n\n%s", sql)
 		log.Printf("Prepared response text, length: %d", len(responseText))
 
 		// Execute SQL and save result in background (don't block response)
 		// Check if SQL service is available before starting goroutine
-		if h.sqlService == nil {
+		if h.cfg.DemoMode {
+			log.Printf("Demo mode, skipping background SQL execution and HTML generation")
+		} else if needsReview {
+			log.Printf("SQL confidence %d is below %d (unknown tables: %v), returning it for review instead of executing",
+				sqlMeta.Confidence, h.cfg.SQLAutoExecuteMinConfidence, sqlMeta.UnknownTables)
+			responseText += fmt.Sprintf("\n\nThis query was not run automatically because it uses tables the reference queries don't: %s. Please review it before running it.",
+				strings.Join(sqlMeta.UnknownTables, ", "))
+		} else if h.sqlService == nil {
 			log.Printf("SQL service is nil, skipping background SQL execution and HTML generation")
 		} else {
 			// Capture variables needed for the goroutine
-			sqlService := h.sqlService
-			aiService := h.aiService
-			go func() {
-				log.Printf("Background goroutine started for SQL execution")
-				defer func() {
-					if r := recover(); r != nil {
-						log.Printf("Panic in background SQL execution: %v", r)
-					}
-				}()
-
-				resultsStorage := sqlService.GetResultsStorage()
-				if resultsStorage == nil {
-					log.Printf("Results storage is nil, skipping background execution")
-					return
-				}
-
-				log.Printf("Starting SQL execution with query length: %d", len(finalSQL))
-				// Execute SQL and save as JSON
-				sqlResult, err := sqlService.ExecuteQueryWithSave(finalSQL, "json", true)
-				if err != nil {
-					log.Printf("Error executing SQL: %v", err)
-					return
-				}
-				if sqlResult.Error != "" {
-					log.Printf("SQL execution error: %s", sqlResult.Error)
-					return
-				}
-				if sqlResult.Filename == "" {
-					log.Printf("No filename returned from SQL execution")
-					return
-				}
-				log.Printf("SQL executed successfully, result file: %s", sqlResult.Filename)
-
-				// Load the ResultFile
-				resultFile, err := resultsStorage.GetResultFile(sqlResult.Filename)
-				if err != nil {
-					log.Printf("Error loading result file: %v", err)
-					return
-				}
-				log.Printf("Result file loaded, rows: %d", resultFile.RowCount)
-
-				// Generate HTML page
-				title := fmt.Sprintf("SQL Query Results - %s", sqlResult.Filename)
-				log.Printf("Generating HTML page with title: %s", title)
-				html, err := aiService.GenerateHTMLPage(resultFile, title)
-				if err != nil {
-					log.Printf("Error generating HTML: %v", err)
-					return
-				}
-				log.Printf("HTML generated successfully, length: %d", len(html))
-
-				// Save HTML to products folder
-				productsDir := "products"
-				if err := os.MkdirAll(productsDir, 0755); err != nil {
-					log.Printf("Error creating products directory: %v", err)
-					return
-				}
-				// Generate HTML filename from result filename
-				htmlFilename := sqlResult.Filename
-				ext := filepath.Ext(htmlFilename)
-				if ext != "" {
-					htmlFilename = htmlFilename[:len(htmlFilename)-len(ext)]
-				}
-				htmlFilename += ".html"
-				htmlPath := filepath.Join(productsDir, htmlFilename)
-
-				if err := os.WriteFile(htmlPath, []byte(html), 0644); err != nil {
-					log.Printf("Error saving HTML file: %v", err)
-				} else {
-					log.Printf("HTML page saved successfully to: %s", htmlPath)
-				}
-			}()
+			fresh := req.Fresh
+			generateHTML := h.cfg.ReportAutoHTML
+			if req.GenerateHTML != nil {
+				generateHTML = *req.GenerateHTML
+			}
+			startReport = func(messageID string) {
+				go h.executeReport(messageID, finalSQL, fresh, generateHTML)
+			}
 		}
 	}
 
@@ -391,8 +376,42 @@ func (h *Handlers) ChatHandler(c *gin.Context) {
 	if formJSON != "" {
 		response.FormJSON = formJSON
 	}
+	response.Warning = warning
+	response.ProposedForm = proposedForm
+	if sql != "" {
+		response.Report = &models.ReportResponse{SQL: sql, Status: models.ReportStatusRunning, Metadata: sqlMeta}
+		if h.cfg.DemoMode {
+			response.Report.Status = models.ReportStatusFailed
+			response.Report.Error = demoModeMessage
+		} else if sqlMeta != nil && sqlMeta.Confidence < h.cfg.SQLAutoExecuteMinConfidence {
+			response.Report.Status = models.ReportStatusReview
+		} else if h.sqlService == nil {
+			response.Report.Status = models.ReportStatusFailed
+			response.Report.Error = "SQL Server service is not configured"
+		}
+	}
 
 	persistChatExchange(h, userID, sessionID, req.Message, &response)
+	if sql != "" && response.MessageID != "" {
+		record := &models.MessageSQL{
+			MessageID:    response.MessageID,
+			UserID:       userID,
+			SessionID:    sessionID,
+			Prompt:       req.Message,
+			GeneratedSQL: sql,
+			FinalSQL:     finalSQL,
+			Metadata:     sqlMeta,
+			Status:       response.Report.Status,
+			Error:        response.Report.Error,
+			CreatedAt:    models.NowTimestamp(),
+		}
+		if err := h.db.StoreMessageSQL(record); err != nil {
+			log.Printf("[CHAT] Failed to store SQL for message %s: %v", response.MessageID, err)
+		}
+	}
+	if startReport != nil {
+		startReport(response.MessageID)
+	}
 	log.Printf("Sending response to client")
 	c.JSON(http.StatusOK, response)
 	log.Printf("Response sent successfully")
@@ -407,9 +426,10 @@ func resolveSessionID(s string) string {
 	return s
 }
 
-// persistChatExchange appends user and assistant messages to the session.
+// persistChatExchange appends user and assistant messages to the session and sets
+// resp.MessageID to the ID given to the assistant message.
 func persistChatExchange(h *Handlers, userID, sessionID string, userMessage string, resp *models.ChatResponse) {
-	if resp == nil {
+	if resp == nil || h.cfg.DemoMode {
 		return
 	}
 	userMsg := &models.StoredChatMessage{Role: "user", Content: userMessage}
@@ -418,6 +438,7 @@ func persistChatExchange(h *Handlers, userID, sessionID string, userMessage stri
 		return
 	}
 	assistantMsg := &models.StoredChatMessage{
+		ID:              uuid.New().String(),
 		Role:            "assistant",
 		Content:         resp.Response,
 		SQL:             resp.SQL,
@@ -427,6 +448,8 @@ func persistChatExchange(h *Handlers, userID, sessionID string, userMessage stri
 	}
 	if err := h.db.AppendChatMessage(userID, sessionID, assistantMsg); err != nil {
 		log.Printf("[CHAT] Failed to append assistant message to session: %v", err)
+		return
 	}
+	resp.MessageID = assistantMsg.ID
 }
 
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"idongivaflyinfa/models"
+)
+
+// maxSummarizedMessages bounds the messages sent in one summary call. When more than this have
+// dropped out of the window since the last summary (e.g. the first summary of a long session),
+// only the most recent of them are folded in.
+const maxSummarizedMessages = 40
+
+// chatContext returns the prior conversation of a session for general chat: the last
+// ChatContextTurns turns verbatim, plus a summary of anything older. Both are empty when
+// history is disabled or the session has none.
+func (h *Handlers) chatContext(ctx context.Context, userID, sessionID string) (string, []models.StoredChatMessage) {
+	if h.cfg.ChatContextTurns <= 0 {
+		return "", nil
+	}
+	stored, err := h.db.GetChatSessionMessages(userID, sessionID)
+	if err != nil {
+		log.Printf("[CHAT] Failed to load session history: %v", err)
+		return "", nil
+	}
+
+	var messages []models.StoredChatMessage
+	for _, m := range stored {
+		if (m.Role == "user" || m.Role == "assistant") && m.Content != "" {
+			messages = append(messages, m)
+		}
+	}
+
+	// A turn is a user message and the reply to it
+	keep := h.cfg.ChatContextTurns * 2
+	if len(messages) <= keep {
+		return "", messages
+	}
+	older, recent := messages[:len(messages)-keep], messages[len(messages)-keep:]
+	return h.olderSummary(ctx, userID, sessionID, older), recent
+}
+
+// olderSummary returns the summary of older, updating the session's stored running summary with
+// the messages added since it was made. It returns "" when summarizing fails.
+func (h *Handlers) olderSummary(ctx context.Context, userID, sessionID string, older []models.StoredChatMessage) string {
+	stored, err := h.db.GetChatSummary(userID, sessionID)
+	if err != nil {
+		log.Printf("[CHAT] Failed to load conversation summary: %v", err)
+	}
+	if stored == nil || stored.Messages > len(older) {
+		stored = &models.ChatSummary{}
+	}
+	if stored.Messages == len(older) {
+		return stored.Summary
+	}
+
+	pending := older[stored.Messages:]
+	if len(pending) > maxSummarizedMessages {
+		log.Printf("[CHAT] Summarizing the last %d of %d unsummarized messages", maxSummarizedMessages, len(pending))
+		pending = pending[len(pending)-maxSummarizedMessages:]
+	}
+	summary, err := h.aiService.SummarizeConversation(ctx, stored.Summary, pending)
+	if err != nil {
+		log.Printf("[CHAT] Failed to summarize earlier conversation, sending recent turns only: %v", err)
+		return ""
+	}
+	if !h.cfg.DemoMode {
+		next := &models.ChatSummary{Summary: summary, Messages: len(older), UpdatedAt: models.NowTimestamp()}
+		if err := h.db.StoreChatSummary(userID, sessionID, next); err != nil {
+			log.Printf("[CHAT] Failed to store conversation summary: %v", err)
+		}
+	}
+	return summary
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"idongivaflyinfa/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportChatHistoryHandler downloads all of the current user's chat sessions as one file.
+// @Summary      Export chat history
+// @Description  Download every chat session of the current user, oldest first, as a JSON document or a Markdown transcript
+// @Tags         Chat
+// @Produce      json
+// @Produce      text/markdown
+// @Param        format  query     string  false  "json (default) or md"
+// @Success      200     {object}  models.ChatExport
+// @Failure      400     {object}  map[string]string  "Unknown format"
+// @Failure      500     {object}  map[string]string  "Failed to load history"
+// @Router       /api/chat/export [get]
+func (h *Handlers) ExportChatHistoryHandler(c *gin.Context) {
+	userID, ok := h.requireUserID(c)
+	if !ok {
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	if format == "markdown" {
+		format = "md"
+	}
+	if format != "json" && format != "md" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or md"})
+		return
+	}
+
+	// Sessions are stored per user, so only the caller's own sessions are read
+	sessions, err := h.db.ListChatSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return models.TimestampAfter(sessions[j].CreatedAt, sessions[i].CreatedAt)
+	})
+
+	export := models.ChatExport{
+		UserID:     userID,
+		ExportedAt: models.NowTimestamp(),
+		Sessions:   make([]models.ChatExportSession, 0, len(sessions)),
+	}
+	for _, sess := range sessions {
+		messages, err := h.db.GetChatSessionMessages(userID, sess.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to load session %s: %v", sess.ID, err)})
+			return
+		}
+		export.Sessions = append(export.Sessions, models.ChatExportSession{Session: sess, Messages: messages})
+	}
+
+	filename := fmt.Sprintf("chat_history_%s.%s", time.Now().Format("20060102_150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "md" {
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(chatExportMarkdown(&export)))
+		return
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// chatExportMarkdown renders an export as a readable transcript
+func chatExportMarkdown(export *models.ChatExport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Chat history\n\nExported %s\n", export.ExportedAt)
+	for _, s := range export.Sessions {
+		fmt.Fprintf(&b, "\n## %s\n\nStarted %s\n", s.Session.Title, s.Session.CreatedAt)
+		for _, m := range s.Messages {
+			speaker := "Assistant"
+			switch m.Role {
+			case "user":
+				speaker = "You"
+			case "error":
+				speaker = "Error"
+			}
+			fmt.Fprintf(&b, "\n**%s** (%s):\n\n%s\n", speaker, m.Timestamp, m.Content)
+			if m.SQL != "" {
+				fmt.Fprintf(&b, "\n```sql\n%s\n```\n", m.SQL)
+			}
+		}
+	}
+	return b.String()
+}
@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"mime/multipart"
-	"path"
+	"net/http"
 	"strings"
-	"time"
+	"unicode"
 
 	"idongivaflyinfa/models"
 
@@ -14,39 +21,186 @@ import (
 	"github.com/google/uuid"
 )
 
-// handleChatWithFile processes an uploaded image or PDF: extracts content, classifies intent, then form/research/summary.
-func (h *Handlers) handleChatWithFile(c *gin.Context, userID, userMessage string, fileHeader *multipart.FileHeader) (*models.ChatResponse, error) {
+// errUnsupportedFileType is returned for uploads that are neither a PDF nor a supported image.
+var errUnsupportedFileType = errors.New("unsupported file type")
+
+// allowedUploadTypes are the detected content types the document readers accept.
+var allowedUploadTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/bmp":       true,
+}
+
+// detectUploadType sniffs the uploaded file's content type and rewinds it for the reader.
+func detectUploadType(file multipart.File) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("read uploaded file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("rewind uploaded file: %w", err)
+	}
+	contentType := strings.TrimSpace(strings.Split(http.DetectContentType(head[:n]), ";")[0])
+	if !allowedUploadTypes[contentType] {
+		return contentType, fmt.Errorf("%w: %s", errUnsupportedFileType, contentType)
+	}
+	return contentType, nil
+}
+
+// errDocumentReader wraps failures from the external image/PDF reader services.
+var errDocumentReader = errors.New("document reader failed")
+
+// maxUploadFiles caps how many images (e.g. scanned pages) one chat message may carry.
+const maxUploadFiles = 20
+
+// extractUpload runs one uploaded file through the matching reader, reusing a stored extraction
+// for identical content unless the client sends no_cache=true.
+func (h *Handlers) extractUpload(c *gin.Context, fileHeader *multipart.FileHeader, systemPrompt string) (extractedText, aiResult string, err error) {
 	file, err := fileHeader.Open()
 	if err != nil {
-		return nil, fmt.Errorf("open uploaded file: %w", err)
+		return "", "", fmt.Errorf("open uploaded file: %w", err)
 	}
 	defer file.Close()
 
-	ext := strings.ToLower(path.Ext(fileHeader.Filename))
-	// Always use summarize for extraction; user message is used later for intent and research.
-	systemPrompt := "Summarize the following content clearly and concisely."
+	contentType, err := detectUploadType(file)
+	if err != nil {
+		return "", "", err
+	}
+	fileContent, err := io.ReadAll(file)
+	if err != nil {
+		return "", "", fmt.Errorf("read uploaded file: %w", err)
+	}
 
-	var extractedText, aiResult string
-	isPDF := ext == ".pdf"
-	if isPDF {
-		extractedText, aiResult, err = h.ReadPDFAndProcess(file, fileHeader.Filename, systemPrompt)
+	useCache := h.cfg.CacheDocumentExtractions && c.PostForm("no_cache") != "true"
+	sum := sha256.Sum256(append([]byte(systemPrompt+"\x00"), fileContent...))
+	hash := hex.EncodeToString(sum[:])
+	if useCache {
+		if cached, err := h.db.GetDocumentExtraction(hash); err == nil && cached != nil {
+			log.Printf("[CHAT FILE] Reusing stored extraction for %s (%s)", fileHeader.Filename, hash[:12])
+			return cached.ExtractedText, cached.AIResult, nil
+		}
+	}
+
+	if contentType == "application/pdf" {
+		extractedText, aiResult, err = h.ReadPDFAndProcess(c.Request.Context(), bytes.NewReader(fileContent), fileHeader.Filename, systemPrompt)
 	} else {
-		extractedText, aiResult, err = h.ReadImageAndProcess(file, fileHeader.Filename, systemPrompt)
+		extractedText, aiResult, err = h.ReadImageAndProcess(c.Request.Context(), bytes.NewReader(fileContent), fileHeader.Filename, systemPrompt)
 	}
 	if err != nil {
-		log.Printf("[CHAT FILE] Extract/process error: %v", err)
-		return &models.ChatResponse{
-			Response: fmt.Sprintf("Could not process the uploaded file: %v. Make sure the Image Reader / PDF Reader service is running at %s.", err, h.externalAPIBase),
-		}, nil
+		return "", "", fmt.Errorf("%w: %v", errDocumentReader, err)
+	}
+
+	if h.cfg.CacheDocumentExtractions && !h.cfg.DemoMode {
+		if err := h.db.StoreDocumentExtraction(&models.DocumentExtraction{
+			Hash:          hash,
+			Filename:      fileHeader.Filename,
+			ContentType:   contentType,
+			ExtractedText: extractedText,
+			AIResult:      aiResult,
+			CreatedAt:     models.NowTimestamp(),
+		}); err != nil {
+			log.Printf("[CHAT FILE] Error storing extraction: %v", err)
+		}
+	}
+	return extractedText, aiResult, nil
+}
+
+// extractionInstructionPrefixes mark a message that tells the reader what to pull out of the document.
+var extractionInstructionPrefixes = []string{
+	"extract", "transcribe", "convert", "list", "pull out", "give me the", "copy", "translate", "read out", "get the", "output",
+}
+
+// documentFormPhrases ask for a form built from the document ("convert this into a form"); such
+// messages go through intent classification rather than steering the reader
+var documentFormPhrases = []string{
+	"into a form", "as a form", "to a form", "a form from", "form from this", "fillable form",
+	"create a form", "make a form", "build a form", "generate a form", "new form",
+}
+
+// isExtractionInstruction returns true if the message is a specific extraction request
+// (e.g. "extract the table as CSV") rather than a question about the document or a form request.
+func isExtractionInstruction(message string) bool {
+	s := strings.TrimSpace(strings.ToLower(message))
+	if s == "" {
+		return false
+	}
+	if containsAny(" "+wordText(s)+" ", paddedWords(documentFormPhrases, "")) {
+		return false
+	}
+	for _, p := range extractionInstructionPrefixes {
+		if strings.HasPrefix(s, p+" ") {
+			return true
+		}
+	}
+	for _, format := range []string{"as csv", "as json", "as a table", "as markdown", "as a list"} {
+		if strings.Contains(s, format) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleChatWithFile processes uploaded images or a PDF: extracts content, classifies intent, then form/research/summary.
+// Multiple files (e.g. pages of a scanned form) are extracted in order and combined into one document.
+func (h *Handlers) handleChatWithFile(c *gin.Context, userID, userMessage string, fileHeaders []*multipart.FileHeader) (*models.ChatResponse, error) {
+	// Summarize by default; an explicit system_prompt field or a specific extraction request
+	// ("extract the table as CSV") steers the reader instead, and its output is returned as is.
+	systemPrompt := h.cfg.SummarizePrompt
+	if systemPrompt == "" {
+		systemPrompt = defaultSummarizePrompt
+	}
+	steered := false
+	if override := strings.TrimSpace(c.PostForm("system_prompt")); override != "" {
+		systemPrompt, steered = override, true
+	} else if isExtractionInstruction(userMessage) {
+		systemPrompt, steered = strings.TrimSpace(userMessage), true
+	}
+
+	var extractedText, aiResult string
+	if len(fileHeaders) == 1 {
+		var err error
+		extractedText, aiResult, err = h.extractUpload(c, fileHeaders[0], systemPrompt)
+		if errors.Is(err, errDocumentReader) {
+			log.Printf("[CHAT FILE] Extract/process error: %v", err)
+			return &models.ChatResponse{
+				Response: fmt.Sprintf("Could not process the uploaded file: %v. Make sure the Image Reader / PDF Reader service is running at %s.", err, h.externalAPIBase),
+			}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var texts, results []string
+		for i, fileHeader := range fileHeaders {
+			text, result, err := h.extractUpload(c, fileHeader, systemPrompt)
+			if errors.Is(err, errDocumentReader) {
+				log.Printf("[CHAT FILE] Extract/process error on page %d: %v", i+1, err)
+				return &models.ChatResponse{
+					Response: fmt.Sprintf("Could not process page %d (%s): %v. Make sure the Image Reader / PDF Reader service is running at %s.", i+1, fileHeader.Filename, err, h.externalAPIBase),
+				}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			header := fmt.Sprintf("--- Page %d (%s) ---\n", i+1, fileHeader.Filename)
+			texts = append(texts, header+text)
+			results = append(results, header+result)
+		}
+		extractedText = strings.Join(texts, "\n\n")
+		aiResult = strings.Join(results, "\n\n")
 	}
 
 	// Default when user didn't ask for anything specific: just return the summary
-	if strings.TrimSpace(userMessage) == "" {
+	if strings.TrimSpace(userMessage) == "" || steered {
 		return &models.ChatResponse{Response: aiResult}, nil
 	}
 
 	// Classify intent: FORM, RESEARCH, or SUMMARY
-	intent, err := h.aiService.ClassifyDocumentIntent(userMessage, extractedText, aiResult)
+	intent, err := h.aiService.ClassifyDocumentIntent(c.Request.Context(), userMessage, extractedText, aiResult)
 	if err != nil {
 		log.Printf("[CHAT FILE] Classify intent error: %v, defaulting to SUMMARY", err)
 		intent = "SUMMARY"
@@ -54,14 +208,14 @@ func (h *Handlers) handleChatWithFile(c *gin.Context, userID, userMessage string
 
 	switch intent {
 	case "FORM":
-		template, err := h.aiService.GenerateFormTemplateFromContent(aiResult+"\n\n"+extractedText, userMessage)
+		template, err := h.aiService.GenerateFormTemplateFromContent(c.Request.Context(), aiResult+"\n\n"+extractedText, userMessage)
 		if err != nil {
 			log.Printf("[CHAT FILE] Generate form from content error: %v", err)
 			return &models.ChatResponse{
 				Response: "I extracted the content but couldn't generate a form from it. You can try: \"Create a form from this\" or describe the form you want.",
 			}, nil
 		}
-		setPendingForm(userID, template)
+		h.proposeForm(userID, template)
 		return &models.ChatResponse{
 			Response:     "I've created a form from the document. **Review the form below** and reply **Yes** to save it, or tell me what to change.",
 			ProposedForm: &models.ProposedFormCard{FormTemplate: *template},
@@ -71,7 +225,7 @@ func (h *Handlers) handleChatWithFile(c *gin.Context, userID, userMessage string
 		if userMessage != "" {
 			gatherPrompt = userMessage + "\n\nContext from document: " + aiResult
 		}
-		content, err := h.Gather(gatherPrompt, 10)
+		content, err := h.Gather(c.Request.Context(), gatherPrompt, 10)
 		if err != nil {
 			log.Printf("[CHAT FILE] Gathering error: %v", err)
 			return &models.ChatResponse{
@@ -102,36 +256,113 @@ func isFormConfirmMessage(message string) bool {
 	return false
 }
 
-// savePendingFormAndClear saves the pending form template and clears state. Maps "general" to "student" for API.
+var (
+	formEditVerbs = []string{"add", "remove", "delete", "rename", "change", "make", "replace", "update", "move", "drop", "set", "include"}
+	// formPartNouns name parts of a form; an edit must mention one of them or one of the form's fields
+	formPartNouns = []string{"field", "question", "option", "label", "section", "title", "description", "placeholder", "dropdown", "checkbox", "required", "optional"}
+)
+
+// isFormChangeRequest returns true if the message looks like an edit to a proposed form: an edit
+// verb plus a form part or one of fields (names or labels), e.g. "add a phone field",
+// "rename section to Contact Info", "make email optional".
+func isFormChangeRequest(message string, fields []string) bool {
+	s := " " + wordText(message) + " "
+	if strings.TrimSpace(s) == "" || !containsAny(s, paddedWords(formEditVerbs, "")) {
+		return false
+	}
+	if containsAny(s, paddedWords(formPartNouns, "")) || containsAny(s, paddedWords(formPartNouns, "s")) {
+		return true
+	}
+	for _, f := range fields {
+		if f = wordText(f); f != "" && strings.Contains(s, " "+f+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// wordText lower-cases s and joins its words with single spaces ("Phone_Number?" -> "phone number")
+func wordText(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// paddedWords returns " word<suffix> " for each word, for whole-word matching against padded wordText
+func paddedWords(words []string, suffix string) []string {
+	padded := make([]string, len(words))
+	for i, w := range words {
+		padded[i] = " " + w + suffix + " "
+	}
+	return padded
+}
+
+// refineFailedResponse keeps the original proposal on the table when a refinement fails.
+const refineFailedResponse = "I couldn't update the form. Reply **Yes** to save it as is, or describe the change again."
+
+// refinePendingForm applies a change request to the user's pending form and proposes the result again.
+func (h *Handlers) refinePendingForm(ctx context.Context, userID string, pending *models.FormTemplate, instruction string) *models.ChatResponse {
+	existing, err := json.Marshal(pending)
+	if err != nil {
+		log.Printf("[CHAT] Marshal pending form error: %v", err)
+		return &models.ChatResponse{Response: refineFailedResponse}
+	}
+	refined, err := h.aiService.RefineForm(ctx, string(existing), instruction)
+	if err != nil {
+		log.Printf("[CHAT] Refine form error: %v", err)
+		return &models.ChatResponse{Response: refineFailedResponse}
+	}
+	var template models.FormTemplate
+	if err := json.Unmarshal([]byte(refined), &template); err != nil {
+		log.Printf("[CHAT] Refined form is not a valid template: %v", err)
+		return &models.ChatResponse{Response: refineFailedResponse}
+	}
+	if template.UserType != "student" && template.UserType != "staff" {
+		template.UserType = "general"
+	}
+	template.Public = pending.Public
+	h.updateProposedForm(userID, &template)
+	return &models.ChatResponse{
+		Response:     "I've updated the form. **Review the form below** and reply **Yes** to save it, or tell me what else to change.",
+		ProposedForm: &models.ProposedFormCard{FormTemplate: template},
+	}
+}
+
+// savePendingFormAndClear saves the pending form template and, once it is stored, clears state. A user type other than
+// student/staff (e.g. "general") is inferred from the form, falling back to DEFAULT_FORM_USER_TYPE.
 func (h *Handlers) savePendingFormAndClear(c *gin.Context, userID string) (*models.ChatResponse, error) {
 	template := getPendingForm(userID)
 	if template == nil {
 		return nil, nil
 	}
-	clearPendingForm(userID)
+	proposalID := getPendingProposalID(userID)
 
-	userType := template.UserType
-	if userType != "student" && userType != "staff" {
-		userType = "student"
+	reply, saved := h.storeChatForm(userID, template)
+	if !saved {
+		// Keep the proposal so the user can still confirm (or change) it
+		return &models.ChatResponse{Response: reply}, nil
 	}
-	template.UserType = userType
+	clearPendingForm(userID)
+	h.setProposalStatus(userID, proposalID, "saved", template.ID)
+	return &models.ChatResponse{Response: reply}, nil
+}
+
+// storeChatForm stores a form created in chat as a new template of userID and returns the chat
+// reply, and whether the template was stored (not in demo mode or on a storage error).
+func (h *Handlers) storeChatForm(userID string, template *models.FormTemplate) (string, bool) {
+	template.UserType = h.resolveFormUserType(template)
 	template.ID = uuid.New().String()
-	now := time.Now().Format(time.RFC3339)
+	now := models.NowTimestamp()
 	template.CreatedAt = now
 	template.UpdatedAt = now
-	createdBy := c.GetHeader("X-User-ID")
-	if createdBy == "" {
-		createdBy = "admin"
-	}
-	template.CreatedBy = createdBy
+	template.CreatedBy = userID
 
+	if h.cfg.DemoMode {
+		return demoModeMessage, false
+	}
 	if err := h.db.StoreFormTemplate(template); err != nil {
 		log.Printf("[CHAT] Save proposed form error: %v", err)
-		return &models.ChatResponse{
-			Response: "Failed to save the form: " + err.Error(),
-		}, nil
+		return "Failed to save the form: " + err.Error(), false
 	}
-	return &models.ChatResponse{
-		Response: fmt.Sprintf("Form **%s** has been saved. You can use it under **Forms** and collect answers under **Form Answers**.", template.Name),
-	}, nil
+	return fmt.Sprintf("Form **%s** has been saved. You can use it under **Forms** and collect answers under **Form Answers**.", template.Name), true
 }
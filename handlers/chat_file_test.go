@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"idongivaflyinfa/db"
+	"idongivaflyinfa/models"
+)
+
+func TestSavePendingFormKeepsProposalWhenStoreFails(t *testing.T) {
+	store := openTestDB(t, t.TempDir())
+	defer store.Close()
+	h := &Handlers{db: store}
+
+	const userID = "user-3"
+	template := &models.FormTemplate{
+		Name:        "Field trip permission",
+		UserType:    "student",
+		Description: strings.Repeat("x", db.MaxValueSize+1),
+		Fields:      []models.FormField{{Name: "phone", Label: "Phone", Type: "tel"}},
+	}
+	setPendingForm(userID, template)
+	defer clearPendingForm(userID)
+
+	resp, err := h.savePendingFormAndClear(nil, userID)
+	if err != nil || resp == nil || !strings.HasPrefix(resp.Response, "Failed to save the form") {
+		t.Fatalf("oversized save = %+v, %v; want a failure reply", resp, err)
+	}
+	if getPendingForm(userID) == nil {
+		t.Fatal("pending form was cleared although it was not saved")
+	}
+
+	// The user fixes the form and confirms again
+	template.Description = "Permission to join the museum trip"
+	resp, err = h.savePendingFormAndClear(nil, userID)
+	if err != nil || resp == nil || !strings.Contains(resp.Response, "has been saved") {
+		t.Fatalf("second save = %+v, %v; want the saved reply", resp, err)
+	}
+	if getPendingForm(userID) != nil {
+		t.Error("pending form is still set after it was saved")
+	}
+	if _, err := store.GetFormTemplate(template.ID); err != nil {
+		t.Errorf("saved template %s not found: %v", template.ID, err)
+	}
+}
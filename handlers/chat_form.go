@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"idongivaflyinfa/models"
+	"idongivaflyinfa/validation"
+)
+
+// generatedForm is the part of the chat-generated form JSON (modeled on config/form_sample.json)
+// needed to build a FormTemplate.
+type generatedForm struct {
+	Name           string             `json:"Name"`
+	DisplayName    string             `json:"DisplayName"`
+	Description    string             `json:"Description"`
+	Public         bool               `json:"Public"`
+	UDGridFields   []generatedField   `json:"UDGridFields"`
+	UDGridSections []generatedSection `json:"UDGridSections"`
+}
+
+type generatedSection struct {
+	UDGridFields []generatedField `json:"UDGridFields"`
+}
+
+type generatedField struct {
+	Name         string `json:"Name"`
+	DisplayName  string `json:"DisplayName"`
+	Description  string `json:"Description"`
+	Required     bool   `json:"Required"`
+	TypeName     string `json:"TypeName"`
+	FieldOptions string `json:"FieldOptions"` // JSON-encoded options (list choices, boolean labels)
+}
+
+// formTemplateFromGeneratedJSON converts form JSON generated in chat into a FormTemplate
+// (without ID or timestamps). JSON already in template shape ("name", "fields") is accepted as is.
+func formTemplateFromGeneratedJSON(formJSON string) (*models.FormTemplate, error) {
+	if errs := validation.ValidateFormTemplateJSON([]byte(formJSON)); len(errs) == 0 {
+		var t models.FormTemplate
+		if err := json.Unmarshal([]byte(formJSON), &t); err == nil && len(t.Fields) > 0 {
+			return &t, nil
+		}
+	}
+
+	var g generatedForm
+	if err := json.Unmarshal([]byte(formJSON), &g); err != nil {
+		return nil, fmt.Errorf("invalid form JSON: %w", err)
+	}
+	name := strings.TrimSpace(g.Name)
+	if name == "" {
+		name = strings.TrimSpace(g.DisplayName)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("generated form has no name")
+	}
+
+	fields := g.UDGridFields
+	for _, s := range g.UDGridSections {
+		fields = append(fields, s.UDGridFields...)
+	}
+
+	t := &models.FormTemplate{
+		Name:        name,
+		Description: g.Description,
+		Public:      g.Public,
+	}
+	seen := make(map[string]int)
+	for _, f := range fields {
+		label := strings.TrimSpace(f.DisplayName)
+		if label == "" {
+			label = strings.TrimSpace(f.Name)
+		}
+		if label == "" {
+			continue
+		}
+		fieldName := fieldNameFromLabel(label)
+		if seen[fieldName]++; seen[fieldName] > 1 {
+			fieldName = fmt.Sprintf("%s_%d", fieldName, seen[fieldName])
+		}
+		fieldType, options := generatedFieldType(f)
+		t.Fields = append(t.Fields, models.FormField{
+			Name:        fieldName,
+			Label:       label,
+			Type:        fieldType,
+			Required:    f.Required,
+			Placeholder: f.Description,
+			Options:     options,
+		})
+	}
+	if len(t.Fields) == 0 {
+		return nil, fmt.Errorf("generated form has no fields")
+	}
+	return t, nil
+}
+
+// fieldNameFromLabel makes a short snake_case field name ("Phone Number" -> "phone_number")
+func fieldNameFromLabel(label string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(label) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			underscore = false
+		} else if b.Len() > 0 && !underscore {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" {
+		return "field"
+	}
+	return name
+}
+
+// generatedFieldType maps a generated question type to a FormFieldTypes value, with options for selects
+func generatedFieldType(f generatedField) (string, []string) {
+	var opts struct {
+		TrueDisplayName  string   `json:"TrueDisplayName"`
+		FalseDisplayName string   `json:"FalseDisplayName"`
+		Options          []string `json:"Options"`
+		PickListOptions  []string `json:"PickListOptions"`
+	}
+	if f.FieldOptions != "" {
+		_ = json.Unmarshal([]byte(f.FieldOptions), &opts)
+	}
+
+	switch strings.ToLower(f.TypeName) {
+	case "email":
+		return "email", nil
+	case "phone number", "phone":
+		return "tel", nil
+	case "date", "time", "date/time", "datetime":
+		return "date", nil
+	case "number", "integer", "decimal", "currency":
+		return "number", nil
+	case "boolean":
+		yes, no := opts.TrueDisplayName, opts.FalseDisplayName
+		if yes == "" || no == "" {
+			yes, no = "Yes", "No"
+		}
+		return "select", []string{yes, no}
+	case "list", "dropdown", "select", "multiple choice", "checkbox":
+		options := opts.Options
+		if len(options) == 0 {
+			options = opts.PickListOptions
+		}
+		if len(options) > 0 {
+			return "select", options
+		}
+	}
+	return "text", nil
+}
+
+// staffFormKeywords and studentFormKeywords mark who a form is meant for
+var (
+	staffFormKeywords   = []string{"staff", "teacher", "employee", "faculty", "personnel", "substitute", "payroll", "timesheet", "driver"}
+	studentFormKeywords = []string{"student", "pupil", "enrollment", "enrolment", "learner", "parent", "guardian", "class registration"}
+)
+
+// inferFormUserType decides "student" or "staff" from the request and form text (checked in order),
+// or returns "" when none of them says.
+func inferFormUserType(texts ...string) string {
+	for _, text := range texts {
+		lower := strings.ToLower(text)
+		staff := containsAny(lower, staffFormKeywords)
+		student := containsAny(lower, studentFormKeywords)
+		switch {
+		case staff && !student:
+			return "staff"
+		case student && !staff:
+			return "student"
+		}
+	}
+	return ""
+}
+
+func containsAny(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFormUserType returns t.UserType if it is "student" or "staff", else the type inferred
+// from hints and the form's name and description, else the configured default.
+func (h *Handlers) resolveFormUserType(t *models.FormTemplate, hints ...string) string {
+	if t.UserType == "student" || t.UserType == "staff" {
+		return t.UserType
+	}
+	if inferred := inferFormUserType(append(hints, t.Name, t.Description)...); inferred != "" {
+		return inferred
+	}
+	return h.cfg.DefaultFormUserType
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"idongivaflyinfa/models"
+)
+
+// executeReport runs report SQL in the background: it saves the result as JSON and, with
+// generateHTML, an HTML page under products. The outcome is recorded on the SQL record of
+// messageID (skipped when the message was not stored).
+func (h *Handlers) executeReport(messageID, finalSQL string, fresh, generateHTML bool) {
+	log.Printf("Background goroutine started for SQL execution")
+	record := func(update func(m *models.MessageSQL)) {
+		if messageID == "" {
+			return
+		}
+		if err := h.db.UpdateMessageSQL(messageID, func(m *models.MessageSQL) {
+			update(m)
+			m.UpdatedAt = models.NowTimestamp()
+		}); err != nil {
+			log.Printf("[CHAT] Failed to record report outcome for message %s: %v", messageID, err)
+		}
+	}
+	fail := func(reason string) {
+		record(func(m *models.MessageSQL) {
+			m.Status = models.ReportStatusFailed
+			m.Error = reason
+		})
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic in background SQL execution: %v", r)
+			fail("Internal error while running the report")
+		}
+	}()
+
+	resultsStorage := h.sqlService.GetResultsStorage()
+	if resultsStorage == nil {
+		log.Printf("Results storage is nil, skipping background execution")
+		fail("Results storage is not initialized")
+		return
+	}
+
+	log.Printf("Starting SQL execution with query length: %d", len(finalSQL))
+	// Execute SQL and save as JSON
+	sqlResult, err := h.executeSQL(finalSQL, "json", "", true, fresh)
+	if err != nil {
+		log.Printf("Error executing SQL: %v", err)
+		fail(err.Error())
+		return
+	}
+	if sqlResult.Error != "" {
+		log.Printf("SQL execution error: %s", sqlResult.Error)
+		fail(sqlResult.Error)
+		return
+	}
+	if sqlResult.Filename == "" {
+		log.Printf("No filename returned from SQL execution")
+		fail("The result was not saved")
+		return
+	}
+	log.Printf("SQL executed successfully, result file: %s", sqlResult.Filename)
+
+	// complete records the result; htmlFile is the product page ("" if none), htmlErr why it is missing
+	complete := func(htmlFile, htmlErr string) {
+		record(func(m *models.MessageSQL) {
+			m.Status = models.ReportStatusCompleted
+			m.ResultFilename = sqlResult.Filename
+			m.RowCount = len(sqlResult.Rows)
+			m.Truncated = sqlResult.Truncated
+			if htmlFile != "" {
+				m.HTMLPath = "/products/" + htmlFile
+			}
+			m.Error = htmlErr
+		})
+	}
+
+	if !generateHTML {
+		log.Printf("HTML generation disabled for this report, result saved as %s", sqlResult.Filename)
+		complete("", "")
+		return
+	}
+
+	// Generate HTML filename from result filename
+	htmlFilename := sqlResult.Filename
+	ext := filepath.Ext(htmlFilename)
+	if ext != "" {
+		htmlFilename = htmlFilename[:len(htmlFilename)-len(ext)]
+	}
+	htmlFilename += ".html"
+
+	// A cached result already has its page unless generation failed last time
+	if sqlResult.Cached {
+		if _, err := os.Stat(filepath.Join(productsDir, htmlFilename)); err == nil {
+			log.Printf("Reusing HTML page for cached result: %s", htmlFilename)
+			complete(htmlFilename, "")
+			return
+		}
+	}
+
+	// Load the ResultFile
+	resultFile, err := resultsStorage.GetResultFile(sqlResult.Filename)
+	if err != nil {
+		log.Printf("Error loading result file: %v", err)
+		complete("", fmt.Sprintf("Failed to load the result for the HTML page: %v", err))
+		return
+	}
+	log.Printf("Result file loaded, rows: %d", resultFile.RowCount)
+
+	// Generate HTML page
+	title := fmt.Sprintf("SQL Query Results - %s", sqlResult.Filename)
+	log.Printf("Generating HTML page with title: %s", title)
+	html, err := h.aiService.GenerateHTMLPage(context.Background(), resultFile, title, models.HTMLTheme{}, h.htmlDataURL(sqlResult.Filename, resultFile))
+	if err != nil {
+		log.Printf("Error generating HTML: %v", err)
+		complete("", fmt.Sprintf("Failed to generate the HTML page: %v", err))
+		return
+	}
+	log.Printf("HTML generated successfully, length: %d", len(html))
+
+	// Save HTML to products folder
+	htmlPath, err := h.saveProduct(htmlFilename, []byte(html))
+	if err != nil {
+		log.Printf("Error saving HTML page for %s: %v", sqlResult.Filename, err)
+		complete("", fmt.Sprintf("Failed to save the HTML page: %v", err))
+		return
+	}
+	log.Printf("HTML page saved successfully to: %s", htmlPath)
+	complete(htmlFilename, "")
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"strings"
+)
+
+// chatRoute is the flow ChatHandler dispatches a message to
+type chatRoute string
+
+const (
+	routeSavePendingForm   chatRoute = "save_pending_form"   // "yes" to a proposed form
+	routeRefinePendingForm chatRoute = "refine_pending_form" // change request for a proposed form
+	routeVoice             chatRoute = "voice"
+	routeComplaint         chatRoute = "complaint"
+	routeRegistration      chatRoute = "registration"
+	routeForm              chatRoute = "form"
+	routeReport            chatRoute = "report"
+	routeChat              chatRoute = "chat"
+)
+
+// chatRouteState is the per-user state that influences routing
+type chatRouteState struct {
+	HasPendingForm     bool     // a form proposed in chat is awaiting confirmation
+	PendingFormFields  []string // names and labels of the proposed form's fields
+	ActiveComplaint    bool     // complaint conversation in progress
+	ActiveRegistration bool     // registration conversation in progress
+}
+
+// chatRouteState loads the routing state for userID
+func (h *Handlers) chatRouteState(userID string) chatRouteState {
+	var state chatRouteState
+	if pending := getPendingForm(userID); pending != nil {
+		state.HasPendingForm = true
+		for _, f := range pending.Fields {
+			state.PendingFormFields = append(state.PendingFormFields, f.Name, f.Label)
+		}
+	}
+	if cs, err := h.db.GetComplaintStateByUserID(userID); err == nil && cs != nil &&
+		cs.ConversationID != "" && !complaintFinished(cs.Step) {
+		state.ActiveComplaint = true
+	}
+	if rs, err := h.db.GetRegistrationStateByUserID(userID); err == nil && rs != nil &&
+		rs.Step != "" && rs.Step != "complete" {
+		state.ActiveRegistration = true
+	}
+	return state
+}
+
+// routeChatIntent picks the flow for a chat message. Precedence, highest first:
+//  1. confirming or changing a proposed form, unless a complaint/registration is in progress
+//     (a bare "yes" then belongs to that flow) or the message explicitly asks for a complaint,
+//     a registration or a new form
+//  2. voice input
+//  3. then routeTextIntent
+func routeChatIntent(message string, hasAudio bool, state chatRouteState) chatRoute {
+	if state.HasPendingForm && !state.ActiveComplaint && !state.ActiveRegistration && !isNewFlowRequest(message) {
+		if isFormConfirmMessage(message) {
+			return routeSavePendingForm
+		}
+		if isFormChangeRequest(message, state.PendingFormFields) {
+			return routeRefinePendingForm
+		}
+	}
+	if hasAudio {
+		return routeVoice
+	}
+	return routeTextIntent(message, state)
+}
+
+// routeTextIntent picks the flow for a (spell-corrected) text message. Precedence, highest first:
+// active complaint, new complaint request, active registration, new registration request,
+// then routeGenerationIntent.
+func routeTextIntent(message string, state chatRouteState) chatRoute {
+	switch {
+	case state.ActiveComplaint, isComplaintRequest(message):
+		return routeComplaint
+	case state.ActiveRegistration, isRegisterStudentRequest(message):
+		return routeRegistration
+	}
+	return routeGenerationIntent(message)
+}
+
+// newFormPhrases ask for another form rather than a change to the proposed one
+var newFormPhrases = []string{"new form", "another form", "different form", "create a form", "generate a form", "make a form", "build a form"}
+
+// isNewFlowRequest reports whether the message explicitly starts a complaint, a registration
+// or a new form, which takes precedence over a pending form proposal.
+func isNewFlowRequest(message string) bool {
+	return isExplicitComplaintRequest(message) || isRegisterStudentRequest(message) ||
+		containsAny(strings.ToLower(message), newFormPhrases)
+}
+
+// routeGenerationIntent picks form generation, a SQL report or a general chat answer from keywords.
+// TODO: change this to AI decision
+func routeGenerationIntent(message string) chatRoute {
+	lowerPrompt := strings.ToLower(message)
+	isFormRequest := (strings.Contains(lowerPrompt, "create") && strings.Contains(lowerPrompt, "form")) ||
+		strings.Contains(lowerPrompt, "i want a new form") ||
+		strings.Contains(lowerPrompt, "generate a form") ||
+		strings.Contains(lowerPrompt, "make a form") ||
+		strings.Contains(lowerPrompt, "build a form") ||
+		(strings.Contains(lowerPrompt, "form") && (strings.Contains(lowerPrompt, "new") || strings.Contains(lowerPrompt, "create")))
+	if isFormRequest {
+		return routeForm
+	}
+
+	hasReportKeywords := strings.Contains(lowerPrompt, "generate") ||
+		strings.Contains(lowerPrompt, "create report") ||
+		strings.Contains(lowerPrompt, "i want a report") ||
+		strings.Contains(lowerPrompt, "i need to make") ||
+		strings.Contains(lowerPrompt, "i need a report") ||
+		strings.Contains(lowerPrompt, "make a report") ||
+		strings.Contains(lowerPrompt, "generate a report") ||
+		strings.Contains(lowerPrompt, "create a report")
+	if hasReportKeywords {
+		return routeReport
+	}
+	return routeChat
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import "testing"
+
+func TestRouteChatIntent(t *testing.T) {
+	pending := chatRouteState{HasPendingForm: true, PendingFormFields: []string{"phone", "Phone Number", "email", "Email"}}
+	complaint := chatRouteState{ActiveComplaint: true}
+	registration := chatRouteState{ActiveRegistration: true}
+	pendingAndComplaint := pending
+	pendingAndComplaint.ActiveComplaint = true
+	pendingAndRegistration := pending
+	pendingAndRegistration.ActiveRegistration = true
+
+	tests := []struct {
+		name     string
+		message  string
+		hasAudio bool
+		state    chatRouteState
+		want     chatRoute
+	}{
+		// A proposed form awaiting confirmation
+		{"pending form + confirm", "yes", false, pending, routeSavePendingForm},
+		{"pending form + save it", "Save it", false, pending, routeSavePendingForm},
+		{"pending form + confirm by voice", "yes", true, pending, routeSavePendingForm},
+		{"pending form + add field", "add a phone field", false, pending, routeRefinePendingForm},
+		{"pending form + make optional", "make email optional", false, pending, routeRefinePendingForm},
+		{"pending form + change by field label", "change Phone Number to mobile", false, pending, routeRefinePendingForm},
+		{"pending form + complaint request", "I want to file a complaint against a teacher", false, pending, routeComplaint},
+		{"pending form + complaint details", "a student threatened me on the bus", false, pending, routeComplaint},
+		{"pending form + registration request", "register a student named Ana", false, pending, routeRegistration},
+		{"pending form + new form request", "create a new form for field trips", false, pending, routeForm},
+		{"pending form + report request", "make a report of attendance", false, pending, routeReport},
+		{"pending form + question", "what does this form do?", false, pending, routeChat},
+
+		// A bare "yes" belongs to the conversation in progress
+		{"pending form + active complaint + confirm", "yes", false, pendingAndComplaint, routeComplaint},
+		{"pending form + active registration + confirm", "yes", false, pendingAndRegistration, routeRegistration},
+
+		// Active complaint and registration conversations
+		{"active complaint + form words", "add a phone field to the form", false, complaint, routeComplaint},
+		{"active complaint + new form", "create a new form", false, complaint, routeComplaint},
+		{"active complaint + report words", "generate a report", false, complaint, routeComplaint},
+		{"active registration + form words", "create a new form", false, registration, routeRegistration},
+		{"active registration + complaint request", "I want to file a complaint", false, registration, routeComplaint},
+
+		// Audio
+		{"audio", "", true, chatRouteState{}, routeVoice},
+		{"audio beats report words", "generate a report", true, chatRouteState{}, routeVoice},
+		{"audio beats active complaint", "", true, complaint, routeVoice},
+
+		// New conversations
+		{"complaint request", "I want to file a complaint", false, chatRouteState{}, routeComplaint},
+		{"registration request", "I want to register a student", false, chatRouteState{}, routeRegistration},
+		{"form request", "create a form for field trip permission", false, chatRouteState{}, routeForm},
+		{"report request", "generate a report of student grades", false, chatRouteState{}, routeReport},
+		{"report request without generate", "I need a report of attendance by class", false, chatRouteState{}, routeReport},
+		{"chat question", "How many students are in grade 10?", false, chatRouteState{}, routeChat},
+		{"chat confirm without pending form", "yes", false, chatRouteState{}, routeChat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routeChatIntent(tt.message, tt.hasAudio, tt.state); got != tt.want {
+				t.Errorf("routeChatIntent(%q, %v, %+v) = %q, want %q", tt.message, tt.hasAudio, tt.state, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,25 +3,32 @@ package handlers
 import (
 	"net/http"
 	"strings"
-	"time"
 
 	"idongivaflyinfa/models"
+	"idongivaflyinfa/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// ListChatSessionsHandler returns all chat sessions for the current user (newest first).
+// ListChatSessionsHandler returns a page of the current user's chat sessions (newest first).
 // @Summary      List chat sessions
 // @Tags         Chat
 // @Produce      json
 // @Header       200      {string}  X-User-ID  "User ID"
-// @Success      200      {array}   models.ChatSession
+// @Param        limit    query     int     false  "Maximum sessions to return (default: all)"
+// @Param        offset   query     int     false  "Sessions to skip"
+// @Success      200      {object}  models.Page[models.ChatSession]
+// @Failure      400      {object}  map[string]string
 // @Router       /api/chat/sessions [get]
 func (h *Handlers) ListChatSessionsHandler(c *gin.Context) {
-	userID := c.GetHeader("X-User-ID")
-	if userID == "" {
-		userID = "admin"
+	userID, ok := h.requireUserID(c)
+	if !ok {
+		return
+	}
+	limit, offset, ok := queryPage(c)
+	if !ok {
+		return
 	}
 	if err := h.db.EnsureDefaultChatSession(userID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ensure default session"})
@@ -32,7 +39,7 @@ func (h *Handlers) ListChatSessionsHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, sessions)
+	c.JSON(http.StatusOK, models.NewPage(sessions, limit, offset))
 }
 
 // CreateChatSessionHandler creates a new chat session.
@@ -44,9 +51,9 @@ func (h *Handlers) ListChatSessionsHandler(c *gin.Context) {
 // @Success      201   {object}  models.ChatSession
 // @Router       /api/chat/sessions [post]
 func (h *Handlers) CreateChatSessionHandler(c *gin.Context) {
-	userID := c.GetHeader("X-User-ID")
-	if userID == "" {
-		userID = "admin"
+	userID, ok := h.requireUserID(c)
+	if !ok {
+		return
 	}
 	var body struct {
 		Title string `json:"title"`
@@ -57,7 +64,7 @@ func (h *Handlers) CreateChatSessionHandler(c *gin.Context) {
 		title = "New chat"
 	}
 	id := uuid.New().String()
-	now := time.Now().Format(time.RFC3339)
+	now := models.NowTimestamp()
 	sess := &models.ChatSession{
 		ID:        id,
 		UserID:    userID,
@@ -80,9 +87,9 @@ func (h *Handlers) CreateChatSessionHandler(c *gin.Context) {
 // @Success      200  {object}  object  "{ \"session\": ChatSession, \"messages\": StoredChatMessage[] }"
 // @Router       /api/chat/sessions/{id} [get]
 func (h *Handlers) GetChatSessionHandler(c *gin.Context) {
-	userID := c.GetHeader("X-User-ID")
-	if userID == "" {
-		userID = "admin"
+	userID, ok := h.requireUserID(c)
+	if !ok {
+		return
 	}
 	sessionID := c.Param("id")
 	if sessionID == "" {
@@ -105,6 +112,38 @@ func (h *Handlers) GetChatSessionHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"session": sess, "messages": messages})
 }
 
+// GetMessageSQLHandler returns the SQL behind a report message.
+// @Summary      Get the SQL of a chat message
+// @Description  Returns the generated and executed SQL of a past report message, by the message_id from the chat response or the id of a stored message, with the report's status and, once it has run, its result file and HTML page.
+// @Tags         Chat
+// @Produce      json
+// @Param        id   path      string  true  "Message ID"
+// @Success      200  {object}  models.MessageSQL
+// @Failure      404  {object}  map[string]string  "No SQL for this message"
+// @Router       /api/chat/messages/{id}/sql [get]
+func (h *Handlers) GetMessageSQLHandler(c *gin.Context) {
+	userID, ok := h.requireUserID(c)
+	if !ok {
+		return
+	}
+	messageID := c.Param("id")
+	if !validation.IsValidID(messageID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		return
+	}
+	record, err := h.db.GetMessageSQL(messageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Another user's message is reported as missing rather than forbidden
+	if record == nil || record.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no SQL found for this message"})
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
 // UpdateChatSessionHandler updates session title.
 // @Summary      Update chat session title
 // @Tags         Chat
@@ -114,9 +153,9 @@ func (h *Handlers) GetChatSessionHandler(c *gin.Context) {
 // @Success      200   {object}  models.ChatSession
 // @Router       /api/chat/sessions/{id} [put]
 func (h *Handlers) UpdateChatSessionHandler(c *gin.Context) {
-	userID := c.GetHeader("X-User-ID")
-	if userID == "" {
-		userID = "admin"
+	userID, ok := h.requireUserID(c)
+	if !ok {
+		return
 	}
 	sessionID := c.Param("id")
 	if sessionID == "" {
@@ -150,9 +189,9 @@ func (h *Handlers) UpdateChatSessionHandler(c *gin.Context) {
 // @Success      204  "No Content"
 // @Router       /api/chat/sessions/{id} [delete]
 func (h *Handlers) DeleteChatSessionHandler(c *gin.Context) {
-	userID := c.GetHeader("X-User-ID")
-	if userID == "" {
-		userID = "admin"
+	userID, ok := h.requireUserID(c)
+	if !ok {
+		return
 	}
 	sessionID := c.Param("id")
 	if sessionID == "" {
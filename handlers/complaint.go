@@ -2,62 +2,121 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"strings"
 
 	"idongivaflyinfa/models"
+	"idongivaflyinfa/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// isComplaintRequest checks if the user message is about filing a complaint
-// It detects both explicit complaint requests and messages containing complaint details
-func isComplaintRequest(message string) bool {
-	lowerMsg := strings.ToLower(message)
-	
-	// Explicit complaint phrases
-	complaintPhrases := []string{
-		"file a complaint",
-		"file complaint",
-		"filing a complaint",
-		"filing complaint",
-		"want to file a complaint",
-		"want to file complaint",
-		"i want to file a complaint",
-		"i want to file complaint",
-		"i wanna file a complaint",
-		"i wanna file complaint",
-		"complaint against",
-		"file complaint against",
-		"file a complaint on",
-		"file a complaint against",
-		"report a user",
-		"report user",
-		"complain about",
-		"complain against",
-		"report on",
-		"i need to report a student's behavior",
-		"need to report a student's behavior",
-		"report a student's behavior",
-		"report student's behavior",
-		"report student behavior",
-		"report behavior",
-		"behavior report",
-		"misconduct form",
-		"fill out a misconduct form",
-		"fill out misconduct form",
-		"misconduct",
-		"please help me fill out a misconduct form",
-		"help me fill out a misconduct form",
-		"help fill out misconduct form",
+// complaintErrorResponse maps a complaint flow error to an HTTP status and a message safe to
+// show the user. The underlying error is expected to be logged by the caller.
+func (h *Handlers) complaintErrorResponse(err error) (int, string) {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return http.StatusGatewayTimeout, "The complaint service took too long to respond. Please try again in a moment."
+		}
+		return http.StatusBadGateway, "The complaint service is unavailable right now. Please try again later."
+	}
+	var statusErr *service.UpstreamStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone:
+			return http.StatusBadGateway, "Your complaint session has expired. Please describe your complaint again to start over."
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return http.StatusBadGateway, "The complaint service is busy. Please try again in a minute."
+		case statusErr.StatusCode >= 500:
+			return http.StatusBadGateway, "The complaint service is having trouble right now. Please try again later."
+		}
 	}
+	return http.StatusInternalServerError, h.cfg.ComplaintErrorMessage
+}
+
+// startComplaintDialogue runs the initialize and start-dialogue steps for a new complaint session.
+// A start response without a conversation_id is usually a transient upstream problem, so the
+// whole sequence is retried once before giving up.
+func (h *Handlers) startComplaintDialogue(userMessage string) (*service.InitializeResponse, *service.StartDialogueResponse, error) {
+	for attempt := 1; ; attempt++ {
+		initResp, err := h.complaintService.InitializeProcess()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize complaint process: %w", err)
+		}
 
+		log.Printf("[COMPLAINT FLOW] Starting dialogue with full message: %s", userMessage)
+		dialogueResp, err := h.complaintService.StartDialogue(userMessage)
+		if errors.Is(err, service.ErrNoConversationID) && attempt == 1 {
+			log.Printf("[COMPLAINT FLOW] Dialogue start returned no conversation_id, retrying initialize and start once")
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to start dialogue: %w", err)
+		}
+		return initResp, dialogueResp, nil
+	}
+}
+
+// complaintPhrases are explicit requests to file a complaint
+var complaintPhrases = []string{
+	"file a complaint",
+	"file complaint",
+	"filing a complaint",
+	"filing complaint",
+	"want to file a complaint",
+	"want to file complaint",
+	"i want to file a complaint",
+	"i want to file complaint",
+	"i wanna file a complaint",
+	"i wanna file complaint",
+	"complaint against",
+	"file complaint against",
+	"file a complaint on",
+	"file a complaint against",
+	"report a user",
+	"report user",
+	"complain about",
+	"complain against",
+	"report on",
+	"i need to report a student's behavior",
+	"need to report a student's behavior",
+	"report a student's behavior",
+	"report student's behavior",
+	"report student behavior",
+	"report behavior",
+	"behavior report",
+	"misconduct form",
+	"fill out a misconduct form",
+	"fill out misconduct form",
+	"misconduct",
+	"please help me fill out a misconduct form",
+	"help me fill out a misconduct form",
+	"help fill out misconduct form",
+}
+
+// isExplicitComplaintRequest checks if the user message explicitly asks to file a complaint
+func isExplicitComplaintRequest(message string) bool {
+	lowerMsg := strings.ToLower(message)
 	for _, phrase := range complaintPhrases {
 		if strings.Contains(lowerMsg, phrase) {
 			return true
 		}
 	}
+	return false
+}
+
+// isComplaintRequest checks if the user message is about filing a complaint
+// It detects both explicit complaint requests and messages containing complaint details
+func isComplaintRequest(message string) bool {
+	if isExplicitComplaintRequest(message) {
+		return true
+	}
+	lowerMsg := strings.ToLower(message)
 
 	// Also detect messages that contain complaint details even without explicit "file complaint"
 	// These patterns suggest the user wants to report something
@@ -114,7 +173,7 @@ func isComplaintRequest(message string) bool {
 // handleComplaintFlow handles the multi-step complaint filing process
 func (h *Handlers) handleComplaintFlow(c *gin.Context, userID, userMessage string) (*models.ChatResponse, error) {
 	// Correct spelling errors in user message before processing
-	correctedMessage, err := h.aiService.CorrectSpelling(userMessage)
+	correctedMessage, err := h.correctSpelling(c.Request.Context(), userMessage)
 	if err != nil {
 		log.Printf("[COMPLAINT FLOW] Error correcting spelling: %v, using original message", err)
 		correctedMessage = userMessage
@@ -140,21 +199,14 @@ func (h *Handlers) handleComplaintFlow(c *gin.Context, userID, userMessage strin
 		complaintState = nil // Force new session
 	}
 
-	// If no state exists or state is complete, start a NEW complaint session
-	if err != nil || complaintState == nil || complaintState.Step == "complete" || complaintState.ConversationID == "" {
+	// If no state exists or state is finished, start a NEW complaint session
+	if err != nil || complaintState == nil || complaintFinished(complaintState.Step) || complaintState.ConversationID == "" {
 		log.Printf("[COMPLAINT FLOW] Starting NEW complaint session for user: %s", userID)
 
-		// Step 1: Initialize and capture initial_data
-		initResp, err := h.complaintService.InitializeProcess()
+		// Steps 1-2: initialize (capturing initial_data) and start the dialogue with the full message
+		initResp, dialogueResp, err := h.startComplaintDialogue(userMessage)
 		if err != nil {
-			return nil, fmt.Errorf("failed to initialize complaint process: %w", err)
-		}
-
-		// Step 2: Start dialogue with the full user message (including complaint details)
-		log.Printf("[COMPLAINT FLOW] Starting dialogue with full message: %s", userMessage)
-		dialogueResp, err := h.complaintService.StartDialogue(userMessage)
-		if err != nil {
-			return nil, fmt.Errorf("failed to start dialogue: %w", err)
+			return nil, err
 		}
 
 		log.Printf("[COMPLAINT FLOW] Started dialogue, conversationID: '%s'", dialogueResp.ConversationID)
@@ -171,6 +223,7 @@ func (h *Handlers) handleComplaintFlow(c *gin.Context, userID, userMessage strin
 			Step:           "dialogue",
 			ExchangeCount:  1, // First exchange (user message + AI response)
 			LastResponse:   dialogueResp.Response,
+			ComplaintText:  userMessage,
 			InitialData:   initResp.InitialData, // Store initial_data from first execute step
 		}
 		
@@ -199,6 +252,12 @@ func (h *Handlers) handleComplaintFlow(c *gin.Context, userID, userMessage strin
 	log.Printf("[COMPLAINT FLOW] Continuing existing session for user %s, conversationID: %s, step: %s, exchanges: %d",
 		userID, complaintState.ConversationID, complaintState.Step, complaintState.ExchangeCount)
 
+	// The dialogue finished but executing it did not (it failed, or the server restarted): retry the execute
+	if complaintState.Step == "executing" && complaintState.DialogueResult != nil {
+		log.Printf("[COMPLAINT FLOW] Resuming execute of completed dialogue for user %s", userID)
+		return h.executeComplaint(userID, userMessage, complaintState, complaintState.LastResponse)
+	}
+
 	// Continue existing session - check if we've exceeded max exchanges
 	if complaintState.ExchangeCount >= 12 {
 		log.Printf("[COMPLAINT FLOW] Session exceeded 12 exchanges, clearing old state and starting new session for user %s", userID)
@@ -214,17 +273,10 @@ func (h *Handlers) handleComplaintFlow(c *gin.Context, userID, userMessage strin
 	if complaintState == nil {
 		log.Printf("[COMPLAINT FLOW] Starting NEW complaint session (old session cleared) for user: %s", userID)
 
-		// Step 1: Initialize and capture initial_data
-		initResp, err := h.complaintService.InitializeProcess()
-		if err != nil {
-			return nil, fmt.Errorf("failed to initialize complaint process: %w", err)
-		}
-
-		// Step 2: Start dialogue with the full user message (including complaint details)
-		log.Printf("[COMPLAINT FLOW] Starting dialogue with full message: %s", userMessage)
-		dialogueResp, err := h.complaintService.StartDialogue(userMessage)
+		// Steps 1-2: initialize (capturing initial_data) and start the dialogue with the full message
+		initResp, dialogueResp, err := h.startComplaintDialogue(userMessage)
 		if err != nil {
-			return nil, fmt.Errorf("failed to start dialogue: %w", err)
+			return nil, err
 		}
 
 		log.Printf("[COMPLAINT FLOW] Started dialogue, conversationID: '%s'", dialogueResp.ConversationID)
@@ -241,6 +293,7 @@ func (h *Handlers) handleComplaintFlow(c *gin.Context, userID, userMessage strin
 			Step:           "dialogue",
 			ExchangeCount:  1, // First exchange (user message + AI response)
 			LastResponse:   dialogueResp.Response,
+			ComplaintText:  userMessage,
 			InitialData:   initResp.InitialData, // Store initial_data from first execute step
 		}
 		
@@ -275,16 +328,9 @@ func (h *Handlers) handleComplaintFlow(c *gin.Context, userID, userMessage strin
 			h.db.StoreComplaintState(userID, complaintState)
 
 			// Start new session
-			initResp, err := h.complaintService.InitializeProcess()
-			if err != nil {
-				return nil, fmt.Errorf("failed to initialize complaint process: %w", err)
-			}
-
-			// Start dialogue with the full user message (including complaint details)
-			log.Printf("[COMPLAINT FLOW] Starting dialogue with full message: %s", userMessage)
-			dialogueResp, err := h.complaintService.StartDialogue(userMessage)
+			initResp, dialogueResp, err := h.startComplaintDialogue(userMessage)
 			if err != nil {
-				return nil, fmt.Errorf("failed to start dialogue: %w", err)
+				return nil, err
 			}
 
 			// Create new state with initial_data
@@ -293,6 +339,7 @@ func (h *Handlers) handleComplaintFlow(c *gin.Context, userID, userMessage strin
 				Step:           "dialogue",
 				ExchangeCount:  1,
 				LastResponse:   dialogueResp.Response,
+			ComplaintText:  userMessage,
 				InitialData:   initResp.InitialData, // Store initial_data from first execute step
 			}
 
@@ -354,61 +401,14 @@ func (h *Handlers) handleComplaintFlow(c *gin.Context, userID, userMessage strin
 			}
 		}
 
-		// Build the execute request body with the structure expected by the API
-		// Structure: resume_from_phase, dialogue_phase1_result (from continue), and initial_data (from first execute)
-		executeRequestBody := map[string]interface{}{
-			"resume_from_phase":     "dialogue",
-			"dialogue_phase1_result": dialogueResult,
-		}
-
-		// Add initial_data from the first execute step (required)
-		if complaintState.InitialData != nil {
-			executeRequestBody["initial_data"] = complaintState.InitialData
-			log.Printf("[COMPLAINT FLOW] Added initial_data to execute request with %d keys", len(complaintState.InitialData))
-		} else {
-			log.Printf("[COMPLAINT FLOW] WARNING: initial_data is nil, execute request may fail")
-		}
-
-		// Execute using the request body
-		executeResp, err := h.complaintService.ExecuteWithResponseBody(executeRequestBody)
-		if err != nil {
-			log.Printf("[COMPLAINT FLOW] Error executing with response body: %v", err)
-			return nil, fmt.Errorf("failed to execute complaint: %w", err)
-		}
-
-		// Check if we have final_outcome
-		if executeResp.FinalOutcome != nil {
-			log.Printf("[COMPLAINT FLOW] Final outcome received: %v", executeResp.FinalOutcome)
-			// Print to console
-			finalOutcomeJSON, _ := json.MarshalIndent(executeResp.FinalOutcome, "", "  ")
-			log.Printf("[COMPLAINT FLOW] Final outcome (console only):\n%s", string(finalOutcomeJSON))
-
-			// Mark as complete
-			complaintState.Step = "complete"
-			if err := h.db.StoreComplaintState(userID, complaintState); err != nil {
-				log.Printf("Error storing final complaint state: %v", err)
-			}
-
-			// Store success message in chat history
-			successMsg := "We have successfully filed the complaint for you. Your complaint has been received and will be reviewed by our team. Thank you for bringing this to our attention."
-			h.db.StoreChatHistory(userID, userMessage, successMsg)
-
-			return &models.ChatResponse{
-				Response: successMsg,
-			}, nil
-		} else {
-			log.Printf("[COMPLAINT FLOW] No final outcome received, but dialogue is complete")
-			// Even if no final_outcome, mark as complete since dialogue is done
-			complaintState.Step = "complete"
-			if err := h.db.StoreComplaintState(userID, complaintState); err != nil {
-				log.Printf("Error storing complaint state: %v", err)
-			}
-
-			// Return the response message
-			return &models.ChatResponse{
-				Response: continueResp.Response,
-			}, nil
+		// Persist the finished dialogue before executing, so a failed execute or a restart resumes
+		// from here instead of continuing a conversation the upstream has already closed
+		complaintState.Step = "executing"
+		complaintState.DialogueResult = dialogueResult
+		if err := h.db.StoreComplaintState(userID, complaintState); err != nil {
+			log.Printf("[COMPLAINT FLOW] Error storing complaint state before execute: %v", err)
 		}
+		return h.executeComplaint(userID, userMessage, complaintState, continueResp.Response)
 	}
 
 	// Dialogue is not complete yet - just display the response message
@@ -425,3 +425,117 @@ func (h *Handlers) handleComplaintFlow(c *gin.Context, userID, userMessage strin
 		Response: continueResp.Response,
 	}, nil
 }
+
+// maxComplaintExecuteAttempts is how often a finished dialogue is submitted before it is given up
+const maxComplaintExecuteAttempts = 3
+
+// complaintGiveUpResponse is returned when a finished dialogue could not be submitted
+const complaintGiveUpResponse = "Sorry, we could not file your complaint after several attempts. Please start a new complaint, or contact the office directly."
+
+// complaintFinished reports whether a complaint in step is over: filed ("complete") or given up
+// after failed submissions ("failed"). A finished complaint is not continued by later messages.
+func complaintFinished(step string) bool {
+	return step == "complete" || step == "failed"
+}
+
+// recordComplaintExecuteFailure counts a failed submission of state's dialogue. After
+// maxComplaintExecuteAttempts the complaint is marked failed, so later messages are no longer
+// routed back into the execute; it reports whether that happened.
+func recordComplaintExecuteFailure(state *models.ComplaintState) bool {
+	state.ExecuteAttempts++
+	if state.ExecuteAttempts >= maxComplaintExecuteAttempts {
+		state.Step = "failed"
+		return true
+	}
+	return false
+}
+
+// complaintExecuteFailed records a failed submission and returns the reply: err while attempts
+// remain, the give-up message once they are used up.
+func (h *Handlers) complaintExecuteFailed(userID string, complaintState *models.ComplaintState, err error) (*models.ChatResponse, error) {
+	gaveUp := recordComplaintExecuteFailure(complaintState)
+	if storeErr := h.db.StoreComplaintState(userID, complaintState); storeErr != nil {
+		log.Printf("[COMPLAINT FLOW] Error storing complaint state: %v", storeErr)
+	}
+	if gaveUp {
+		log.Printf("[COMPLAINT FLOW] Giving up on complaint for user %s after %d failed attempts: %v", userID, complaintState.ExecuteAttempts, err)
+		return &models.ChatResponse{Response: complaintGiveUpResponse}, nil
+	}
+	return nil, err
+}
+
+// executeComplaint submits a finished complaint dialogue using the dialogue result and initial_data
+// stored in complaintState, marking it complete once the upstream accepts it. Everything it needs
+// comes from the stored state, so it also works after a restart. fallbackResponse is returned
+// when no final outcome comes back. Failures are retried on the next message, up to
+// maxComplaintExecuteAttempts submissions.
+func (h *Handlers) executeComplaint(userID, userMessage string, complaintState *models.ComplaintState, fallbackResponse string) (*models.ChatResponse, error) {
+	// Build the execute request body with the structure expected by the API
+	// Structure: resume_from_phase, dialogue_phase1_result (from continue), and initial_data (from first execute)
+	executeRequestBody := map[string]interface{}{
+		"resume_from_phase":     "dialogue",
+		"dialogue_phase1_result": complaintState.DialogueResult,
+	}
+
+	// Add initial_data from the first execute step (required); states stored without it get a fresh one
+	if complaintState.InitialData == nil {
+		log.Printf("[COMPLAINT FLOW] initial_data missing from stored state, re-initializing")
+		initResp, err := h.complaintService.InitializeProcess()
+		if err != nil {
+			return h.complaintExecuteFailed(userID, complaintState, fmt.Errorf("failed to initialize complaint process: %w", err))
+		}
+		complaintState.InitialData = initResp.InitialData
+		if err := h.db.StoreComplaintState(userID, complaintState); err != nil {
+			log.Printf("[COMPLAINT FLOW] Error storing complaint state: %v", err)
+		}
+	}
+	executeRequestBody["initial_data"] = complaintState.InitialData
+	log.Printf("[COMPLAINT FLOW] Added initial_data to execute request with %d keys", len(complaintState.InitialData))
+
+	if h.cfg.DemoMode {
+		complaintState.Step = "complete"
+		h.db.StoreComplaintState(userID, complaintState)
+		return &models.ChatResponse{Response: demoModeMessage}, nil
+	}
+
+	// Execute using the request body
+	executeResp, err := h.complaintService.ExecuteWithResponseBody(executeRequestBody)
+	if err != nil {
+		log.Printf("[COMPLAINT FLOW] Error executing with response body: %v", err)
+		return h.complaintExecuteFailed(userID, complaintState, fmt.Errorf("failed to execute complaint: %w", err))
+	}
+
+	// Check if we have final_outcome
+	if executeResp.FinalOutcome != nil {
+		log.Printf("[COMPLAINT FLOW] Final outcome received: %v", executeResp.FinalOutcome)
+		// Print to console
+		finalOutcomeJSON, _ := json.MarshalIndent(executeResp.FinalOutcome, "", "  ")
+		log.Printf("[COMPLAINT FLOW] Final outcome (console only):\n%s", string(finalOutcomeJSON))
+
+		// Mark as complete
+		complaintState.Step = "complete"
+		if err := h.db.StoreComplaintState(userID, complaintState); err != nil {
+			log.Printf("Error storing final complaint state: %v", err)
+		}
+
+		// Store success message in chat history
+		successMsg := "We have successfully filed the complaint for you. Your complaint has been received and will be reviewed by our team. Thank you for bringing this to our attention."
+		h.db.StoreChatHistory(userID, userMessage, successMsg)
+
+		return &models.ChatResponse{
+			Response: successMsg,
+		}, nil
+	} else {
+		log.Printf("[COMPLAINT FLOW] No final outcome received, but dialogue is complete")
+		// Even if no final_outcome, mark as complete since dialogue is done
+		complaintState.Step = "complete"
+		if err := h.db.StoreComplaintState(userID, complaintState); err != nil {
+			log.Printf("Error storing complaint state: %v", err)
+		}
+
+		// Return the response message
+		return &models.ChatResponse{
+			Response: fallbackResponse,
+		}, nil
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"idongivaflyinfa/db"
+	"idongivaflyinfa/models"
+	"idongivaflyinfa/service"
+)
+
+// openTestDB opens a Badger store in dir; the caller closes it
+func openTestDB(t *testing.T, dir string) *db.DB {
+	t.Helper()
+	store, err := db.New(dir)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	return store
+}
+
+func TestComplaintStateSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	const userID = "user-1"
+	const complaintText = "A student was bullied on bus 12 on Monday; 学生 was pushed 🚌"
+
+	store := openTestDB(t, dir)
+	state := &models.ComplaintState{
+		ConversationID: "conv-1",
+		Step:           "executing",
+		ComplaintText:  complaintText,
+		DialogueResult: map[string]interface{}{"response": complaintText, "is_complete": true},
+		InitialData:    map[string]interface{}{"flow": "chaintest1"},
+		ExchangeCount:  4,
+	}
+	if err := store.StoreComplaintState(userID, state); err != nil {
+		t.Fatalf("store state: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close db: %v", err)
+	}
+
+	// Restart: a new process opens the same directory
+	store = openTestDB(t, dir)
+	defer store.Close()
+
+	got, err := store.GetComplaintStateByUserID(userID)
+	if err != nil || got == nil {
+		t.Fatalf("load state after restart: %v, %v", got, err)
+	}
+	if got.Step != "executing" {
+		t.Errorf("Step = %q, want executing", got.Step)
+	}
+	if got.ComplaintText != complaintText {
+		t.Errorf("ComplaintText = %q, want %q", got.ComplaintText, complaintText)
+	}
+	if got.DialogueResult["response"] != complaintText {
+		t.Errorf("DialogueResult[response] = %v, want %q", got.DialogueResult["response"], complaintText)
+	}
+	if got.InitialData["flow"] != "chaintest1" {
+		t.Errorf("InitialData[flow] = %v, want chaintest1", got.InitialData["flow"])
+	}
+
+	h := &Handlers{db: store}
+	if !h.chatRouteState(userID).ActiveComplaint {
+		t.Error("restored executing complaint is not routed as active")
+	}
+}
+
+func TestComplaintExecuteGivesUpAfterMaxAttempts(t *testing.T) {
+	store := openTestDB(t, t.TempDir())
+	defer store.Close()
+	h := &Handlers{db: store}
+
+	const userID = "user-2"
+	state := &models.ComplaintState{
+		ConversationID: "conv-2",
+		Step:           "executing",
+		ComplaintText:  "Harassment in the hallway",
+		DialogueResult: map[string]interface{}{"response": "done"},
+	}
+	upstream := errors.New("upstream unavailable")
+
+	for attempt := 1; attempt < maxComplaintExecuteAttempts; attempt++ {
+		resp, err := h.complaintExecuteFailed(userID, state, upstream)
+		if !errors.Is(err, upstream) || resp != nil {
+			t.Fatalf("attempt %d: got %v, %v; want the upstream error", attempt, resp, err)
+		}
+		if !h.chatRouteState(userID).ActiveComplaint {
+			t.Fatalf("attempt %d: complaint no longer active before attempts ran out", attempt)
+		}
+	}
+
+	resp, err := h.complaintExecuteFailed(userID, state, upstream)
+	if err != nil || resp == nil || resp.Response != complaintGiveUpResponse {
+		t.Fatalf("last attempt: got %v, %v; want the give-up response", resp, err)
+	}
+	stored, err := store.GetComplaintStateByUserID(userID)
+	if err != nil || stored == nil {
+		t.Fatalf("load state: %v, %v", stored, err)
+	}
+	if stored.Step != "failed" || stored.ExecuteAttempts != maxComplaintExecuteAttempts {
+		t.Errorf("stored Step = %q, ExecuteAttempts = %d; want failed, %d", stored.Step, stored.ExecuteAttempts, maxComplaintExecuteAttempts)
+	}
+	if h.chatRouteState(userID).ActiveComplaint {
+		t.Error("given-up complaint is still routed as active")
+	}
+}
+
+// roundTripFunc answers HTTP requests in-process, standing in for the complaint API
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// reloadComplaintState reopens the store in dir, as after a restart, and loads userID's complaint
+func reloadComplaintState(t *testing.T, dir, userID string) (*db.DB, *models.ComplaintState) {
+	t.Helper()
+	store := openTestDB(t, dir)
+	state, err := store.GetComplaintStateByUserID(userID)
+	if err != nil || state == nil {
+		store.Close()
+		t.Fatalf("load state: %v, %v", state, err)
+	}
+	return store, state
+}
+
+func TestExecuteComplaintFromReloadedState(t *testing.T) {
+	const userID = "user-3"
+	tests := []struct {
+		name      string
+		status    int
+		wantStep  string
+		wantCalls int
+	}{
+		{"upstream accepts", http.StatusOK, "complete", 1},
+		{"upstream keeps failing", http.StatusServiceUnavailable, "failed", maxComplaintExecuteAttempts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := openTestDB(t, dir)
+			if err := store.StoreComplaintState(userID, &models.ComplaintState{
+				ConversationID: "conv-3",
+				Step:           "executing",
+				ComplaintText:  "Bullying at recess",
+				DialogueResult: map[string]interface{}{"response": "done", "is_complete": true},
+				InitialData:    map[string]interface{}{"flow": "chaintest1"},
+				LastResponse:   "Thanks, we have everything we need.",
+			}); err != nil {
+				t.Fatalf("store state: %v", err)
+			}
+			store.Close()
+
+			calls := 0
+			client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				calls++
+				if !strings.HasSuffix(req.URL.Path, "/execute") {
+					t.Errorf("unexpected request to %s", req.URL.Path)
+				}
+				body := `{"final_outcome": {"status": "filed"}}`
+				if tt.status != http.StatusOK {
+					body = "unavailable"
+				}
+				return &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}, Request: req}, nil
+			})}
+
+			// Each message after a failure comes from a fresh process resuming the stored state
+			var resp *models.ChatResponse
+			var err error
+			for attempt := 1; attempt <= tt.wantCalls; attempt++ {
+				var state *models.ComplaintState
+				store, state = reloadComplaintState(t, dir, userID)
+				if state.Step != "executing" {
+					t.Fatalf("attempt %d: reloaded Step = %q, want executing", attempt, state.Step)
+				}
+				h := &Handlers{db: store, complaintService: service.NewComplaintServiceWithClient(3, client)}
+				resp, err = h.executeComplaint(userID, "any news?", state, state.LastResponse)
+				store.Close()
+			}
+			if err != nil || resp == nil {
+				t.Fatalf("last attempt: got %v, %v; want a response", resp, err)
+			}
+
+			store = openTestDB(t, dir)
+			defer store.Close()
+			stored, err := store.GetComplaintStateByUserID(userID)
+			if err != nil || stored == nil {
+				t.Fatalf("load state: %v, %v", stored, err)
+			}
+			if stored.Step != tt.wantStep || calls != tt.wantCalls {
+				t.Errorf("Step = %q after %d calls, want %q after %d", stored.Step, calls, tt.wantStep, tt.wantCalls)
+			}
+			if (&Handlers{db: store}).chatRouteState(userID).ActiveComplaint {
+				t.Error("finished complaint is still routed as active")
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const demoModeMessage = "This action is disabled in demo mode."
+
+// demoAllowedRoutes are the non-GET routes that stay available in demo mode. They generate or
+// preview content; the handlers themselves skip any writes when DEMO_MODE is on.
+var demoAllowedRoutes = map[string]bool{
+	"/api/chat":            true,
+	"/api/forms/validate":  true,
+	"/api/voice/recognize": true,
+}
+
+// DemoReadOnly rejects mutating requests with 403 when demo mode is enabled.
+func DemoReadOnly(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if demoAllowedRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": demoModeMessage})
+	}
+}
@@ -1,275 +1,367 @@
-package handlers
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"mime"
-	"mime/multipart"
-	"net/http"
-	"path"
-	"strings"
-	"time"
-)
-
-const (
-	defaultSummarizePrompt = "Summarize the following content clearly and concisely."
-	// Image/PDF reader: try Qwen first, then Mistral as fallback.
-	imageReaderProviderPrimary   = "qwen"
-	imageReaderModelPrimary      = "qwen-vl-plus"
-	imageReaderProviderFallback  = "mistral"
-	imageReaderModelFallback     = "mistral-small-latest"
-)
-
-// imageReaderProviderModel pairs provider and model for read-and-process.
-var imageReaderProviderModels = []struct{ provider, model string }{
-	{imageReaderProviderPrimary, imageReaderModelPrimary},
-	{imageReaderProviderFallback, imageReaderModelFallback},
-}
-
-// detectImageContentType returns an image/* MIME type for the image-reader. Uses content
-// detection first so uploads sent as application/octet-stream are sent with the correct type.
-func detectImageContentType(fileContent []byte, filename string) string {
-	detected := http.DetectContentType(fileContent)
-	if strings.HasPrefix(detected, "image/") {
-		return strings.TrimSpace(strings.Split(detected, ";")[0])
-	}
-	// Magic bytes for common image formats (when DetectContentType returns application/octet-stream)
-	if len(fileContent) >= 8 {
-		switch {
-		case len(fileContent) >= 3 && fileContent[0] == 0xFF && fileContent[1] == 0xD8 && fileContent[2] == 0xFF:
-			return "image/jpeg"
-		case len(fileContent) >= 8 && fileContent[0] == 0x89 && fileContent[1] == 0x50 && fileContent[2] == 0x4E && fileContent[3] == 0x47:
-			return "image/png"
-		case len(fileContent) >= 6 && fileContent[0] == 0x47 && fileContent[1] == 0x49 && fileContent[2] == 0x46:
-			return "image/gif"
-		case len(fileContent) >= 12 && fileContent[0] == 0x52 && fileContent[1] == 0x49 && fileContent[2] == 0x46 && fileContent[3] == 0x46 &&
-			fileContent[8] == 0x57 && fileContent[9] == 0x45 && fileContent[10] == 0x42 && fileContent[11] == 0x50:
-			return "image/webp"
-		}
-	}
-	// Fall back to extension
-	if ext := path.Ext(filename); ext != "" {
-		if t := mime.TypeByExtension(ext); t != "" && strings.HasPrefix(t, "image/") {
-			return strings.TrimSpace(strings.Split(t, ";")[0])
-		}
-	}
-	return "image/jpeg"
-}
-
-// readImageAndProcessWithProvider sends one image to image-reader/read-and-process with the given provider/model.
-func (h *Handlers) readImageAndProcessWithProvider(fileContent []byte, filename string, systemPrompt string, provider, model string) (extractedText, aiResult string, err error) {
-	base := strings.TrimSuffix(h.externalAPIBase, "/")
-	url := base + "/image-reader/read-and-process"
-
-	body := &bytes.Buffer{}
-	w := multipart.NewWriter(body)
-
-	_ = w.WriteField("system_prompt", systemPrompt)
-	_ = w.WriteField("provider", provider)
-	_ = w.WriteField("model", model)
-	contentType := detectImageContentType(fileContent, filename)
-	part, err := w.CreatePart(map[string][]string{
-		"Content-Disposition": {`form-data; name="file"; filename="` + filename + `"`},
-		"Content-Type":       {contentType},
-	})
-	if err != nil {
-		return "", "", err
-	}
-	if _, err := part.Write(fileContent); err != nil {
-		return "", "", err
-	}
-	if err := w.Close(); err != nil {
-		return "", "", err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, url, body)
-	if err != nil {
-		return "", "", err
-	}
-	req.Header.Set("Content-Type", w.FormDataContentType())
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", "", err
-	}
-	defer resp.Body.Close()
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", err
-	}
-	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("image-reader returned %d: %s", resp.StatusCode, string(data))
-	}
-
-	var out struct {
-		Success       bool   `json:"success"`
-		ExtractedText string `json:"extracted_text"`
-		AIResult      string `json:"ai_result"`
-	}
-	if err := json.Unmarshal(data, &out); err != nil {
-		return "", "", err
-	}
-	if !out.Success {
-		return "", "", fmt.Errorf("image-reader success=false")
-	}
-	return out.ExtractedText, out.AIResult, nil
-}
-
-// ReadImageAndProcess sends one image to image-reader/read-and-process. Tries Qwen first, then Mistral on failure.
-func (h *Handlers) ReadImageAndProcess(file io.Reader, filename string, systemPrompt string) (extractedText, aiResult string, err error) {
-	if systemPrompt == "" {
-		systemPrompt = defaultSummarizePrompt
-	}
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		return "", "", err
-	}
-	var lastErr error
-	for _, pm := range imageReaderProviderModels {
-		extractedText, aiResult, err := h.readImageAndProcessWithProvider(fileContent, filename, systemPrompt, pm.provider, pm.model)
-		if err == nil {
-			return extractedText, aiResult, nil
-		}
-		lastErr = err
-	}
-	return "", "", lastErr
-}
-
-// readPDFAndProcessWithProvider sends a PDF to pdf-reader/read with the given llm_provider and model_name.
-func (h *Handlers) readPDFAndProcessWithProvider(fileContent []byte, filename string, systemPrompt string, provider, model string) (extractedText, aiResult string, err error) {
-	base := strings.TrimSuffix(h.externalAPIBase, "/")
-	url := base + "/pdf-reader/read"
-
-	body := &bytes.Buffer{}
-	w := multipart.NewWriter(body)
-
-	_ = w.WriteField("system_prompt", systemPrompt)
-	_ = w.WriteField("llm_provider", provider)
-	_ = w.WriteField("model_name", model)
-	part, err := w.CreateFormFile("file", filename)
-	if err != nil {
-		return "", "", err
-	}
-	if _, err := part.Write(fileContent); err != nil {
-		return "", "", err
-	}
-	if err := w.Close(); err != nil {
-		return "", "", err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, url, body)
-	if err != nil {
-		return "", "", err
-	}
-	req.Header.Set("Content-Type", w.FormDataContentType())
-
-	client := &http.Client{Timeout: 180 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", "", err
-	}
-	defer resp.Body.Close()
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", err
-	}
-	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("pdf-reader returned %d: %s", resp.StatusCode, string(data))
-	}
-
-	var out struct {
-		Success       bool   `json:"success"`
-		ExtractedText string `json:"extracted_text"`
-		AIResult      string `json:"ai_result"`
-	}
-	if err := json.Unmarshal(data, &out); err != nil {
-		return "", "", err
-	}
-	if !out.Success {
-		return "", "", fmt.Errorf("pdf-reader success=false")
-	}
-	return out.ExtractedText, out.AIResult, nil
-}
-
-// ReadPDFAndProcess sends a PDF to pdf-reader/read. Tries Qwen first, then Mistral on failure.
-func (h *Handlers) ReadPDFAndProcess(file io.Reader, filename string, systemPrompt string) (extractedText, aiResult string, err error) {
-	if systemPrompt == "" {
-		systemPrompt = defaultSummarizePrompt
-	}
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		return "", "", err
-	}
-	var lastErr error
-	for _, pm := range imageReaderProviderModels {
-		extractedText, aiResult, err := h.readPDFAndProcessWithProvider(fileContent, filename, systemPrompt, pm.provider, pm.model)
-		if err == nil {
-			return extractedText, aiResult, nil
-		}
-		lastErr = err
-	}
-	return "", "", lastErr
-}
-
-// Gather calls the gathering API for web research and returns the markdown content.
-func (h *Handlers) Gather(prompt string, maxIterations int) (content string, err error) {
-	if maxIterations <= 0 {
-		maxIterations = 10
-	}
-	if maxIterations > 20 {
-		maxIterations = 20
-	}
-	base := strings.TrimSuffix(h.externalAPIBase, "/")
-	url := base + "/gathering/gather"
-
-	body := struct {
-		Prompt         string  `json:"prompt"`
-		MaxIterations  int     `json:"max_iterations"`
-		LLMProvider    string  `json:"llm_provider,omitempty"`
-		ModelName      string  `json:"model_name,omitempty"`
-		MaxTokens      int     `json:"max_tokens,omitempty"`
-		Temperature    float64 `json:"temperature,omitempty"`
-	}{Prompt: prompt, MaxIterations: maxIterations}
-	data, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 300 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	respData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("gathering returned %d: %s", resp.StatusCode, string(respData))
-	}
-
-	var out struct {
-		Success bool   `json:"success"`
-		Content string `json:"content"`
-	}
-	if err := json.Unmarshal(respData, &out); err != nil {
-		return "", err
-	}
-	if !out.Success {
-		return "", fmt.Errorf("gathering success=false")
-	}
-	return out.Content, nil
-}
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSummarizePrompt = "Summarize the following content clearly and concisely."
+	// Image/PDF reader: try Qwen first, then Mistral as fallback.
+	imageReaderProviderPrimary   = "qwen"
+	imageReaderModelPrimary      = "qwen-vl-plus"
+	imageReaderProviderFallback  = "mistral"
+	imageReaderModelFallback     = "mistral-small-latest"
+)
+
+// imageReaderProviderModel pairs provider and model for read-and-process.
+var imageReaderProviderModels = []struct{ provider, model string }{
+	{imageReaderProviderPrimary, imageReaderModelPrimary},
+	{imageReaderProviderFallback, imageReaderModelFallback},
+}
+
+// readerRetryDelay is the pause before retrying a provider after a transient failure.
+const readerRetryDelay = 2 * time.Second
+
+// readerStatusError is a non-200 response from the document reader / gathering services.
+type readerStatusError struct {
+	service    string
+	statusCode int
+	body       string
+}
+
+func (e *readerStatusError) Error() string {
+	return fmt.Sprintf("%s returned HTTP %d: %s", e.service, e.statusCode, bodySnippet(e.body))
+}
+
+var (
+	htmlTitlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlTagPattern   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// bodySnippet returns a short readable excerpt of a response body for error messages.
+// HTML error pages (e.g. from a proxy or gateway) are reduced to their title or text.
+func bodySnippet(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if strings.HasPrefix(trimmed, "<") {
+		if m := htmlTitlePattern.FindStringSubmatch(trimmed); m != nil && strings.TrimSpace(m[1]) != "" {
+			trimmed = m[1]
+		} else {
+			trimmed = htmlTagPattern.ReplaceAllString(trimmed, " ")
+		}
+		trimmed = strings.Join(strings.Fields(trimmed), " ")
+	}
+	if short, truncated := truncateRunes(trimmed, 300); truncated {
+		trimmed = short + "..."
+	}
+	return trimmed
+}
+
+// decodeReaderResponse checks the status and content of a reader/gathering response and
+// decodes the JSON body into out. Non-JSON bodies (such as HTML gateway pages) are reported
+// with the status and a snippet instead of a JSON parse error.
+func decodeReaderResponse(service string, resp *http.Response, data []byte, out interface{}) error {
+	if resp.StatusCode != http.StatusOK {
+		return &readerStatusError{service: service, statusCode: resp.StatusCode, body: string(data)}
+	}
+	contentType := resp.Header.Get("Content-Type")
+	trimmed := bytes.TrimSpace(data)
+	if !strings.Contains(contentType, "json") && (len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[')) {
+		return fmt.Errorf("%s returned a non-JSON response (HTTP %d, %s): %s", service, resp.StatusCode, contentType, bodySnippet(string(data)))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s returned invalid JSON (HTTP %d): %v: %s", service, resp.StatusCode, err, bodySnippet(string(data)))
+	}
+	return nil
+}
+
+// isTransientReaderError reports whether err is a timeout or 5xx worth retrying on the same provider.
+func isTransientReaderError(err error) bool {
+	var statusErr *readerStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.statusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// withReaderRetry runs call once more after a transient failure before giving up on the provider.
+// The pause before the retry ends early when ctx is done.
+func withReaderRetry(ctx context.Context, provider string, call func() (string, string, error)) (string, string, error) {
+	extractedText, aiResult, err := call()
+	if err != nil && isTransientReaderError(err) {
+		log.Printf("[DOCUMENT] Provider %s failed (%v), retrying in %v", provider, err, readerRetryDelay)
+		select {
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		case <-time.After(readerRetryDelay):
+		}
+		extractedText, aiResult, err = call()
+	}
+	return extractedText, aiResult, err
+}
+
+// detectImageContentType returns an image/* MIME type for the image-reader. Uses content
+// detection first so uploads sent as application/octet-stream are sent with the correct type.
+func detectImageContentType(fileContent []byte, filename string) string {
+	detected := http.DetectContentType(fileContent)
+	if strings.HasPrefix(detected, "image/") {
+		return strings.TrimSpace(strings.Split(detected, ";")[0])
+	}
+	// Magic bytes for common image formats (when DetectContentType returns application/octet-stream)
+	if len(fileContent) >= 8 {
+		switch {
+		case len(fileContent) >= 3 && fileContent[0] == 0xFF && fileContent[1] == 0xD8 && fileContent[2] == 0xFF:
+			return "image/jpeg"
+		case len(fileContent) >= 8 && fileContent[0] == 0x89 && fileContent[1] == 0x50 && fileContent[2] == 0x4E && fileContent[3] == 0x47:
+			return "image/png"
+		case len(fileContent) >= 6 && fileContent[0] == 0x47 && fileContent[1] == 0x49 && fileContent[2] == 0x46:
+			return "image/gif"
+		case len(fileContent) >= 12 && fileContent[0] == 0x52 && fileContent[1] == 0x49 && fileContent[2] == 0x46 && fileContent[3] == 0x46 &&
+			fileContent[8] == 0x57 && fileContent[9] == 0x45 && fileContent[10] == 0x42 && fileContent[11] == 0x50:
+			return "image/webp"
+		}
+	}
+	// Fall back to extension
+	if ext := path.Ext(filename); ext != "" {
+		if t := mime.TypeByExtension(ext); t != "" && strings.HasPrefix(t, "image/") {
+			return strings.TrimSpace(strings.Split(t, ";")[0])
+		}
+	}
+	return "image/jpeg"
+}
+
+// readImageAndProcessWithProvider sends one image to image-reader/read-and-process with the given provider/model.
+func (h *Handlers) readImageAndProcessWithProvider(ctx context.Context, fileContent []byte, filename string, systemPrompt string, provider, model string) (extractedText, aiResult string, err error) {
+	base := strings.TrimSuffix(h.externalAPIBase, "/")
+	url := base + "/image-reader/read-and-process"
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	_ = w.WriteField("system_prompt", systemPrompt)
+	_ = w.WriteField("provider", provider)
+	_ = w.WriteField("model", model)
+	contentType := detectImageContentType(fileContent, filename)
+	part, err := w.CreatePart(map[string][]string{
+		"Content-Disposition": {`form-data; name="file"; filename="` + filename + `"`},
+		"Content-Type":       {contentType},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := part.Write(fileContent); err != nil {
+		return "", "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	client := &http.Client{Timeout: 120 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	var out struct {
+		Success       bool   `json:"success"`
+		ExtractedText string `json:"extracted_text"`
+		AIResult      string `json:"ai_result"`
+	}
+	if err := decodeReaderResponse("image-reader", resp, data, &out); err != nil {
+		return "", "", err
+	}
+	if !out.Success {
+		return "", "", fmt.Errorf("image-reader success=false")
+	}
+	return out.ExtractedText, out.AIResult, nil
+}
+
+// ReadImageAndProcess sends one image to image-reader/read-and-process. Tries Qwen first, then Mistral on failure;
+// each provider gets one retry on timeouts/5xx.
+func (h *Handlers) ReadImageAndProcess(ctx context.Context, file io.Reader, filename string, systemPrompt string) (extractedText, aiResult string, err error) {
+	if systemPrompt == "" {
+		systemPrompt = h.cfg.SummarizePrompt
+	}
+	if systemPrompt == "" {
+		systemPrompt = defaultSummarizePrompt
+	}
+	fileContent, err := io.ReadAll(file)
+	if err != nil {
+		return "", "", err
+	}
+	var lastErr error
+	for _, pm := range imageReaderProviderModels {
+		extractedText, aiResult, err := withReaderRetry(ctx, pm.provider, func() (string, string, error) {
+			return h.readImageAndProcessWithProvider(ctx, fileContent, filename, systemPrompt, pm.provider, pm.model)
+		})
+		if err == nil {
+			return extractedText, aiResult, nil
+		}
+		lastErr = fmt.Errorf("%s/%s: %w", pm.provider, pm.model, err)
+	}
+	return "", "", lastErr
+}
+
+// readPDFAndProcessWithProvider sends a PDF to pdf-reader/read with the given llm_provider and model_name.
+func (h *Handlers) readPDFAndProcessWithProvider(ctx context.Context, fileContent []byte, filename string, systemPrompt string, provider, model string) (extractedText, aiResult string, err error) {
+	base := strings.TrimSuffix(h.externalAPIBase, "/")
+	url := base + "/pdf-reader/read"
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	_ = w.WriteField("system_prompt", systemPrompt)
+	_ = w.WriteField("llm_provider", provider)
+	_ = w.WriteField("model_name", model)
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := part.Write(fileContent); err != nil {
+		return "", "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	client := &http.Client{Timeout: 180 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	var out struct {
+		Success       bool   `json:"success"`
+		ExtractedText string `json:"extracted_text"`
+		AIResult      string `json:"ai_result"`
+	}
+	if err := decodeReaderResponse("pdf-reader", resp, data, &out); err != nil {
+		return "", "", err
+	}
+	if !out.Success {
+		return "", "", fmt.Errorf("pdf-reader success=false")
+	}
+	return out.ExtractedText, out.AIResult, nil
+}
+
+// ReadPDFAndProcess sends a PDF to pdf-reader/read. Tries Qwen first, then Mistral on failure;
+// each provider gets one retry on timeouts/5xx.
+func (h *Handlers) ReadPDFAndProcess(ctx context.Context, file io.Reader, filename string, systemPrompt string) (extractedText, aiResult string, err error) {
+	if systemPrompt == "" {
+		systemPrompt = h.cfg.SummarizePrompt
+	}
+	if systemPrompt == "" {
+		systemPrompt = defaultSummarizePrompt
+	}
+	fileContent, err := io.ReadAll(file)
+	if err != nil {
+		return "", "", err
+	}
+	var lastErr error
+	for _, pm := range imageReaderProviderModels {
+		extractedText, aiResult, err := withReaderRetry(ctx, pm.provider, func() (string, string, error) {
+			return h.readPDFAndProcessWithProvider(ctx, fileContent, filename, systemPrompt, pm.provider, pm.model)
+		})
+		if err == nil {
+			return extractedText, aiResult, nil
+		}
+		lastErr = fmt.Errorf("%s/%s: %w", pm.provider, pm.model, err)
+	}
+	return "", "", lastErr
+}
+
+// Gather calls the gathering API for web research and returns the markdown content.
+// The call is aborted when ctx is canceled (e.g. the client disconnects).
+func (h *Handlers) Gather(ctx context.Context, prompt string, maxIterations int) (content string, err error) {
+	if maxIterations <= 0 {
+		maxIterations = 10
+	}
+	if maxIterations > 20 {
+		maxIterations = 20
+	}
+	base := strings.TrimSuffix(h.externalAPIBase, "/")
+	url := base + "/gathering/gather"
+
+	body := struct {
+		Prompt         string  `json:"prompt"`
+		MaxIterations  int     `json:"max_iterations"`
+		LLMProvider    string  `json:"llm_provider,omitempty"`
+		ModelName      string  `json:"model_name,omitempty"`
+		MaxTokens      int     `json:"max_tokens,omitempty"`
+		Temperature    float64 `json:"temperature,omitempty"`
+	}{Prompt: prompt, MaxIterations: maxIterations}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 300*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("gathering aborted: %w", ctx.Err())
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Success bool   `json:"success"`
+		Content string `json:"content"`
+	}
+	if err := decodeReaderResponse("gathering", resp, respData, &out); err != nil {
+		return "", err
+	}
+	if !out.Success {
+		return "", fmt.Errorf("gathering success=false")
+	}
+	return out.Content, nil
+}
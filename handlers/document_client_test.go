@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithReaderRetryStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	start := time.Now()
+	_, _, err := withReaderRetry(ctx, "qwen", func() (string, string, error) {
+		calls++
+		return "", "", &readerStatusError{service: "image-reader", statusCode: 503}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("call ran %d times, want 1", calls)
+	}
+	if elapsed := time.Since(start); elapsed >= readerRetryDelay {
+		t.Errorf("returned after %v, want before the %v retry delay", elapsed, readerRetryDelay)
+	}
+}
+
+func TestWithReaderRetryDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	_, _, err := withReaderRetry(context.Background(), "qwen", func() (string, string, error) {
+		calls++
+		return "", "", &readerStatusError{service: "image-reader", statusCode: 400}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("err = %v after %d calls, want the 400 error after 1 call", err, calls)
+	}
+}
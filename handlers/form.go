@@ -13,7 +13,7 @@ import (
 
 // GenerateHTMLHandler generates an HTML page from a result file
 // @Summary      Generate HTML page
-// @Description  Use AI to generate a professional HTML page displaying the content of a result file
+// @Description  Use AI to generate a professional HTML page displaying the content of a result file (theme: light, dark or branded with theme_colors)
 // @Tags         Results
 // @Accept       json
 // @Produce      json
@@ -31,6 +31,15 @@ func (h *Handlers) GenerateHTMLHandler(c *gin.Context) {
 		return
 	}
 
+	theme := models.HTMLTheme{Name: req.Theme}
+	if req.ThemeColors != nil {
+		theme.Colors = *req.ThemeColors
+	}
+	if theme.Name != "" && theme.Name != "light" && theme.Name != "dark" && theme.Name != "branded" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Theme must be 'light', 'dark' or 'branded'"})
+		return
+	}
+
 	if h.sqlService == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SQL Server service is not configured"})
 		return
@@ -56,7 +65,7 @@ func (h *Handlers) GenerateHTMLHandler(c *gin.Context) {
 	}
 
 	// Generate HTML using AI
-	html, err := h.aiService.GenerateHTMLPage(resultFile, title)
+	html, err := h.aiService.GenerateHTMLPage(c.Request.Context(), resultFile, title, theme, h.htmlDataURL(req.Filename, resultFile))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to generate HTML: %v", err)})
 		return
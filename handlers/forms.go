@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
+	"idongivaflyinfa/db"
 	"idongivaflyinfa/models"
+	"idongivaflyinfa/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -41,26 +43,36 @@ func (h *Handlers) CreateFormTemplateHandler(c *gin.Context) {
 		return
 	}
 
+	tags, err := validation.NormalizeTags(template.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	template.Tags = tags
+
+	if template.IdentityField != "" && !hasFormField(template.Fields, template.IdentityField) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Identity field %q is not one of the form's fields", template.IdentityField)})
+		return
+	}
+
 	// Generate ID if not provided
 	if template.ID == "" {
 		template.ID = uuid.New().String()
+	} else if !validation.IsValidID(template.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form template ID"})
+		return
 	}
 
 	// Set timestamps
-	now := time.Now().Format(time.RFC3339)
+	now := models.NowTimestamp()
 	template.CreatedAt = now
 	template.UpdatedAt = now
 
-	// Get user ID from header or use default
-	userID := c.GetHeader("X-User-ID")
-	if userID == "" {
-		userID = "admin"
-	}
-	template.CreatedBy = userID
+	template.CreatedBy = h.actorID(c)
 
 	// Store in database
 	if err := h.db.StoreFormTemplate(&template); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create form template: %v", err)})
+		c.JSON(storeErrorStatus(err), gin.H{"error": fmt.Sprintf("Failed to create form template: %v", err)})
 		return
 	}
 
@@ -78,8 +90,8 @@ func (h *Handlers) CreateFormTemplateHandler(c *gin.Context) {
 // @Router       /api/forms/templates/{id} [get]
 func (h *Handlers) GetFormTemplateHandler(c *gin.Context) {
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Form template ID is required"})
+	if !validation.IsValidID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form template ID"})
 		return
 	}
 
@@ -94,10 +106,11 @@ func (h *Handlers) GetFormTemplateHandler(c *gin.Context) {
 
 // ListFormTemplatesHandler lists all form templates
 // @Summary      List form templates
-// @Description  Get all form templates, optionally filtered by user type
+// @Description  Get all form templates, optionally filtered by user type and tag
 // @Tags         Forms
 // @Produce      json
 // @Param        user_type  query     string  false  "Filter by user type (student or staff)"
+// @Param        tag        query     string  false  "Only templates with this tag"
 // @Success      200        {array}   models.FormTemplate
 // @Failure      500        {object}  map[string]string
 // @Router       /api/forms/templates [get]
@@ -120,6 +133,16 @@ func (h *Handlers) ListFormTemplatesHandler(c *gin.Context) {
 		templates = filtered
 	}
 
+	if tag := c.Query("tag"); tag != "" {
+		var filtered []models.FormTemplate
+		for _, template := range templates {
+			if validation.HasTag(template.Tags, tag) {
+				filtered = append(filtered, template)
+			}
+		}
+		templates = filtered
+	}
+
 	c.JSON(http.StatusOK, templates)
 }
 
@@ -138,8 +161,8 @@ func (h *Handlers) ListFormTemplatesHandler(c *gin.Context) {
 // @Router       /api/forms/templates/{id} [put]
 func (h *Handlers) UpdateFormTemplateHandler(c *gin.Context) {
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Form template ID is required"})
+	if !validation.IsValidID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form template ID"})
 		return
 	}
 
@@ -162,15 +185,75 @@ func (h *Handlers) UpdateFormTemplateHandler(c *gin.Context) {
 		return
 	}
 
+	// Tags are kept unless the update sets them
+	if template.Tags == nil {
+		template.Tags = existing.Tags
+	} else if template.Tags, err = validation.NormalizeTags(template.Tags); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if template.IdentityField != "" && !hasFormField(template.Fields, template.IdentityField) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Identity field %q is not one of the form's fields", template.IdentityField)})
+		return
+	}
+
 	// Preserve ID and creation info
 	template.ID = id
 	template.CreatedAt = existing.CreatedAt
 	template.CreatedBy = existing.CreatedBy
-	template.UpdatedAt = time.Now().Format(time.RFC3339)
+	template.UpdatedAt = models.NowTimestamp()
 
 	// Store updated template
 	if err := h.db.StoreFormTemplate(&template); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update form template: %v", err)})
+		c.JSON(storeErrorStatus(err), gin.H{"error": fmt.Sprintf("Failed to update form template: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// SetFormTemplateTagsHandler replaces the tags of a form template
+// @Summary      Set form template tags
+// @Description  Replace the tags of a form template. Tags are lower-cased and de-duplicated; an empty list clears them.
+// @Tags         Forms
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string              true  "Form template ID"
+// @Param        request  body      models.TagsRequest  true  "Tags"
+// @Success      200      {object}  models.FormTemplate
+// @Failure      400      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /api/forms/templates/{id}/tags [put]
+func (h *Handlers) SetFormTemplateTagsHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !validation.IsValidID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form template ID"})
+		return
+	}
+
+	var req models.TagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+		return
+	}
+	tags, err := validation.NormalizeTags(req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.db.GetFormTemplate(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Form template not found"})
+		return
+	}
+	template.Tags = tags
+	template.UpdatedAt = models.NowTimestamp()
+
+	if err := h.db.StoreFormTemplate(template); err != nil {
+		c.JSON(storeErrorStatus(err), gin.H{"error": fmt.Sprintf("Failed to update form template: %v", err)})
 		return
 	}
 
@@ -189,8 +272,8 @@ func (h *Handlers) UpdateFormTemplateHandler(c *gin.Context) {
 // @Router       /api/forms/templates/{id} [delete]
 func (h *Handlers) DeleteFormTemplateHandler(c *gin.Context) {
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Form template ID is required"})
+	if !validation.IsValidID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form template ID"})
 		return
 	}
 
@@ -202,17 +285,42 @@ func (h *Handlers) DeleteFormTemplateHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Form template deleted successfully"})
 }
 
+// ValidateFormTemplateHandler validates form template JSON without saving it
+// @Summary      Validate form template
+// @Description  Check form template JSON for structural problems (missing keys, invalid field types) before saving
+// @Tags         Forms
+// @Accept       json
+// @Produce      json
+// @Param        template  body      models.FormTemplate  true  "Form template JSON"
+// @Success      200       {object}  map[string]interface{}  "valid flag and list of errors"
+// @Failure      400       {object}  map[string]string
+// @Router       /api/forms/validate [post]
+func (h *Handlers) ValidateFormTemplateHandler(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil || len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request body must contain form JSON"})
+		return
+	}
+
+	errs := validation.ValidateFormTemplateJSON(data)
+	if errs == nil {
+		errs = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"valid": len(errs) == 0, "errors": errs})
+}
+
 // Form Answer Handlers
 
 // CreateFormAnswerHandler creates a new form answer
 // @Summary      Create form answer
-// @Description  Submit a new form answer
+// @Description  Submit a new form answer (public survey forms accept anonymous answers)
 // @Tags         Form Answers
 // @Accept       json
 // @Produce      json
 // @Param        answer  body      models.FormAnswer  true  "Form answer"
 // @Success      200     {object}  models.FormAnswer
 // @Failure      400     {object}  map[string]string
+// @Failure      429     {object}  map[string]string
 // @Failure      500     {object}  map[string]string
 // @Router       /api/forms/answers [post]
 func (h *Handlers) CreateFormAnswerHandler(c *gin.Context) {
@@ -227,12 +335,18 @@ func (h *Handlers) CreateFormAnswerHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Form ID is required"})
 		return
 	}
-	if answer.UserID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+	if !validation.IsValidID(answer.FormID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
 		return
 	}
-	if answer.UserType != "student" && answer.UserType != "staff" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User type must be 'student' or 'staff'"})
+	if answer.ID != "" && !validation.IsValidID(answer.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form answer ID"})
+		return
+	}
+
+	// Throttle repeated submissions to the same form from one client
+	if !h.answerLimiter.Allow(answer.FormID + ":" + c.ClientIP()) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many submissions for this form, please try again later"})
 		return
 	}
 
@@ -243,6 +357,23 @@ func (h *Handlers) CreateFormAnswerHandler(c *gin.Context) {
 		return
 	}
 
+	// Public surveys accept anonymous answers; everything else needs a registered user
+	if formTemplate.Public {
+		if answer.UserID == "" {
+			answer.UserID = "anonymous"
+		}
+		if answer.UserType == "" {
+			answer.UserType = formTemplate.UserType
+		}
+	} else if answer.UserID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+	if answer.UserType != "student" && answer.UserType != "staff" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User type must be 'student' or 'staff'"})
+		return
+	}
+
 	// Generate ID if not provided
 	if answer.ID == "" {
 		answer.ID = uuid.New().String()
@@ -252,18 +383,21 @@ func (h *Handlers) CreateFormAnswerHandler(c *gin.Context) {
 	answer.FormName = formTemplate.Name
 
 	// Set timestamp
-	answer.SubmittedAt = time.Now().Format(time.RFC3339)
+	answer.SubmittedAt = models.NowTimestamp()
 
-	// Get user ID from header or use provided
-	userID := c.GetHeader("X-User-ID")
+	// Submitter is the caller's user ID, else the answer's user, else the anonymous fallback
+	userID := explicitUserID(c)
+	if userID == "" {
+		userID = cleanUserID(answer.UserID)
+	}
 	if userID == "" {
-		userID = answer.UserID
+		userID = h.actorID(c)
 	}
 	answer.SubmittedBy = userID
 
 	// Store in database
 	if err := h.db.StoreFormAnswer(&answer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create form answer: %v", err)})
+		c.JSON(storeErrorStatus(err), gin.H{"error": fmt.Sprintf("Failed to create form answer: %v", err)})
 		return
 	}
 
@@ -281,8 +415,8 @@ func (h *Handlers) CreateFormAnswerHandler(c *gin.Context) {
 // @Router       /api/forms/answers/{id} [get]
 func (h *Handlers) GetFormAnswerHandler(c *gin.Context) {
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Form answer ID is required"})
+	if !validation.IsValidID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form answer ID"})
 		return
 	}
 
@@ -297,21 +431,33 @@ func (h *Handlers) GetFormAnswerHandler(c *gin.Context) {
 
 // ListFormAnswersHandler lists all form answers
 // @Summary      List form answers
-// @Description  Get all form answers, optionally filtered by form ID or user ID
+// @Description  Get a page of form answers, optionally filtered by form ID or user ID
 // @Tags         Form Answers
 // @Produce      json
 // @Param        form_id  query     string  false  "Filter by form ID"
 // @Param        user_id  query     string  false  "Filter by user ID"
-// @Success      200      {array}   models.FormAnswer
+// @Param        limit    query     int     false  "Maximum answers to return (default: all)"
+// @Param        offset   query     int     false  "Answers to skip"
+// @Success      200      {object}  models.Page[models.FormAnswer]
+// @Failure      400      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /api/forms/answers [get]
 func (h *Handlers) ListFormAnswersHandler(c *gin.Context) {
 	formID := c.Query("form_id")
 	userID := c.Query("user_id")
+	limit, offset, ok := queryPage(c)
+	if !ok {
+		return
+	}
 
 	var answers []models.FormAnswer
 	var err error
 
+	if formID != "" && !validation.IsValidID(formID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+		return
+	}
+
 	if formID != "" {
 		answers, err = h.db.GetFormAnswersByFormID(formID)
 	} else if userID != "" {
@@ -325,7 +471,7 @@ func (h *Handlers) ListFormAnswersHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, answers)
+	c.JSON(http.StatusOK, models.NewPage(answers, limit, offset))
 }
 
 // UpdateFormAnswerHandler updates an existing form answer
@@ -343,8 +489,8 @@ func (h *Handlers) ListFormAnswersHandler(c *gin.Context) {
 // @Router       /api/forms/answers/{id} [put]
 func (h *Handlers) UpdateFormAnswerHandler(c *gin.Context) {
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Form answer ID is required"})
+	if !validation.IsValidID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form answer ID"})
 		return
 	}
 
@@ -374,6 +520,10 @@ func (h *Handlers) UpdateFormAnswerHandler(c *gin.Context) {
 
 	// Update form name if form ID changed
 	if answer.FormID != "" && answer.FormID != existing.FormID {
+		if !validation.IsValidID(answer.FormID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
 		formTemplate, err := h.db.GetFormTemplate(answer.FormID)
 		if err == nil {
 			answer.FormName = formTemplate.Name
@@ -385,7 +535,7 @@ func (h *Handlers) UpdateFormAnswerHandler(c *gin.Context) {
 
 	// Store updated answer
 	if err := h.db.StoreFormAnswer(&answer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update form answer: %v", err)})
+		c.JSON(storeErrorStatus(err), gin.H{"error": fmt.Sprintf("Failed to update form answer: %v", err)})
 		return
 	}
 
@@ -404,8 +554,8 @@ func (h *Handlers) UpdateFormAnswerHandler(c *gin.Context) {
 // @Router       /api/forms/answers/{id} [delete]
 func (h *Handlers) DeleteFormAnswerHandler(c *gin.Context) {
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Form answer ID is required"})
+	if !validation.IsValidID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form answer ID"})
 		return
 	}
 
@@ -416,3 +566,21 @@ func (h *Handlers) DeleteFormAnswerHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Form answer deleted successfully"})
 }
+
+// hasFormField reports whether fields contains a field with the given name
+func hasFormField(fields []models.FormField, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// storeErrorStatus is the HTTP status for a failed store: 413 when the record is too large to keep
+func storeErrorStatus(err error) int {
+	if errors.Is(err, db.ErrValueTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"idongivaflyinfa/ai"
+	"idongivaflyinfa/config"
 	"idongivaflyinfa/db"
 	"idongivaflyinfa/service"
 )
@@ -23,6 +24,10 @@ import (
 
 // @schemes   http https
 
+// @securityDefinitions.apikey  AdminToken
+// @in                          header
+// @name                        X-Admin-Token
+
 // Handlers contains all handler dependencies
 type Handlers struct {
 	db                *db.DB
@@ -32,17 +37,27 @@ type Handlers struct {
 	voiceService      *service.VoiceService
 	sqlFilesDir       string
 	externalAPIBase   string
+	cfg               config.Config
+	answerLimiter     *rateLimiter
+	cookieSecret      []byte
 }
 
 // New creates a new Handlers instance
-func New(db *db.DB, aiService *ai.AIService, sqlService *service.SQLServerService, sqlFilesDir string, voiceSamplesDir string, externalAPIBase string) *Handlers {
+func New(db *db.DB, aiService *ai.AIService, sqlService *service.SQLServerService, cfg config.Config) *Handlers {
+	cookieSecret := []byte(cfg.UserIDCookieSecret)
+	if len(cookieSecret) == 0 {
+		cookieSecret = newCookieSecret()
+	}
 	return &Handlers{
 		db:               db,
 		aiService:        aiService,
 		sqlService:       sqlService,
-		complaintService: service.NewComplaintService(),
-		voiceService:     service.NewVoiceService(voiceSamplesDir),
-		sqlFilesDir:      sqlFilesDir,
-		externalAPIBase:  externalAPIBase,
+		complaintService: service.NewComplaintService(cfg.ComplaintNResults),
+		voiceService:     service.NewVoiceService(cfg.VoiceSamplesDir),
+		sqlFilesDir:      cfg.SQLFilesDir,
+		externalAPIBase:  cfg.ExternalAPIBase,
+		cfg:              cfg,
+		answerLimiter:    newRateLimiter(cfg.FormAnswerRateLimit, cfg.FormAnswerRateWindow),
+		cookieSecret:     cookieSecret,
 	}
 }
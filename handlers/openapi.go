@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swaggo/swag"
+)
+
+// OpenAPIHandler returns the generated Swagger/OpenAPI document as JSON
+// @Summary      OpenAPI spec
+// @Description  Get the generated Swagger/OpenAPI document so tooling can import the API
+// @Tags         Health
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "OpenAPI document"
+// @Failure      500  {object}  map[string]string       "Spec not available"
+// @Router       /api/openapi.json [get]
+func (h *Handlers) OpenAPIHandler(c *gin.Context) {
+	doc, err := swag.ReadDoc()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load OpenAPI spec: " + err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(doc))
+}
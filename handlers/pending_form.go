@@ -9,6 +9,8 @@ import (
 var (
 	pendingFormMu   sync.RWMutex
 	pendingFormByUser = make(map[string]*models.FormTemplate)
+	// pendingProposalByUser maps user ID to the FormProposal ID backing the pending form
+	pendingProposalByUser = make(map[string]string)
 )
 
 func getPendingForm(userID string) *models.FormTemplate {
@@ -22,6 +24,7 @@ func setPendingForm(userID string, t *models.FormTemplate) {
 	defer pendingFormMu.Unlock()
 	if t == nil {
 		delete(pendingFormByUser, userID)
+		delete(pendingProposalByUser, userID)
 		return
 	}
 	pendingFormByUser[userID] = t
@@ -30,3 +33,15 @@ func setPendingForm(userID string, t *models.FormTemplate) {
 func clearPendingForm(userID string) {
 	setPendingForm(userID, nil)
 }
+
+func getPendingProposalID(userID string) string {
+	pendingFormMu.RLock()
+	defer pendingFormMu.RUnlock()
+	return pendingProposalByUser[userID]
+}
+
+func setPendingProposalID(userID, proposalID string) {
+	pendingFormMu.Lock()
+	defer pendingFormMu.Unlock()
+	pendingProposalByUser[userID] = proposalID
+}
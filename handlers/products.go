@@ -2,93 +2,241 @@ package handlers
 
 import (
 	"fmt"
+	"log"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
+	"idongivaflyinfa/models"
+	"idongivaflyinfa/service"
+
 	"github.com/gin-gonic/gin"
 )
 
-// ProductFileInfo represents information about a product HTML file
-type ProductFileInfo struct {
-	Filename string `json:"filename"`
-	Size     int64  `json:"size"`
-	Modified string `json:"modified"`
-	Type     string `json:"type"` // "form" or "result"
+const productsDir = "products"
+
+// productFileInfo builds the index entry for a product file on disk
+func productFileInfo(filename string, info os.FileInfo) models.ProductFileInfo {
+	// Determine file type based on filename
+	fileType := "result"
+	if strings.HasPrefix(filename, "form_") {
+		fileType = "form"
+	}
+	return models.ProductFileInfo{
+		Filename: filename,
+		Size:     info.Size(),
+		Modified: models.Timestamp(info.ModTime()),
+		Type:     fileType,
+	}
 }
 
-// ListProductsHandler lists all HTML files in the products folder
-// @Summary      List product files
-// @Description  Get a list of all HTML files in the products folder
-// @Tags         Products
-// @Produce      json
-// @Success      200  {object}  map[string][]ProductFileInfo  "List of product files"
-// @Failure      500  {object}  map[string]string            "Failed to list files"
-// @Router       /api/products/files [get]
-func (h *Handlers) ListProductsHandler(c *gin.Context) {
-	productsDir := "products"
-	
-	// Ensure directory exists
-	if err := os.MkdirAll(productsDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create products directory: %v", err)})
+// recordProduct adds a freshly written product file to the index
+func (h *Handlers) recordProduct(htmlPath string) {
+	info, err := os.Stat(htmlPath)
+	if err != nil {
+		log.Printf("[PRODUCTS] Error indexing %s: %v", htmlPath, err)
 		return
 	}
+	entry := productFileInfo(filepath.Base(htmlPath), info)
+	if err := h.db.StoreProduct(&entry); err != nil {
+		log.Printf("[PRODUCTS] Error indexing %s: %v", htmlPath, err)
+	}
+}
+
+// saveProduct writes an HTML file to the products folder and indexes it, returning its path.
+// Failing to create the folder or write the file is returned; an indexing failure is only
+// logged since ReconcileProducts picks the file up at the next startup.
+func (h *Handlers) saveProduct(filename string, content []byte) (string, error) {
+	if err := os.MkdirAll(productsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create products directory: %w", err)
+	}
+	htmlPath := filepath.Join(productsDir, filename)
+	if err := service.WriteFileAtomic(htmlPath, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write product file: %w", err)
+	}
+	h.recordProduct(htmlPath)
+	return htmlPath, nil
+}
 
+// ReconcileProducts syncs the products index with the products folder, dropping files past
+// the configured retention. Run once at startup.
+func (h *Handlers) ReconcileProducts() error {
+	if err := os.MkdirAll(productsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create products directory: %w", err)
+	}
 	files, err := os.ReadDir(productsDir)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read products directory: %v", err)})
-		return
+		return fmt.Errorf("failed to read products directory: %w", err)
 	}
 
-	var productFiles []ProductFileInfo
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
+	var cutoff time.Time
+	if h.cfg.ProductsRetentionDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -h.cfg.ProductsRetentionDays)
+	}
 
+	onDisk := make(map[string]bool)
+	for _, file := range files {
 		// Only include HTML files
-		if filepath.Ext(file.Name()) != ".html" {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".html" {
 			continue
 		}
-
 		info, err := file.Info()
 		if err != nil {
 			continue
 		}
+		if !cutoff.IsZero() && info.ModTime().Before(cutoff) {
+			if err := os.Remove(filepath.Join(productsDir, file.Name())); err == nil {
+				log.Printf("[PRODUCTS] Removed expired product %s", file.Name())
+				continue
+			}
+		}
+		entry := productFileInfo(file.Name(), info)
+		if err := h.db.StoreProduct(&entry); err != nil {
+			return fmt.Errorf("failed to index product %s: %w", file.Name(), err)
+		}
+		onDisk[file.Name()] = true
+	}
 
-		// Determine file type based on filename
-		fileType := "result"
-		if len(file.Name()) >= 5 && file.Name()[:5] == "form_" {
-			fileType = "form"
+	indexed, err := h.db.ListProducts()
+	if err != nil {
+		return fmt.Errorf("failed to list product index: %w", err)
+	}
+	for _, p := range indexed {
+		if !onDisk[p.Filename] {
+			if err := h.db.DeleteProduct(p.Filename); err != nil {
+				return fmt.Errorf("failed to remove stale product %s: %w", p.Filename, err)
+			}
 		}
+	}
+	log.Printf("[PRODUCTS] Index reconciled: %d products", len(onDisk))
+	return nil
+}
+
+// ListProductsHandler lists all HTML files in the products folder
+// @Summary      List product files
+// @Description  Get a page of the HTML files in the products folder (newest first), served from the products index
+// @Tags         Products
+// @Produce      json
+// @Param        limit   query     int     false  "Maximum files to return (default: all)"
+// @Param        offset  query     int     false  "Files to skip"
+// @Success      200     {object}  models.Page[models.ProductFileInfo]  "Page of product files"
+// @Failure      400     {object}  map[string]string                    "Invalid query parameters"
+// @Failure      500     {object}  map[string]string                    "Failed to list files"
+// @Router       /api/products/files [get]
+func (h *Handlers) ListProductsHandler(c *gin.Context) {
+	limit, offset, ok := queryPage(c)
+	if !ok {
+		return
+	}
 
-		productFiles = append(productFiles, ProductFileInfo{
-			Filename: file.Name(),
-			Size:     info.Size(),
-			Modified: info.ModTime().Format(time.RFC3339),
-			Type:     fileType,
-		})
+	productFiles, err := h.db.ListProducts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list products: %v", err)})
+		return
 	}
 
-	// Sort by modified time, newest first
-	sort.Slice(productFiles, func(i, j int) bool {
-		timeI, _ := time.Parse(time.RFC3339, productFiles[i].Modified)
-		timeJ, _ := time.Parse(time.RFC3339, productFiles[j].Modified)
-		return timeI.After(timeJ)
+	// Sort by modified time, newest first; stable so pages keep index order for equal times
+	sort.SliceStable(productFiles, func(i, j int) bool {
+		return models.TimestampAfter(productFiles[i].Modified, productFiles[j].Modified)
 	})
 
-	c.JSON(http.StatusOK, gin.H{"files": productFiles})
+	c.JSON(http.StatusOK, models.NewPage(productFiles, limit, offset))
+}
+
+// DeleteProductHandler deletes an HTML file from the products folder
+// @Summary      Delete product file
+// @Description  Delete a product HTML file and its index entry
+// @Tags         Products
+// @Produce      json
+// @Param        filename  path      string  true  "Product file name"
+// @Success      200       {object}  map[string]string  "Product deleted"
+// @Failure      400       {object}  map[string]string  "Invalid filename"
+// @Failure      404       {object}  map[string]string  "File not found"
+// @Failure      500       {object}  map[string]string  "Failed to delete file"
+// @Router       /api/products/files/{filename} [delete]
+func (h *Handlers) DeleteProductHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" || filepath.Base(filename) != filename || filepath.Ext(filename) != ".html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filename"})
+		return
+	}
+
+	if err := os.Remove(filepath.Join(productsDir, filename)); err != nil {
+		if os.IsNotExist(err) {
+			// Keep the index consistent even if the file was removed out of band
+			_ = h.db.DeleteProduct(filename)
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete file: %v", err)})
+		return
+	}
+
+	if err := h.db.DeleteProduct(filename); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update products index: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
+}
+
+// productContentTypes are the content types of static artifact extensions products may be served as
+var productContentTypes = map[string]string{
+	".html":  "text/html; charset=utf-8",
+	".css":   "text/css; charset=utf-8",
+	".js":    "text/javascript; charset=utf-8",
+	".json":  "application/json",
+	".txt":   "text/plain; charset=utf-8",
+	".csv":   "text/csv; charset=utf-8",
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".jpeg":  "image/jpeg",
+	".gif":   "image/gif",
+	".webp":  "image/webp",
+	".svg":   "image/svg+xml",
+	".ico":   "image/x-icon",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+}
+
+// productContentType returns the content type to serve filename with, or false when its
+// extension is not in ProductExtensions
+func (h *Handlers) productContentType(filename string) (string, bool) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		return "", false
+	}
+	for _, allowed := range h.cfg.ProductExtensions {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if !strings.HasPrefix(allowed, ".") {
+			allowed = "." + allowed
+		}
+		if allowed != ext {
+			continue
+		}
+		if contentType, ok := productContentTypes[ext]; ok {
+			return contentType, true
+		}
+		if contentType := mime.TypeByExtension(ext); contentType != "" {
+			return contentType, true
+		}
+		return "application/octet-stream", true
+	}
+	return "", false
 }
 
-// ServeProductHandler serves a specific HTML file from the products folder
+// ServeProductHandler serves a file from the products folder
 // @Summary      Serve product file
-// @Description  Serve a specific HTML file from the products folder
+// @Description  Serve a file from the products folder. Only extensions listed in PRODUCT_EXTENSIONS are served (default: .html).
 // @Tags         Products
 // @Produce      text/html
 // @Param        filename  path      string  true  "Product file name"
-// @Success      200       {string}  string  "HTML content"
+// @Success      200       {string}  string  "File content"
+// @Failure      400       {object}  map[string]string  "Invalid filename or file type not allowed"
 // @Failure      404       {object}  map[string]string  "File not found"
 // @Router       /products/{filename} [get]
 func (h *Handlers) ServeProductHandler(c *gin.Context) {
@@ -104,13 +252,13 @@ func (h *Handlers) ServeProductHandler(c *gin.Context) {
 		return
 	}
 
-	// Ensure it's an HTML file
-	if filepath.Ext(filename) != ".html" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only HTML files are allowed"})
+	contentType, ok := h.productContentType(filename)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File type %q is not allowed", filepath.Ext(filename))})
 		return
 	}
 
-	filePath := filepath.Join("products", filename)
+	filePath := filepath.Join(productsDir, filename)
 	
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
@@ -118,6 +266,8 @@ func (h *Handlers) ServeProductHandler(c *gin.Context) {
 		return
 	}
 
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
 	c.File(filePath)
 }
 
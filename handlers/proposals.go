@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"idongivaflyinfa/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// proposeForm makes t the user's pending form and records it in the proposal history.
+// A previous unconfirmed proposal is marked discarded.
+func (h *Handlers) proposeForm(userID string, t *models.FormTemplate) {
+	if previous := getPendingProposalID(userID); previous != "" {
+		h.setProposalStatus(userID, previous, "discarded", "")
+	}
+	now := models.NowTimestamp()
+	proposal := &models.FormProposal{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    "proposed",
+		Form:      *t,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if !h.cfg.DemoMode {
+		if err := h.db.StoreFormProposal(proposal); err != nil {
+			log.Printf("[PROPOSALS] Error storing proposal for user %s: %v", userID, err)
+		}
+	}
+	setPendingForm(userID, t)
+	setPendingProposalID(userID, proposal.ID)
+}
+
+// updateProposedForm replaces the pending form (e.g. after a refinement) and updates its proposal record.
+func (h *Handlers) updateProposedForm(userID string, t *models.FormTemplate) {
+	proposalID := getPendingProposalID(userID)
+	if proposalID == "" {
+		h.proposeForm(userID, t)
+		return
+	}
+	setPendingForm(userID, t)
+	proposal, err := h.db.GetFormProposal(userID, proposalID)
+	if err != nil {
+		log.Printf("[PROPOSALS] Proposal %s not found for user %s: %v", proposalID, userID, err)
+		return
+	}
+	proposal.Form = *t
+	proposal.UpdatedAt = models.NowTimestamp()
+	if err := h.db.StoreFormProposal(proposal); err != nil {
+		log.Printf("[PROPOSALS] Error updating proposal %s: %v", proposalID, err)
+	}
+}
+
+// discardPendingForm drops the user's unconfirmed form proposal, if any, and marks it discarded.
+func (h *Handlers) discardPendingForm(userID string) {
+	if getPendingForm(userID) == nil {
+		return
+	}
+	proposalID := getPendingProposalID(userID)
+	clearPendingForm(userID)
+	log.Printf("[PROPOSALS] Discarding unconfirmed form proposal %s for user %s", proposalID, userID)
+	if !h.cfg.DemoMode {
+		h.setProposalStatus(userID, proposalID, "discarded", "")
+	}
+}
+
+// setProposalStatus marks a proposal saved or discarded.
+func (h *Handlers) setProposalStatus(userID, proposalID, status, savedFormID string) {
+	if proposalID == "" {
+		return
+	}
+	proposal, err := h.db.GetFormProposal(userID, proposalID)
+	if err != nil {
+		log.Printf("[PROPOSALS] Proposal %s not found for user %s: %v", proposalID, userID, err)
+		return
+	}
+	proposal.Status = status
+	proposal.SavedFormID = savedFormID
+	proposal.UpdatedAt = models.NowTimestamp()
+	if err := h.db.StoreFormProposal(proposal); err != nil {
+		log.Printf("[PROPOSALS] Error updating proposal %s: %v", proposalID, err)
+	}
+}
+
+// ListFormProposalsHandler returns the current user's form proposals (newest first).
+// @Summary      List form proposals
+// @Description  List forms proposed in chat, including ones that were never confirmed. Filter with ?status=proposed|saved|discarded
+// @Tags         Forms
+// @Produce      json
+// @Header       200     {string}  X-User-ID  "User ID"
+// @Param        status  query     string  false  "Filter by status"
+// @Success      200     {object}  map[string][]models.FormProposal
+// @Failure      500     {object}  map[string]string
+// @Router       /api/forms/proposals [get]
+func (h *Handlers) ListFormProposalsHandler(c *gin.Context) {
+	userID, ok := h.requireUserID(c)
+	if !ok {
+		return
+	}
+	proposals, err := h.db.ListFormProposals(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := c.Query("status")
+	filtered := make([]models.FormProposal, 0, len(proposals))
+	for _, p := range proposals {
+		if status == "" || p.Status == status {
+			filtered = append(filtered, p)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"proposals": filtered})
+}
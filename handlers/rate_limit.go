@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a sliding-window counter keyed by an arbitrary string (e.g. form ID + client IP).
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+	// lastSweep is when expired keys were last dropped; sweeps run at most once per window
+	lastSweep time.Time
+}
+
+// newRateLimiter returns a limiter allowing limit events per window per key. A limit <= 0 disables limiting.
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit.
+func (l *rateLimiter) Allow(key string) bool {
+	if l == nil || l.limit <= 0 || l.window <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+	recent := l.hits[key][:0]
+	for _, t := range l.hits[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= l.limit {
+		l.hits[key] = recent
+		return false
+	}
+	l.hits[key] = append(recent, now)
+
+	// Drop keys whose window has fully expired so the map does not grow without bound. Sweeping
+	// once per window keeps Allow O(1) amortized while bounding the map to keys seen in ~2 windows.
+	if now.Sub(l.lastSweep) >= l.window {
+		for k, times := range l.hits {
+			if len(times) == 0 || !times[len(times)-1].After(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 50*time.Millisecond)
+	if !l.Allow("a") || !l.Allow("a") {
+		t.Fatal("first two events for a were refused")
+	}
+	if l.Allow("a") {
+		t.Fatal("third event for a within the window was allowed")
+	}
+	if !l.Allow("b") {
+		t.Fatal("event for b was refused by a's limit")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !l.Allow("a") {
+		t.Fatal("event for a after the window was refused")
+	}
+	l.mu.Lock()
+	_, hasB := l.hits["b"]
+	l.mu.Unlock()
+	if hasB {
+		t.Error("expired key b was not swept")
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	var nilLimiter *rateLimiter
+	for _, l := range []*rateLimiter{nilLimiter, newRateLimiter(0, time.Minute), newRateLimiter(1, 0)} {
+		for i := 0; i < 3; i++ {
+			if !l.Allow("a") {
+				t.Fatalf("disabled limiter %+v refused an event", l)
+			}
+		}
+	}
+}
@@ -1,12 +1,11 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
-	"time"
 
 	"idongivaflyinfa/models"
 
@@ -28,16 +27,32 @@ var registerStudentPhrases = []string{
 	"i wanna register",
 }
 
+// storeRegistrationState trims the conversation history to the most recent turns and persists the state.
+func (h *Handlers) storeRegistrationState(userID string, state *models.RegistrationState) error {
+	state.ConversationHistory = trimRegistrationHistory(state.ConversationHistory, h.cfg.RegistrationHistoryMaxTurns)
+	return h.db.StoreRegistrationState(userID, state)
+}
+
+// trimRegistrationHistory keeps the last maxTurns turns of history, a turn being a user message and
+// the assistant's reply. The kept history always starts with a user message. maxTurns <= 0 keeps all.
+func trimRegistrationHistory(history []models.RegConvTurn, maxTurns int) []models.RegConvTurn {
+	if maxTurns <= 0 || len(history) <= 2*maxTurns {
+		return history
+	}
+	start := len(history) - 2*maxTurns
+	for start < len(history) && history[start].Role != "user" {
+		start++
+	}
+	return append([]models.RegConvTurn(nil), history[start:]...)
+}
+
 func isRegisterStudentRequest(message string) bool {
 	s := strings.TrimSpace(message)
 	if s == "" {
 		return false
 	}
 	lower := strings.ToLower(s)
-	start := lower
-	if len(start) > 80 {
-		start = start[:80]
-	}
+	start, _ := truncateRunes(lower, 80)
 	for _, phrase := range registerStudentPhrases {
 		if strings.HasPrefix(lower, phrase) || strings.Contains(start, phrase) {
 			return true
@@ -48,22 +63,18 @@ func isRegisterStudentRequest(message string) bool {
 
 // parseGatheringResponse tries to extract {"complete":true,"answers":{...}} or {"complete":false,"ask":"..."} from model output.
 func parseGatheringResponse(raw string) (complete bool, answers map[string]interface{}, ask string) {
-	s := strings.TrimSpace(raw)
-	s = strings.TrimPrefix(s, "```json")
-	s = strings.TrimPrefix(s, "```")
-	s = strings.TrimSuffix(s, "```")
-	s = strings.TrimSpace(s)
-	// Find first { ... } block
-	start := strings.Index(s, "{")
-	if start < 0 {
-		return false, nil, ""
-	}
-	end := strings.LastIndex(s, "}")
-	if end < start {
-		return false, nil, ""
-	}
+	// The reply may wrap the object in prose or code fences, or contain other objects;
+	// use the first one that looks like a gathering reply
 	var m map[string]interface{}
-	if err := json.Unmarshal([]byte(s[start:end+1]), &m); err != nil {
+	for _, obj := range jsonObjects(raw) {
+		_, hasComplete := obj["complete"]
+		_, hasAsk := obj["ask"]
+		if hasComplete || hasAsk {
+			m = obj
+			break
+		}
+	}
+	if m == nil {
 		return false, nil, ""
 	}
 	if v, ok := m["complete"].(bool); ok && v {
@@ -77,6 +88,62 @@ func parseGatheringResponse(raw string) (complete bool, answers map[string]inter
 	return false, nil, ""
 }
 
+// requireAnswers turns a "complete" gathering reply that lacks answers for required fields into a
+// question asking for them, since the model sometimes declares a form done too early
+func requireAnswers(fields []models.FormField, complete bool, answers map[string]interface{}, ask string) (bool, map[string]interface{}, string) {
+	if !complete {
+		return complete, answers, ask
+	}
+	missing := missingRequiredFields(fields, answers)
+	if len(missing) == 0 {
+		return complete, answers, ask
+	}
+	log.Printf("[REG] Model reported the form complete but required fields are missing: %s", strings.Join(missing, ", "))
+	return false, answers, fmt.Sprintf("I still need a few details before we can continue: %s.", strings.Join(missing, ", "))
+}
+
+// missingRequiredFields returns the labels of required fields without a non-blank answer
+func missingRequiredFields(fields []models.FormField, answers map[string]interface{}) []string {
+	var missing []string
+	for _, f := range fields {
+		if !f.Required {
+			continue
+		}
+		v, ok := answers[f.Name]
+		if s, isString := v.(string); !ok || v == nil || isString && strings.TrimSpace(s) == "" {
+			label := f.Label
+			if label == "" {
+				label = f.Name
+			}
+			missing = append(missing, label)
+		}
+	}
+	return missing
+}
+
+// jsonObjects returns the top-level JSON objects found in s, in order, skipping any text around
+// them. Each '{' is tried as the start of an object; braces inside strings and nested objects
+// are handled by the decoder, and a nested object is never returned on its own.
+func jsonObjects(s string) []map[string]interface{} {
+	var objects []map[string]interface{}
+	for i := 0; i < len(s); {
+		start := strings.IndexByte(s[i:], '{')
+		if start < 0 {
+			break
+		}
+		start += i
+		dec := json.NewDecoder(strings.NewReader(s[start:]))
+		var obj map[string]interface{}
+		if err := dec.Decode(&obj); err != nil {
+			i = start + 1
+			continue
+		}
+		objects = append(objects, obj)
+		i = start + int(dec.InputOffset())
+	}
+	return objects
+}
+
 // extractFormName returns the form name the model chose, or "" if NONE / unclear.
 func extractFormName(modelReply string, formNames []string) string {
 	s := strings.TrimSpace(strings.ToLower(modelReply))
@@ -111,6 +178,23 @@ func isConfirmationMessage(message string) bool {
 	return false
 }
 
+// identityAnswer returns the answer to the form's identity field, or "" when the form declares none
+// or it was not answered
+func identityAnswer(form *models.FormTemplate, answers map[string]interface{}) string {
+	if form.IdentityField == "" {
+		return ""
+	}
+	switch v := answers[form.IdentityField].(type) {
+	case nil:
+		return ""
+	case float64:
+		// JSON numbers; avoid exponent notation for long numeric IDs
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+}
+
 func (h *Handlers) buildConfirmationCard(formName, userType string, answers map[string]interface{}, fields []models.FormField) *models.RegistrationConfirmationCard {
 	if answers == nil {
 		answers = make(map[string]interface{})
@@ -124,27 +208,27 @@ func (h *Handlers) buildConfirmationCard(formName, userType string, answers map[
 }
 
 func (h *Handlers) handleRegistrationFlow(c *gin.Context, userID, userMessage string) (*models.ChatResponse, error) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	state, _ := h.db.GetRegistrationStateByUserID(userID)
 
 	// If we are pending confirmation: user must confirm or request changes
 	if state != nil && state.Step == "pending_confirmation" && state.FormID != "" {
 		if isConfirmationMessage(userMessage) {
-			submitterID := c.GetHeader("X-User-ID")
-			if submitterID == "" {
-				submitterID = "admin"
-			}
-			userIDForAnswer := ""
-			for _, k := range []string{"user_id", "student_id", "staff_number", "id", "name"} {
-				if v, ok := state.GatheredAnswers[k]; ok {
-					if s, ok := v.(string); ok && s != "" {
-						userIDForAnswer = s
-						break
-					}
+			submitterID := userID
+			userIDForAnswer := submitterID
+			if form, err := h.db.GetFormTemplate(state.FormID); err == nil && form != nil {
+				// The template may have gained required fields since the answers were gathered
+				if missing := missingRequiredFields(form.Fields, state.GatheredAnswers); len(missing) > 0 {
+					ask := fmt.Sprintf("I still need a few details before I can submit: %s.", strings.Join(missing, ", "))
+					state.Step = "gathering_fields"
+					state.ConversationHistory = append(state.ConversationHistory, models.RegConvTurn{Role: "user", Content: userMessage}, models.RegConvTurn{Role: "assistant", Content: ask})
+					state.LastAIResponse = ask
+					_ = h.storeRegistrationState(userID, state)
+					return &models.ChatResponse{Response: ask}, nil
+				}
+				if id := identityAnswer(form, state.GatheredAnswers); id != "" {
+					userIDForAnswer = id
 				}
-			}
-			if userIDForAnswer == "" {
-				userIDForAnswer = submitterID
 			}
 			fa := &models.FormAnswer{
 				ID:          uuid.New().String(),
@@ -153,9 +237,13 @@ func (h *Handlers) handleRegistrationFlow(c *gin.Context, userID, userMessage st
 				UserID:      userIDForAnswer,
 				UserType:    state.UserType,
 				Answers:     state.GatheredAnswers,
-				SubmittedAt: time.Now().Format(time.RFC3339),
+				SubmittedAt: models.NowTimestamp(),
 				SubmittedBy: submitterID,
 			}
+			if h.cfg.DemoMode {
+				h.db.DeleteRegistrationState(userID)
+				return &models.ChatResponse{Response: demoModeMessage}, nil
+			}
 			if err := h.db.StoreFormAnswer(fa); err != nil {
 				log.Printf("[REG] Store form answer error: %v", err)
 				return nil, fmt.Errorf("failed to save registration: %w", err)
@@ -177,10 +265,11 @@ func (h *Handlers) handleRegistrationFlow(c *gin.Context, userID, userMessage st
 			return nil, fmt.Errorf("registration AI error: %w", err)
 		}
 		complete, answers, ask := parseGatheringResponse(reply)
+		complete, answers, ask = requireAnswers(form.Fields, complete, answers, ask)
 		if complete && len(answers) > 0 {
 			state.Step = "pending_confirmation"
 			state.GatheredAnswers = answers
-			_ = h.db.StoreRegistrationState(userID, state)
+			_ = h.storeRegistrationState(userID, state)
 			return &models.ChatResponse{
 				Response:          "I've updated the details. Please review the card below and reply **Confirm** to submit, or tell me what you'd like to change.",
 				ConfirmationCard:  h.buildConfirmationCard(state.FormName, state.UserType, answers, form.Fields),
@@ -209,10 +298,11 @@ func (h *Handlers) handleRegistrationFlow(c *gin.Context, userID, userMessage st
 		}
 
 		complete, answers, ask := parseGatheringResponse(reply)
+		complete, answers, ask = requireAnswers(form.Fields, complete, answers, ask)
 		if complete && len(answers) > 0 {
 			state.Step = "pending_confirmation"
 			state.GatheredAnswers = answers
-			_ = h.db.StoreRegistrationState(userID, state)
+			_ = h.storeRegistrationState(userID, state)
 			return &models.ChatResponse{
 				Response:          "Please review the details below. Reply **Confirm** to submit, or tell me what you'd like to change.",
 				ConfirmationCard:  h.buildConfirmationCard(state.FormName, state.UserType, answers, form.Fields),
@@ -227,7 +317,7 @@ func (h *Handlers) handleRegistrationFlow(c *gin.Context, userID, userMessage st
 				h.db.DeleteRegistrationState(userID)
 				return &models.ChatResponse{Response: "We've hit the limit for this session. Please start again by saying you want to register a student."}, nil
 			}
-			_ = h.db.StoreRegistrationState(userID, state)
+			_ = h.storeRegistrationState(userID, state)
 			return &models.ChatResponse{Response: ask}, nil
 		}
 
@@ -236,7 +326,7 @@ func (h *Handlers) handleRegistrationFlow(c *gin.Context, userID, userMessage st
 		state.ConversationHistory = append(state.ConversationHistory, models.RegConvTurn{Role: "user", Content: userMessage}, models.RegConvTurn{Role: "assistant", Content: fallback})
 		state.LastAIResponse = fallback
 		state.ExchangeCount++
-		_ = h.db.StoreRegistrationState(userID, state)
+		_ = h.storeRegistrationState(userID, state)
 		return &models.ChatResponse{Response: fallback}, nil
 	}
 
@@ -309,9 +399,9 @@ func (h *Handlers) handleRegistrationFlow(c *gin.Context, userID, userMessage st
 		GatheredAnswers:     make(map[string]interface{}),
 		ConversationHistory: nil,
 		ExchangeCount:       0,
-		CreatedAt:           time.Now().Format(time.RFC3339),
+		CreatedAt:           models.NowTimestamp(),
 	}
-	if err := h.db.StoreRegistrationState(userID, state); err != nil {
+	if err := h.storeRegistrationState(userID, state); err != nil {
 		return nil, fmt.Errorf("failed to store registration state: %w", err)
 	}
 
@@ -323,10 +413,11 @@ func (h *Handlers) handleRegistrationFlow(c *gin.Context, userID, userMessage st
 	}
 
 	complete, answers, ask := parseGatheringResponse(reply)
+	complete, answers, ask = requireAnswers(selected.Fields, complete, answers, ask)
 	if complete && len(answers) > 0 {
 		state.Step = "pending_confirmation"
 		state.GatheredAnswers = answers
-		_ = h.db.StoreRegistrationState(userID, state)
+		_ = h.storeRegistrationState(userID, state)
 		return &models.ChatResponse{
 			Response:          "Please review the details below. Reply **Confirm** to submit, or tell me what you'd like to change.",
 			ConfirmationCard:  h.buildConfirmationCard(selected.Name, selected.UserType, answers, selected.Fields),
@@ -340,7 +431,7 @@ func (h *Handlers) handleRegistrationFlow(c *gin.Context, userID, userMessage st
 		}
 		state.LastAIResponse = ask
 		state.ExchangeCount = 1
-		_ = h.db.StoreRegistrationState(userID, state)
+		_ = h.storeRegistrationState(userID, state)
 		return &models.ChatResponse{Response: ask}, nil
 	}
 
@@ -351,6 +442,6 @@ func (h *Handlers) handleRegistrationFlow(c *gin.Context, userID, userMessage st
 	}
 	state.LastAIResponse = fallback
 	state.ExchangeCount = 1
-	_ = h.db.StoreRegistrationState(userID, state)
+	_ = h.storeRegistrationState(userID, state)
 	return &models.ChatResponse{Response: fallback}, nil
 }
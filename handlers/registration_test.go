@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"idongivaflyinfa/models"
+)
+
+func TestParseGatheringResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		raw          string
+		wantComplete bool
+		wantAnswers  map[string]interface{}
+		wantAsk      string
+	}{
+		{
+			name:         "bare object",
+			raw:          `{"complete": true, "answers": {"name": "Jane"}}`,
+			wantComplete: true,
+			wantAnswers:  map[string]interface{}{"name": "Jane"},
+		},
+		{
+			name:    "prose with braces before the object",
+			raw:     "I filled in {name} and {grade} from your reply {see below}.\n{\"complete\": false, \"ask\": \"Which grade are you in?\"}",
+			wantAsk: "Which grade are you in?",
+		},
+		{
+			name:         "json code fence",
+			raw:          "Here you go:\n```json\n{\"complete\": true, \"answers\": {\"name\": \"Jane\", \"grade\": 10}}\n```\nThanks!",
+			wantComplete: true,
+			wantAnswers:  map[string]interface{}{"name": "Jane", "grade": float64(10)},
+		},
+		{
+			name:         "only the second object has complete",
+			raw:          `Collected so far: {"name": "Jane"} Result: {"complete": true, "answers": {"name": "Jane", "email": "jane@example.com"}}`,
+			wantComplete: true,
+			wantAnswers:  map[string]interface{}{"name": "Jane", "email": "jane@example.com"},
+		},
+		{
+			name:    "braces inside strings",
+			raw:     `{"complete": false, "ask": "Please enter your ID like {12345}"}`,
+			wantAsk: "Please enter your ID like {12345}",
+		},
+		{
+			name: "complete without answers",
+			raw:  `{"complete": true}`,
+		},
+		{
+			name: "truncated object",
+			raw:  `{"complete": true, "answers": {"name": "Ja`,
+		},
+		{
+			name: "no json",
+			raw:  "Sorry, I could not understand that. Could you repeat it?",
+		},
+		{
+			name: "empty",
+			raw:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			complete, answers, ask := parseGatheringResponse(tt.raw)
+			if complete != tt.wantComplete || !reflect.DeepEqual(answers, tt.wantAnswers) || ask != tt.wantAsk {
+				t.Errorf("parseGatheringResponse(%q) = %v, %v, %q; want %v, %v, %q",
+					tt.raw, complete, answers, ask, tt.wantComplete, tt.wantAnswers, tt.wantAsk)
+			}
+		})
+	}
+}
+
+func TestTrimRegistrationHistory(t *testing.T) {
+	user := func(s string) models.RegConvTurn { return models.RegConvTurn{Role: "user", Content: s} }
+	assistant := func(s string) models.RegConvTurn { return models.RegConvTurn{Role: "assistant", Content: s} }
+	history := []models.RegConvTurn{user("u1"), assistant("a1"), user("u2"), assistant("a2"), user("u3"), assistant("a3")}
+
+	tests := []struct {
+		name     string
+		history  []models.RegConvTurn
+		maxTurns int
+		want     []models.RegConvTurn
+	}{
+		{"no limit", history, 0, history},
+		{"under the limit", history, 3, history},
+		{"last two turns", history, 2, history[2:]},
+		{"last turn", history, 1, history[4:]},
+		{"starts at a user message", append([]models.RegConvTurn{assistant("greeting")}, history[:4]...), 2, history[:4]},
+		{"skips a leading reply", []models.RegConvTurn{user("u1"), assistant("a1"), assistant("a1b"), user("u2"), assistant("a2")}, 2, []models.RegConvTurn{user("u2"), assistant("a2")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimRegistrationHistory(tt.history, tt.maxTurns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trimRegistrationHistory(%d turns, %d) = %v, want %v", len(tt.history), tt.maxTurns, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"idongivaflyinfa/config"
+	"idongivaflyinfa/models"
+	"idongivaflyinfa/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// builtinReportHeads are used when the report heads directory has no file of that name
+var builtinReportHeads = map[string]string{
+	"student": config.StudentReportSqlHead,
+}
+
+// reportHead returns the resolved name and SQL of the report head called name ("" means
+// DefaultReportHead). <ReportHeadsDir>/<name>.sql is read on every call, so edits apply without
+// a restart; without that file the built-in head of that name is used.
+func (h *Handlers) reportHead(name string) (string, string, error) {
+	if name == "" {
+		name = h.cfg.DefaultReportHead
+	}
+	if !validation.IsValidID(name) {
+		return "", "", fmt.Errorf("invalid report head name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(h.cfg.ReportHeadsDir, name+".sql"))
+	if err == nil {
+		return name, string(data), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read report head %q: %w", name, err)
+	}
+	if head, ok := builtinReportHeads[name]; ok {
+		return name, head, nil
+	}
+	return "", "", fmt.Errorf("unknown report head %q", name)
+}
+
+// ListReportHeadsHandler lists the report heads chat reports can use
+// @Summary      List report heads
+// @Description  List the SQL heads (CTE blocks) that can be put in front of generated report SQL, selected per chat request with report_head. Heads are <name>.sql files in REPORT_HEADS_DIR plus the built-in "student" head; a file overrides the built-in head of the same name.
+// @Tags         SQL
+// @Produce      json
+// @Success      200  {object}  models.ReportHeadsResponse
+// @Failure      500  {object}  map[string]string
+// @Router       /api/report-heads [get]
+func (h *Handlers) ListReportHeadsHandler(c *gin.Context) {
+	sources := make(map[string]string)
+	for name := range builtinReportHeads {
+		sources[name] = "builtin"
+	}
+	entries, err := os.ReadDir(h.cfg.ReportHeadsDir)
+	if err != nil && !os.IsNotExist(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read report heads: %v", err)})
+		return
+	}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".sql")
+		if e.IsDir() || filepath.Ext(e.Name()) != ".sql" || !validation.IsValidID(name) {
+			continue
+		}
+		sources[name] = "file"
+	}
+
+	heads := make([]models.ReportHeadInfo, 0, len(sources))
+	for name, source := range sources {
+		heads = append(heads, models.ReportHeadInfo{Name: name, Source: source})
+	}
+	sort.Slice(heads, func(i, j int) bool { return heads[i].Name < heads[j].Name })
+	c.JSON(http.StatusOK, models.ReportHeadsResponse{Default: h.cfg.DefaultReportHead, Heads: heads})
+}
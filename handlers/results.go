@@ -1,20 +1,34 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"idongivaflyinfa/models"
+	"idongivaflyinfa/service"
+	"idongivaflyinfa/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ListResultFilesHandler lists all result files
 // @Summary      List result files
-// @Description  Get a list of all saved SQL query result files (JSON/CSV)
+// @Description  Get a page of the saved SQL query result files (JSON/CSV) with their tags, optionally only those with a tag
 // @Tags         Results
 // @Produce      json
-// @Success      200  {object}  map[string][]models.ResultFileInfo  "List of result files"
-// @Failure      503  {object}  map[string]string                   "SQL Server not configured"
-// @Failure      500  {object}  map[string]string                  "Failed to list files"
+// @Param        tag     query     string  false  "Only files with this tag"
+// @Param        limit   query     int     false  "Maximum files to return (default: all)"
+// @Param        offset  query     int     false  "Files to skip"
+// @Success      200     {object}  models.Page[models.ResultFileInfo]  "Page of result files"
+// @Failure      400     {object}  map[string]string                   "Invalid query parameters"
+// @Failure      503     {object}  map[string]string                   "SQL Server not configured"
+// @Failure      500     {object}  map[string]string                   "Failed to list files"
 // @Router       /api/results/files [get]
 func (h *Handlers) ListResultFilesHandler(c *gin.Context) {
 	if h.sqlService == nil {
@@ -28,23 +42,97 @@ func (h *Handlers) ListResultFilesHandler(c *gin.Context) {
 		return
 	}
 
+	limit, offset, ok := queryPage(c)
+	if !ok {
+		return
+	}
+
 	files, err := resultsStorage.ListResultFiles()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list files: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"files": files})
+	tags, err := h.db.GetAllResultTags()
+	if err != nil {
+		log.Printf("[RESULTS] Error loading result tags: %v", err)
+	}
+	tag := c.Query("tag")
+	filtered := make([]models.ResultFileInfo, 0, len(files))
+	for _, f := range files {
+		f.Tags = tags[f.Filename]
+		if tag != "" && !validation.HasTag(f.Tags, tag) {
+			continue
+		}
+		filtered = append(filtered, f)
+	}
+
+	c.JSON(http.StatusOK, models.NewPage(filtered, limit, offset))
+}
+
+// SetResultTagsHandler replaces the tags of a result file
+// @Summary      Set result file tags
+// @Description  Replace the tags of a saved result file. Tags are lower-cased and de-duplicated; an empty list clears them.
+// @Tags         Results
+// @Accept       json
+// @Produce      json
+// @Param        filename  path      string              true  "Result file name"
+// @Param        request   body      models.TagsRequest  true  "Tags"
+// @Success      200       {object}  map[string]interface{}  "Filename and its tags"
+// @Failure      400       {object}  map[string]string   "Invalid request"
+// @Failure      404       {object}  map[string]string   "File not found"
+// @Failure      503       {object}  map[string]string   "SQL Server not configured"
+// @Failure      500       {object}  map[string]string   "Failed to store tags"
+// @Router       /api/results/file/{filename}/tags [put]
+func (h *Handlers) SetResultTagsHandler(c *gin.Context) {
+	filename := c.Param("filename")
+
+	var req models.TagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+		return
+	}
+	tags, err := validation.NormalizeTags(req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.sqlService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SQL Server service is not configured"})
+		return
+	}
+
+	resultsStorage := h.sqlService.GetResultsStorage()
+	if resultsStorage == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Results storage is not initialized"})
+		return
+	}
+
+	if _, err := resultsStorage.GetResultFile(filename); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("File not found: %v", err)})
+		return
+	}
+
+	if err := h.db.StoreResultTags(filename, tags); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to store tags: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filename": filename, "tags": tags})
 }
 
 // GetResultFileHandler retrieves a specific result file
 // @Summary      Get result file
-// @Description  Get the complete content of a specific result file by filename
+// @Description  Get the complete content of a specific result file by filename. Rows are arrays aligned
+// @Description  with columns; shape=objects returns each row as a {column: value} object instead
+// @Description  (repeated column names get _2, _3, ... suffixes).
 // @Tags         Results
 // @Produce      json
-// @Param        filename  path      string  true  "Result file name"
-// @Success      200       {object}  models.ResultFile  "Result file content"
-// @Failure      400       {object}  map[string]string   "Filename required"
+// @Param        filename  path      string  true   "Result file name"
+// @Param        shape     query     string  false  "Row shape: arrays (default) or objects"
+// @Success      200       {object}  models.ResultFile  "Result file content (models.ResultFileObjects for shape=objects)"
+// @Failure      400       {object}  map[string]string   "Filename required or invalid shape"
 // @Failure      404       {object}  map[string]string   "File not found"
 // @Failure      503       {object}  map[string]string    "SQL Server not configured"
 // @Router       /api/results/file/{filename} [get]
@@ -54,6 +142,11 @@ func (h *Handlers) GetResultFileHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Filename is required"})
 		return
 	}
+	shape := c.DefaultQuery("shape", "arrays")
+	if shape != "arrays" && shape != "objects" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "shape must be 'arrays' or 'objects'"})
+		return
+	}
 
 	if h.sqlService == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SQL Server service is not configured"})
@@ -72,6 +165,159 @@ func (h *Handlers) GetResultFileHandler(c *gin.Context) {
 		return
 	}
 
+	if shape == "objects" {
+		c.JSON(http.StatusOK, resultFileObjects(resultFile))
+		return
+	}
 	c.JSON(http.StatusOK, resultFile)
 }
 
+// htmlDataURL returns the endpoint a page for result file filename should load rows from, or ""
+// when the result is small enough to embed in the page (see HTMLEmbedMaxRows)
+func (h *Handlers) htmlDataURL(filename string, f *models.ResultFile) string {
+	if h.cfg.HTMLEmbedMaxRows <= 0 || len(f.Rows) <= h.cfg.HTMLEmbedMaxRows {
+		return ""
+	}
+	return "/api/results/file/" + url.PathEscape(filename) + "?shape=objects"
+}
+
+// resultFileObjects converts a result file's rows into objects keyed by (de-duplicated) column name
+func resultFileObjects(f *models.ResultFile) *models.ResultFileObjects {
+	columns := service.UniqueColumnNames(f.Columns)
+	rows := make([]map[string]interface{}, len(f.Rows))
+	for i, row := range f.Rows {
+		obj := make(map[string]interface{}, len(columns))
+		for j, col := range columns {
+			var v interface{}
+			if j < len(row) {
+				v = row[j]
+			}
+			obj[col] = v
+		}
+		rows[i] = obj
+	}
+	return &models.ResultFileObjects{
+		Filename:  f.Filename,
+		Query:     f.Query,
+		Timestamp: f.Timestamp,
+		Columns:   columns,
+		Rows:      rows,
+		RowCount:  f.RowCount,
+		Error:     f.Error,
+		Truncated: f.Truncated,
+	}
+}
+
+
+// DiffResultFilesHandler compares two result files row by row
+// @Summary      Diff result files
+// @Description  Compare two result files (e.g. last week's and this week's report), matching rows by a key column that is unique in both. Returns added, removed and changed rows; columns present in only one file are listed but not compared.
+// @Tags         Results
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.ResultDiffRequest  true  "Files to compare"
+// @Success      200      {object}  models.ResultDiff
+// @Failure      400      {object}  map[string]string  "Invalid request, or key column missing or not unique"
+// @Failure      404      {object}  map[string]string  "File not found"
+// @Failure      503      {object}  map[string]string  "SQL Server not configured"
+// @Router       /api/results/diff [post]
+func (h *Handlers) DiffResultFilesHandler(c *gin.Context) {
+	var req models.ResultDiffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+		return
+	}
+
+	if h.sqlService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SQL Server service is not configured"})
+		return
+	}
+
+	resultsStorage := h.sqlService.GetResultsStorage()
+	if resultsStorage == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Results storage is not initialized"})
+		return
+	}
+
+	diff, err := resultsStorage.DiffResults(req.Old, req.New, req.KeyColumn)
+	switch {
+	case errors.Is(err, service.ErrInvalidResultDiff):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, os.ErrNotExist):
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("File not found: %v", err)})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to diff results: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// maxBatchDelete caps the number of files removed by one batch delete request
+const maxBatchDelete = 100
+
+// DeleteResultFilesHandler deletes several result files at once
+// @Summary      Delete result files
+// @Description  Delete multiple result files (JSON/CSV) along with the HTML pages generated from them. Returns a status per file. Requires the admin token.
+// @Tags         Results
+// @Accept       json
+// @Produce      json
+// @Security     AdminToken
+// @Param        request  body      models.DeleteResultFilesRequest  true  "Filenames to delete"
+// @Success      200      {object}  map[string][]models.DeleteResultFileStatus  "Per-file results"
+// @Failure      400      {object}  map[string]string                           "Invalid request"
+// @Failure      401      {object}  map[string]string                           "Admin token required"
+// @Failure      503      {object}  map[string]string                           "SQL Server not configured"
+// @Router       /api/results/delete [post]
+func (h *Handlers) DeleteResultFilesHandler(c *gin.Context) {
+	var req models.DeleteResultFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+		return
+	}
+	if len(req.Filenames) == 0 || len(req.Filenames) > maxBatchDelete {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Provide between 1 and %d filenames", maxBatchDelete)})
+		return
+	}
+
+	if h.sqlService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SQL Server service is not configured"})
+		return
+	}
+
+	resultsStorage := h.sqlService.GetResultsStorage()
+	if resultsStorage == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Results storage is not initialized"})
+		return
+	}
+
+	results := make([]models.DeleteResultFileStatus, 0, len(req.Filenames))
+	for _, filename := range req.Filenames {
+		status := models.DeleteResultFileStatus{Filename: filename}
+		if err := resultsStorage.DeleteResultFile(filename); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				status.Error = "file not found"
+			} else {
+				status.Error = err.Error()
+			}
+			results = append(results, status)
+			continue
+		}
+		status.Deleted = true
+		if err := h.db.DeleteResultTags(filename); err != nil {
+			log.Printf("[RESULTS] Error deleting tags of %s: %v", filename, err)
+		}
+
+		// Drop the product page generated from this result in the background chat flow
+		product := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".html"
+		if err := os.Remove(filepath.Join(productsDir, product)); err == nil || os.IsNotExist(err) {
+			_ = h.db.DeleteProduct(product)
+		} else {
+			log.Printf("[RESULTS] Error deleting product %s: %v", product, err)
+		}
+		results = append(results, status)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
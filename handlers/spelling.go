@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var (
+	// snake_case, dbo.Students, [Student ID], @param, #temp
+	identifierPattern = regexp.MustCompile(`\w+_\w+|\b[A-Za-z]\w*\.[A-Za-z]\w+\b|\[[^\]]+\]|[@#]\w+`)
+	// studentId, StudentID, GradeLevel: a lower-case letter directly followed by an upper-case one
+	camelCasePattern = regexp.MustCompile(`\b[A-Za-z]*[a-z][A-Z]\w*\b`)
+	// SQL fragments such as "where grade = 5" or "select name from students"
+	sqlFragmentPattern = regexp.MustCompile(`(?i)\bselect\b.+\bfrom\b|\bwhere\b.+[=<>]|\bjoin\b.+\bon\b|[=<>]=?\s*'`)
+)
+
+// looksLikeCode reports whether a message contains code, SQL or identifiers (column and table
+// names) that spelling correction could "fix" into something that no longer matches the schema
+func looksLikeCode(message string) bool {
+	if strings.ContainsAny(message, "`;{}") {
+		return true
+	}
+	return identifierPattern.MatchString(message) ||
+		camelCasePattern.MatchString(message) ||
+		sqlFragmentPattern.MatchString(message)
+}
+
+// correctSpelling returns the message with spelling corrected by the AI service, or unchanged
+// when SPELL_CHECK is off or the message looks like it contains identifiers
+func (h *Handlers) correctSpelling(ctx context.Context, message string) (string, error) {
+	if !h.cfg.SpellCheck {
+		return message, nil
+	}
+	if looksLikeCode(message) {
+		log.Printf("[SPELLING] Message contains code or identifiers, skipping correction")
+		return message, nil
+	}
+	return h.aiService.CorrectSpelling(ctx, message)
+}
@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"idongivaflyinfa/db"
+	"idongivaflyinfa/models"
+	"idongivaflyinfa/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -42,8 +48,12 @@ func (h *Handlers) UploadSQLFileHandler(c *gin.Context) {
 		return
 	}
 
-	// Store in database
-	if err := h.db.StoreSQLFile(file.Filename, string(content)); err != nil {
+	// Store in database, converted to UTF-8 (SSMS exports are often UTF-16)
+	text, encoding := db.DecodeSQLText(content)
+	if encoding != "utf-8" {
+		log.Printf("[SQL] Converted uploaded SQL file %s from %s to UTF-8", file.Filename, encoding)
+	}
+	if err := h.db.StoreSQLFile(file.Filename, text); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store SQL file"})
 		return
 	}
@@ -83,21 +93,26 @@ func (h *Handlers) ListSQLFilesHandler(c *gin.Context) {
 
 // ExecuteSQLHandler executes a SQL query against SQL Server
 // @Summary      Execute SQL query
-// @Description  Execute a SQL query against the configured SQL Server and optionally save the results
+// @Description  Execute a SQL query against the configured SQL Server and optionally save the results.
+// @Description  With preview (SELECT queries only), only the first few rows are fetched for a quick check and no result file is created.
+// @Description  Identical SELECT-only queries within SQL_RESULT_CACHE_TTL_SECONDS are served from the earlier result file (cached: true) unless fresh is set.
 // @Tags         SQL Execution
 // @Accept       json
 // @Produce      json
-// @Param        request  body      object  true  "SQL execution request"  example({"sql": "SELECT * FROM users", "save": true, "format": "json"})
+// @Param        request  body      object  true  "SQL execution request"  example({"sql": "SELECT * FROM users", "save": true, "format": "json", "name": "Q1 attendance report", "fresh": false, "preview": false})
 // @Success      200      {object}  models.SQLResult  "Query execution result"
-// @Failure      400      {object}  map[string]string  "Invalid request"
-// @Failure      503      {object}  map[string]string  "SQL Server not configured"
+// @Failure      400      {object}  map[string]string  "Invalid request or statement type not allowed (SQL_ALLOWED_STATEMENTS)"
+// @Failure      503      {object}  map[string]string  "SQL Server not configured, or cannot log in / connect (the message says what to check)"
 // @Failure      500      {object}  map[string]string  "Query execution error"
 // @Router       /api/sql/execute [post]
 func (h *Handlers) ExecuteSQLHandler(c *gin.Context) {
 	var req struct {
-		SQL    string `json:"sql" example:"SELECT * FROM users"`
-		Save   bool   `json:"save" example:"true"`
-		Format string `json:"format" example:"json"` // "json" or "csv"
+		SQL     string `json:"sql" example:"SELECT * FROM users"`
+		Save    bool   `json:"save" example:"true"`
+		Format  string `json:"format" example:"json"`   // "json" or "csv"
+		Fresh   bool   `json:"fresh" example:"false"`   // Skip the result cache and query SQL Server
+		Preview bool   `json:"preview" example:"false"` // Return only the first SQL_PREVIEW_ROWS rows; nothing is saved or cached
+		Name    string `json:"name" example:"Q1 attendance report"` // Optional result filename (sanitized; _2, _3... added if taken)
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -105,6 +120,11 @@ func (h *Handlers) ExecuteSQLHandler(c *gin.Context) {
 		return
 	}
 
+	if strings.TrimSpace(req.SQL) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sql is required"})
+		return
+	}
+
 	if h.sqlService == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SQL Server service is not configured"})
 		return
@@ -118,7 +138,22 @@ func (h *Handlers) ExecuteSQLHandler(c *gin.Context) {
 		format = "json"
 	}
 
-	result, err := h.sqlService.ExecuteQueryWithSave(req.SQL, format, req.Save)
+	var result *models.SQLResult
+	var err error
+	if req.Preview {
+		result, err = h.sqlService.PreviewQuery(req.SQL, h.cfg.SQLServer.PreviewRows)
+	} else {
+		result, err = h.executeSQL(req.SQL, format, req.Name, req.Save, req.Fresh)
+	}
+	if errors.Is(err, service.ErrStatementNotAllowed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var connErr *service.SQLConnectionError
+	if errors.As(err, &connErr) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": connErr.Message})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
 		return
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+
+	"idongivaflyinfa/models"
+	"idongivaflyinfa/validation"
+)
+
+// sqlCacheKey hashes a query and output format; surrounding whitespace is ignored
+func sqlCacheKey(query, format string) string {
+	sum := sha256.Sum256([]byte(format + "\n" + strings.TrimSpace(query)))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheableSQL reports whether a query only reads (SELECT statements only), so an earlier
+// result can stand in for running it again. A write must always reach the server.
+func cacheableSQL(query string) bool {
+	verbs := validation.SQLStatementTypes(query)
+	return len(verbs) == 1 && verbs[0] == "SELECT"
+}
+
+// executeSQL runs a query through the SQL service, serving the result file of an identical
+// query run within SQLResultCacheTTL instead of hitting SQL Server again. fresh skips the lookup,
+// as does a name (the caller wants a new file under that name); the new result is still cached
+// when it was saved to a file. Only SELECT-only queries are cached.
+func (h *Handlers) executeSQL(query, format, name string, save, fresh bool) (*models.SQLResult, error) {
+	ttl := h.cfg.SQLResultCacheTTL
+	if !cacheableSQL(query) {
+		ttl = 0
+	}
+	key := sqlCacheKey(query, format)
+
+	if ttl > 0 && !fresh && !(save && name != "") {
+		if cached := h.cachedSQLResult(key); cached != nil {
+			return cached, nil
+		}
+	}
+
+	result, err := h.sqlService.ExecuteQueryWithSave(query, format, name, save)
+	if err != nil || result == nil || result.Error != "" || result.Filename == "" {
+		return result, err
+	}
+
+	if ttl > 0 && !h.cfg.DemoMode {
+		entry := &models.SQLResultCacheEntry{
+			Hash:     key,
+			Format:   format,
+			Filename: result.Filename,
+			CachedAt: models.NowTimestamp(),
+		}
+		if err := h.db.StoreSQLResultCache(entry, ttl); err != nil {
+			log.Printf("[SQL] Failed to cache result %s: %v", result.Filename, err)
+		}
+	}
+	return result, nil
+}
+
+// cachedSQLResult loads the result file recorded for key; nil when there is no entry or the file is gone
+func (h *Handlers) cachedSQLResult(key string) *models.SQLResult {
+	entry, err := h.db.GetSQLResultCache(key)
+	if err != nil || entry == nil {
+		return nil
+	}
+	storage := h.sqlService.GetResultsStorage()
+	if storage == nil {
+		return nil
+	}
+	file, err := storage.GetResultFile(entry.Filename)
+	if err != nil {
+		log.Printf("[SQL] Cached result %s is unavailable, re-running query: %v", entry.Filename, err)
+		return nil
+	}
+	log.Printf("[SQL] Serving cached result %s (cached %s)", entry.Filename, entry.CachedAt)
+	return &models.SQLResult{
+		Columns:   file.Columns,
+		Rows:      file.Rows,
+		Filename:  entry.Filename,
+		Truncated: file.Truncated,
+		Cached:    true,
+	}
+}
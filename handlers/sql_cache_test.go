@@ -0,0 +1,25 @@
+package handlers
+
+import "testing"
+
+func TestCacheableSQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"SELECT * FROM students", true},
+		{"WITH s AS (SELECT id FROM students) SELECT * FROM s", true},
+		{"SELECT 1; SELECT 2", true},
+		{"INSERT INTO log (msg) VALUES ('x')", false},
+		{"INSERT INTO log (msg) SELECT name FROM students", false},
+		{"UPDATE students SET grade = 11", false},
+		{"SELECT * INTO students_copy FROM students", false},
+		{"SELECT 1; EXEC sp_refresh", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := cacheableSQL(tt.query); got != tt.want {
+			t.Errorf("cacheableSQL(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
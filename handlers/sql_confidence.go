@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"idongivaflyinfa/models"
+	"idongivaflyinfa/validation"
+)
+
+// knownSQLTables collects the tables and CTEs used by the reference SQL files and the report head
+func knownSQLTables(head string, files []models.SQLFile) map[string]bool {
+	known := make(map[string]bool)
+	add := func(query string) {
+		tables, ctes := validation.SQLTableReferences(query)
+		for _, t := range tables {
+			known[t] = true
+		}
+		for _, t := range ctes {
+			known[t] = true
+		}
+	}
+	add(head)
+	for _, f := range files {
+		add(f.Content)
+	}
+	return known
+}
+
+// sqlConfidence rates generated SQL from 0 to 100 by the share of the tables it reads that the
+// reference SQL also uses (CTEs it defines itself count as known), and returns the unknown ones.
+// A table the model invented or guessed is the usual reason generated SQL fails or returns the
+// wrong data. A query that reads no tables scores 100.
+func sqlConfidence(query, head string, files []models.SQLFile) (int, []string) {
+	tables, ctes := validation.SQLTableReferences(query)
+	if len(tables) == 0 {
+		return 100, nil
+	}
+	known := knownSQLTables(head, files)
+	for _, c := range ctes {
+		known[c] = true
+	}
+	var unknown []string
+	for _, t := range tables {
+		if !known[t] {
+			unknown = append(unknown, t)
+		}
+	}
+	return (len(tables) - len(unknown)) * 100 / len(tables), unknown
+}
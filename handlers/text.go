@@ -0,0 +1,14 @@
+package handlers
+
+// truncateRunes returns the first n characters of s. Slicing by byte index could cut a
+// multi-byte character in half, leaving invalid UTF-8 in matches, logs and responses.
+func truncateRunes(s string, n int) (string, bool) {
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i], true
+		}
+		count++
+	}
+	return s, false
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	const mixed = "注册 🙋‍♀️ student 学生 — café 👩🏽‍🏫 报名"
+	tests := []struct {
+		name          string
+		in            string
+		n             int
+		want          string
+		wantTruncated bool
+	}{
+		{"cjk", "学生注册表格", 2, "学生", true},
+		{"emoji", "🙂🙃😀", 1, "🙂", true},
+		{"mixed cut inside emoji sequence", mixed, 4, "注册 🙋", true},
+		{"mixed cut after cjk", mixed, 18, "注册 🙋‍♀️ student 学生", true},
+		{"exact length", "学生🙂", 3, "学生🙂", false},
+		{"shorter than n", "hi 学生", 80, "hi 学生", false},
+		{"zero", "学生", 0, "", true},
+		{"empty", "", 5, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := truncateRunes(tt.in, tt.n)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Fatalf("truncateRunes(%q, %d) = %q, %v; want %q, %v", tt.in, tt.n, got, truncated, tt.want, tt.wantTruncated)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncateRunes(%q, %d) = %q is not valid UTF-8", tt.in, tt.n, got)
+			}
+			if count := utf8.RuneCountInString(got); count > tt.n {
+				t.Errorf("truncateRunes(%q, %d) kept %d runes", tt.in, tt.n, count)
+			}
+		})
+	}
+
+	// Every cut point of a multi-byte string stays valid and has exactly n runes
+	total := utf8.RuneCountInString(mixed)
+	for n := 0; n <= total; n++ {
+		got, truncated := truncateRunes(mixed, n)
+		if !utf8.ValidString(got) || utf8.RuneCountInString(got) != n || truncated != (n < total) {
+			t.Errorf("truncateRunes(mixed, %d) = %q, %v", n, got, truncated)
+		}
+	}
+}
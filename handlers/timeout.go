@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutWriter discards the handler's output once the request has been answered with a timeout.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	timedOut bool
+	// header is the handler's view of the response headers. It is copied to the real headers when
+	// the response is written, so the handler never touches the map timeOut writes from the
+	// deadline callback.
+	header http.Header
+}
+
+func newTimeoutWriter(w gin.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{ResponseWriter: w, header: w.Header().Clone()}
+}
+
+// Header is only used by the handler goroutine, so it needs no lock
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+// commitHeader replaces the real headers with the handler's until the response is written; w.mu must be held
+func (w *timeoutWriter) commitHeader() {
+	if w.timedOut || w.ResponseWriter.Written() {
+		return
+	}
+	dst := w.ResponseWriter.Header()
+	for k := range dst {
+		delete(dst, k)
+	}
+	for k, v := range w.header {
+		dst[k] = v
+	}
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.commitHeader()
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return len(data), nil
+	}
+	w.commitHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.commitHeader()
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Written()
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Status()
+}
+
+// timeoutBody is the 504 response body
+const timeoutBody = `{"error":"Request timed out"}`
+
+// timeOut answers 504 unless the handler has already started its response. The body carries a
+// Content-Length and is flushed, so the client has a complete response while the handler runs on.
+func (w *timeoutWriter) timeOut() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ResponseWriter.Written() {
+		return false
+	}
+	w.timedOut = true
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(timeoutBody)))
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	w.ResponseWriter.WriteString(timeoutBody)
+	w.ResponseWriter.Flush()
+	return true
+}
+
+// RouteTimeouts gives every request a deadline: the per-route budget from overrides (keyed by
+// route path, e.g. "/api/chat") or defaultTimeout. The deadline is set on the request context
+// so context-aware AI and HTTP calls are canceled, and the client gets 504 when it passes even if the
+// handler is still running. A zero budget disables the deadline for that route.
+func RouteTimeouts(defaultTimeout time.Duration, overrides map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := defaultTimeout
+		if d, ok := overrides[c.FullPath()]; ok {
+			timeout = d
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		tw := newTimeoutWriter(c.Writer)
+		c.Writer = tw
+
+		// The handler chain runs on this goroutine; only the 504 is written from the deadline callback
+		method, path := c.Request.Method, c.Request.URL.Path
+		fired := make(chan struct{})
+		stop := context.AfterFunc(ctx, func() {
+			defer close(fired)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && tw.timeOut() {
+				log.Printf("[TIMEOUT] %s %s exceeded %v", method, path, timeout)
+			}
+		})
+		c.Next()
+		// If the callback has started, let it finish before gin completes the response
+		if !stop() {
+			<-fired
+		}
+		// gin writes a body-less response itself, bypassing tw; hand it the handler's headers
+		tw.mu.Lock()
+		tw.commitHeader()
+		tw.mu.Unlock()
+	}
+}
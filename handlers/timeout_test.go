@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTimeoutRouter serves handler at /t behind RouteTimeouts and a middleware setting a header first
+func newTimeoutRouter(timeout time.Duration, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Header("X-Before", "1")
+		c.Next()
+	})
+	r.Use(RouteTimeouts(timeout, nil))
+	r.GET("/t", handler)
+	return r
+}
+
+func TestRouteTimeoutsAnswers504WhileHandlerSetsHeaders(t *testing.T) {
+	done := make(chan struct{})
+	r := newTimeoutRouter(20*time.Millisecond, func(c *gin.Context) {
+		defer close(done)
+		// Keep setting headers across the deadline, as a slow handler finishing its response would
+		end := time.Now().Add(60 * time.Millisecond)
+		for i := 0; time.Now().Before(end); i++ {
+			c.Header("X-Progress", strconv.Itoa(i))
+		}
+		c.JSON(http.StatusOK, gin.H{"late": true})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t", nil))
+	<-done
+
+	if w.Code != http.StatusGatewayTimeout || w.Body.String() != timeoutBody {
+		t.Fatalf("response = %d %q, want 504 %q", w.Code, w.Body.String(), timeoutBody)
+	}
+	if got := w.Header().Get("Content-Length"); got != strconv.Itoa(len(timeoutBody)) {
+		t.Errorf("Content-Length = %q, want %d", got, len(timeoutBody))
+	}
+	if w.Header().Get("X-Progress") != "" {
+		t.Error("handler header leaked into the 504 response")
+	}
+}
+
+func TestRouteTimeoutsKeepsHandlerHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  gin.HandlerFunc
+		wantCode int
+	}{
+		{"json", func(c *gin.Context) {
+			c.Header("X-Handler", "1")
+			c.JSON(http.StatusCreated, gin.H{"ok": true})
+		}, http.StatusCreated},
+		{"status only", func(c *gin.Context) {
+			c.Header("X-Handler", "1")
+			c.Status(http.StatusNoContent)
+		}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newTimeoutRouter(time.Second, tt.handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t", nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if w.Header().Get("X-Handler") != "1" || w.Header().Get("X-Before") != "1" {
+				t.Errorf("headers = %v, want X-Handler and X-Before", w.Header())
+			}
+		})
+	}
+}
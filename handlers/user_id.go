@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Fallback strategies for requests without an X-User-ID header (config USER_ID_FALLBACK)
+const (
+	userIDFallbackCookie = "cookie" // stable per-client ID from a signed cookie (default)
+	userIDFallbackShared = "shared" // everyone shares the "admin" bucket (legacy behavior)
+	userIDFallbackReject = "reject" // stateful endpoints require X-User-ID
+)
+
+const (
+	anonymousUserCookie = "anon_uid"
+	sharedUserID        = "admin"
+)
+
+// newCookieSecret returns a random signing key used when USER_ID_COOKIE_SECRET is not set.
+// Anonymous IDs then change on restart.
+func newCookieSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("failed to generate user ID cookie secret: " + err.Error())
+	}
+	log.Printf("[USER] USER_ID_COOKIE_SECRET not set; anonymous user IDs will not survive a restart")
+	return secret
+}
+
+// signAnonymousID returns id.signature for the anonymous user cookie
+func (h *Handlers) signAnonymousID(id string) string {
+	mac := hmac.New(sha256.New, h.cookieSecret)
+	mac.Write([]byte(id))
+	return id + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// anonymousIDFromCookie returns the verified anonymous ID from the request cookie, if any
+func (h *Handlers) anonymousIDFromCookie(c *gin.Context) string {
+	value, err := c.Cookie(anonymousUserCookie)
+	if err != nil || value == "" {
+		return ""
+	}
+	dot := strings.LastIndex(value, ".")
+	if dot <= 0 {
+		return ""
+	}
+	if !hmac.Equal([]byte(h.signAnonymousID(value[:dot])), []byte(value)) {
+		return ""
+	}
+	return value[:dot]
+}
+
+// maxUserIDLength bounds caller-supplied user IDs, which become part of storage keys
+const maxUserIDLength = 128
+
+// cleanUserID trims a caller-supplied user ID and returns "" if it is too long or contains
+// whitespace, control characters or the ':' key delimiter.
+func cleanUserID(userID string) string {
+	userID = strings.TrimSpace(userID)
+	if len(userID) > maxUserIDLength {
+		return ""
+	}
+	for _, r := range userID {
+		if r == ':' || r <= ' ' || r == 0x7f {
+			return ""
+		}
+	}
+	return userID
+}
+
+// explicitUserID returns the cleaned user ID the caller sent: the X-User-ID header, then the
+// user_id query parameter (for clients that cannot set headers, e.g. img/EventSource requests).
+// It is "" when neither is set or valid.
+func explicitUserID(c *gin.Context) string {
+	if userID := cleanUserID(c.GetHeader("X-User-ID")); userID != "" {
+		return userID
+	}
+	return cleanUserID(c.Query("user_id"))
+}
+
+// userIDFromRequest returns the caller's user ID (see explicitUserID), or the configured
+// fallback for anonymous requests. It returns "" when the strategy rejects anonymous callers.
+func (h *Handlers) userIDFromRequest(c *gin.Context) string {
+	if userID := explicitUserID(c); userID != "" {
+		return userID
+	}
+	switch h.cfg.UserIDFallback {
+	case userIDFallbackShared:
+		return sharedUserID
+	case userIDFallbackReject:
+		return ""
+	default:
+		id := h.anonymousIDFromCookie(c)
+		if id == "" {
+			id = uuid.New().String()
+			c.SetCookie(anonymousUserCookie, h.signAnonymousID(id), 365*24*60*60, "/", "", false, true)
+		}
+		return "anon-" + id
+	}
+}
+
+// requireUserID resolves the user ID for stateful endpoints, responding 401 when anonymous
+// callers are rejected. Handlers should return immediately when ok is false.
+func (h *Handlers) requireUserID(c *gin.Context) (userID string, ok bool) {
+	userID = h.userIDFromRequest(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "X-User-ID header or user_id query parameter is required"})
+		return "", false
+	}
+	return userID, true
+}
+
+// actorID returns the user ID to record as creator/submitter, "anonymous" if there is none.
+func (h *Handlers) actorID(c *gin.Context) string {
+	if userID := h.userIDFromRequest(c); userID != "" {
+		return userID
+	}
+	return "anonymous"
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build information, injected at build time:
+//
+//	go build -ldflags "-X idongivaflyinfa/handlers.Version=1.2.0 -X idongivaflyinfa/handlers.GitCommit=$(git rev-parse --short HEAD) -X idongivaflyinfa/handlers.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" -o tran_demo main.go
+var (
+	Version   = "dev"
+	GitCommit = ""
+	BuildTime = ""
+)
+
+// VersionHandler returns the version, git commit and build time of the running binary
+// @Summary      Build version
+// @Description  Get the version, git commit and build time injected via -ldflags (commit and time fall back to Go's embedded VCS info)
+// @Tags         Health
+// @Produce      json
+// @Success      200  {object}  map[string]string  "Build information"
+// @Router       /api/version [get]
+func (h *Handlers) VersionHandler(c *gin.Context) {
+	commit, built := GitCommit, BuildTime
+	if info, ok := debug.ReadBuildInfo(); ok && (commit == "" || built == "") {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && commit == "":
+				commit = s.Value
+			case s.Key == "vcs.time" && built == "":
+				built = s.Value
+			}
+		}
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	if built == "" {
+		built = "unknown"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version":    Version,
+		"git_commit": commit,
+		"build_time": built,
+	})
+}
@@ -3,12 +3,15 @@ package handlers
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
-	"time"
 
+	"idongivaflyinfa/db"
 	"idongivaflyinfa/models"
 
 	"github.com/gin-gonic/gin"
@@ -32,8 +35,8 @@ func (h *Handlers) RegisterVoiceHandler(c *gin.Context) {
 		return
 	}
 
-	// Get user ID from header or generate one
-	userID := c.GetHeader("X-User-ID")
+	// Get user ID from the request or generate one
+	userID := explicitUserID(c)
 	if userID == "" {
 		// Generate user ID from name hash
 		hash := md5.Sum([]byte(strings.ToLower(req.Name)))
@@ -80,7 +83,7 @@ func (h *Handlers) RegisterVoiceHandler(c *gin.Context) {
 
 // RecognizeVoiceHandler recognizes a speaker from voice input
 // @Summary      Recognize voice
-// @Description  Recognize a speaker and detect attendance intent from voice input
+// @Description  Recognize a speaker and detect attendance intent from voice input. With dry_run, attendance is not logged.
 // @Tags         Voice Recognition
 // @Accept       json
 // @Produce      json
@@ -104,14 +107,6 @@ func (h *Handlers) RecognizeVoiceHandler(c *gin.Context) {
 		return
 	}
 
-	if len(profiles) == 0 {
-		c.JSON(http.StatusOK, models.VoiceRecognitionResponse{
-			Recognized: false,
-			Message:    "Sorry, you're not in our school.",
-		})
-		return
-	}
-
 	// Recognize voice
 	response, err := h.voiceService.RecognizeVoice(req.AudioData, profiles)
 	if err != nil {
@@ -120,41 +115,110 @@ func (h *Handlers) RecognizeVoiceHandler(c *gin.Context) {
 		return
 	}
 
+	if req.DryRun {
+		log.Printf("[VOICE] Dry run recognition: recognized=%v user=%s intent=%s", response.Recognized, response.UserID, response.Intent)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
 	// If recognized and intent is attendance-related, log it
-	if response.Recognized && (response.Intent == "attendance" || response.Intent == "punch_in" || response.Intent == "here") {
-		log.Printf("[VOICE] Attendance logged for: %s (%s)", response.Name, response.UserID)
-		// Store attendance in chat history
-		attendanceMsg := fmt.Sprintf("%s - %s", response.Name, time.Now().Format("2006-01-02 15:04:05"))
-		h.db.StoreChatHistory(response.UserID, "Voice attendance", attendanceMsg)
+	if response.Recognized && isAttendanceIntent(response.Intent) {
+		h.logAttendance(response)
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// ListVoiceProfilesHandler lists all registered voice profiles
+// ListVoiceProfilesHandler lists registered voice profiles
 // @Summary      List voice profiles
-// @Description  Get a list of all registered voice profiles
+// @Description  Get registered voice profiles, ordered by name (default) or newest first, optionally filtered by name and paged
 // @Tags         Voice Recognition
 // @Produce      json
-// @Success      200  {object}  map[string][]models.VoiceProfile  "List of voice profiles"
-// @Failure      500  {object}  map[string]string                  "Failed to list profiles"
+// @Param        q       query     string  false  "Case-insensitive name filter (substring)"
+// @Param        sort    query     string  false  "Sort order: name (default) or created (newest first)"
+// @Param        limit   query     int     false  "Maximum profiles to return (default: all)"
+// @Param        offset  query     int     false  "Profiles to skip"
+// @Success      200     {object}  models.Page[models.VoiceProfile]  "Page of profiles (total counts matching profiles before paging)"
+// @Failure      400     {object}  map[string]string                 "Invalid query parameters"
+// @Failure      500     {object}  map[string]string                 "Failed to list profiles"
 // @Router       /api/voice/profiles [get]
 func (h *Handlers) ListVoiceProfilesHandler(c *gin.Context) {
+	sortBy := c.DefaultQuery("sort", "name")
+	if sortBy != "name" && sortBy != "created" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be name or created"})
+		return
+	}
+	limit, offset, ok := queryPage(c)
+	if !ok {
+		return
+	}
+
 	profiles, err := h.db.GetAllVoiceProfiles()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list voice profiles: " + err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"profiles": profiles})
+	if q := strings.ToLower(strings.TrimSpace(c.Query("q"))); q != "" {
+		filtered := profiles[:0]
+		for _, p := range profiles {
+			if strings.Contains(strings.ToLower(p.Name), q) {
+				filtered = append(filtered, p)
+			}
+		}
+		profiles = filtered
+	}
+
+	// Ties fall back to user ID so pages are stable between requests
+	sort.Slice(profiles, func(i, j int) bool {
+		if sortBy == "created" && profiles[i].CreatedAt != profiles[j].CreatedAt {
+			return models.TimestampAfter(profiles[i].CreatedAt, profiles[j].CreatedAt)
+		}
+		ni, nj := strings.ToLower(profiles[i].Name), strings.ToLower(profiles[j].Name)
+		if sortBy == "name" && ni != nj {
+			return ni < nj
+		}
+		return profiles[i].UserID < profiles[j].UserID
+	})
+
+	c.JSON(http.StatusOK, models.NewPage(profiles, limit, offset))
+}
+
+// queryPage parses the optional limit and offset query parameters of a paged list endpoint,
+// writing a 400 response and returning false if either is malformed.
+func queryPage(c *gin.Context) (limit, offset int, ok bool) {
+	if limit, ok = queryNonNegativeInt(c, "limit"); !ok {
+		return 0, 0, false
+	}
+	if offset, ok = queryNonNegativeInt(c, "offset"); !ok {
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
+
+// queryNonNegativeInt parses an optional integer query parameter (0 when absent),
+// writing a 400 response and returning false if it is malformed or negative.
+func queryNonNegativeInt(c *gin.Context, name string) (int, bool) {
+	s := c.Query(name)
+	if s == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a non-negative integer"})
+		return 0, false
+	}
+	return n, true
 }
 
 // DeleteVoiceProfileHandler deletes a voice profile
 // @Summary      Delete voice profile
-// @Description  Delete a registered voice profile
+// @Description  Delete a registered voice profile. Requires the admin token.
 // @Tags         Voice Recognition
+// @Security     AdminToken
 // @Param        user_id  path      string  true  "User ID"
 // @Success      200      {object}  map[string]string  "Profile deleted"
+// @Failure      401      {object}  map[string]string  "Admin token required"
 // @Failure      404      {object}  map[string]string  "Profile not found"
 // @Failure      500      {object}  map[string]string  "Failed to delete profile"
 // @Router       /api/voice/profile/{user_id} [delete]
@@ -173,6 +237,45 @@ func (h *Handlers) DeleteVoiceProfileHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Voice profile deleted successfully"})
 }
 
+// MergeVoiceProfilesHandler consolidates a duplicate voice profile into another
+// @Summary      Merge voice profiles
+// @Description  Move all voice samples of source_user_id into target_user_id's profile and delete the source profile. For users registered twice. Requires the admin token.
+// @Tags         Voice Recognition
+// @Accept       json
+// @Produce      json
+// @Security     AdminToken
+// @Param        request  body      models.VoiceProfileMergeRequest  true  "Profiles to merge"
+// @Success      200      {object}  models.VoiceProfile  "Merged profile"
+// @Failure      400      {object}  map[string]string    "Invalid request"
+// @Failure      404      {object}  map[string]string    "Profile not found"
+// @Failure      500      {object}  map[string]string    "Failed to merge profiles"
+// @Router       /api/voice/profiles/merge [post]
+func (h *Handlers) MergeVoiceProfilesHandler(c *gin.Context) {
+	var req models.VoiceProfileMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	if req.TargetUserID == req.SourceUserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_user_id and source_user_id must differ"})
+		return
+	}
+
+	profile, err := h.db.MergeVoiceProfiles(req.TargetUserID, req.SourceUserID)
+	if errors.Is(err, db.ErrVoiceProfileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("[VOICE] Error merging profile %s into %s: %v", req.SourceUserID, req.TargetUserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge voice profiles: " + err.Error()})
+		return
+	}
+
+	log.Printf("[VOICE] Merged profile %s into %s (%d samples)", req.SourceUserID, req.TargetUserID, len(profile.VoiceSamples))
+	c.JSON(http.StatusOK, profile)
+}
+
 // HandleVoiceChat processes voice input through the chat interface
 func (h *Handlers) HandleVoiceChat(c *gin.Context, userID string, audioData string) (*models.ChatResponse, error) {
 	// Get all voice profiles
@@ -191,17 +294,14 @@ func (h *Handlers) HandleVoiceChat(c *gin.Context, userID string, audioData stri
 	var chatResponse models.ChatResponse
 	
 	if !voiceResponse.Recognized {
-		chatResponse.Response = voiceResponse.Message // "Sorry, you're not in our school."
+		chatResponse.Response = voiceResponse.Message // explains why (no profiles, samples unreadable, no match)
 		return &chatResponse, nil
 	}
 
 	// User recognized - check intent
-	if voiceResponse.Intent == "attendance" || voiceResponse.Intent == "punch_in" || voiceResponse.Intent == "here" {
+	if isAttendanceIntent(voiceResponse.Intent) {
 		chatResponse.Response = voiceResponse.Message // "Punched in" or "Gotcha!"
-		
-		// Log attendance
-		attendanceMsg := fmt.Sprintf("%s - %s", voiceResponse.Name, time.Now().Format("2006-01-02 15:04:05"))
-		h.db.StoreChatHistory(voiceResponse.UserID, "Voice attendance", attendanceMsg)
+		h.logAttendance(voiceResponse)
 	} else {
 		chatResponse.Response = voiceResponse.Message
 	}
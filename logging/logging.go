@@ -0,0 +1,161 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the per-request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// Setup configures the standard logger (and gin's writers) for the given format.
+// "json" emits one JSON object per line; anything else keeps the default text output.
+// In both modes the last errorBuffer error-level entries are kept for RecentErrors.
+func Setup(format string, errorBuffer int) {
+	recentErrors = newErrorRing(errorBuffer)
+	if format != "json" {
+		log.SetOutput(&textWriter{out: os.Stderr})
+		return
+	}
+	w := &jsonWriter{out: os.Stderr}
+	log.SetFlags(0)
+	log.SetOutput(w)
+	gin.DefaultWriter = w
+	gin.DefaultErrorWriter = w
+}
+
+// IsJSON reports whether format selects JSON logging
+func IsJSON(format string) bool {
+	return format == "json"
+}
+
+// textWriter passes log output through unchanged, noting error lines for RecentErrors
+type textWriter struct {
+	out io.Writer
+}
+
+func (w *textWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			recordIfError(newEntry(line))
+		}
+	}
+	return w.out.Write(p)
+}
+
+// jsonWriter turns each text log line into a JSON record
+type jsonWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// Entry is one structured log record
+type Entry struct {
+	Time      string                 `json:"time"`
+	Level     string                 `json:"level"`
+	Msg       string                 `json:"msg"`
+	Component string                 `json:"component,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (w *jsonWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := w.emit(newEntry(line)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *jsonWriter) emit(r Entry) error {
+	recordIfError(r)
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(r); err != nil {
+		return err
+	}
+	_, err := w.out.Write(buf.Bytes())
+	return err
+}
+
+// newEntry builds an entry from a text line, lifting a leading "[TAG]" into component
+// and inferring the level from the message.
+func newEntry(line string) Entry {
+	r := Entry{Time: time.Now().UTC().Format(time.RFC3339Nano), Level: "info", Msg: line}
+	if strings.HasPrefix(line, "[") {
+		if end := strings.Index(line, "]"); end > 0 {
+			r.Component = line[1:end]
+			r.Msg = strings.TrimSpace(line[end+1:])
+		}
+	}
+	lower := strings.ToLower(r.Msg)
+	switch {
+	// A warning often describes a failure ("Warning: Failed to ..."), so it is checked first
+	case strings.HasPrefix(lower, "warn") || strings.Contains(lower, "warning"):
+		r.Level = "warn"
+	case strings.Contains(lower, "error") || strings.Contains(lower, "failed") || strings.Contains(lower, "panic"):
+		r.Level = "error"
+	}
+	return r
+}
+
+// RequestLogger assigns each request an ID (reusing an incoming X-Request-ID) and, in JSON mode,
+// writes a JSON access log line when it completes; use it instead of gin's text logger there.
+// 5xx responses are kept for RecentErrors with their request ID in either mode.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := "info"
+		if status >= 500 {
+			level = "error"
+		} else if status >= 400 {
+			level = "warn"
+		}
+		entry := Entry{
+			Time:      start.UTC().Format(time.RFC3339Nano),
+			Level:     level,
+			Msg:       "request completed",
+			Component: "HTTP",
+			RequestID: requestID,
+			Fields: map[string]interface{}{
+				"method":     c.Request.Method,
+				"path":       c.Request.URL.Path,
+				"status":     status,
+				"latency_ms": time.Since(start).Milliseconds(),
+				"client_ip":  c.ClientIP(),
+			},
+		}
+		w, ok := log.Writer().(*jsonWriter)
+		if !ok {
+			recordIfError(entry)
+			return
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		_ = w.emit(entry)
+	}
+}
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestNewEntry(t *testing.T) {
+	tests := []struct {
+		line          string
+		wantLevel     string
+		wantComponent string
+		wantMsg       string
+	}{
+		{"Server starting on port 9090", "info", "", "Server starting on port 9090"},
+		{"[DB] Storing complaint state", "info", "DB", "Storing complaint state"},
+		{"Warning: Failed to initialize SQL Server service: timeout", "warn", "", "Warning: Failed to initialize SQL Server service: timeout"},
+		{"[USER] Warning: cookie secret not set", "warn", "USER", "Warning: cookie secret not set"},
+		{"[AI] GenerateSQL failed: upstream error", "error", "AI", "GenerateSQL failed: upstream error"},
+		{"Panic in background SQL execution: boom", "error", "", "Panic in background SQL execution: boom"},
+	}
+	for _, tt := range tests {
+		got := newEntry(tt.line)
+		if got.Level != tt.wantLevel || got.Component != tt.wantComponent || got.Msg != tt.wantMsg {
+			t.Errorf("newEntry(%q) = level %q, component %q, msg %q; want %q, %q, %q",
+				tt.line, got.Level, got.Component, got.Msg, tt.wantLevel, tt.wantComponent, tt.wantMsg)
+		}
+	}
+}
+
+func TestJSONWriterEmitsOneRecordPerLine(t *testing.T) {
+	var out bytes.Buffer
+	w := &jsonWriter{out: &out}
+	if _, err := w.Write([]byte("[AI] Response status 400: {\n  \"code\": \"InvalidParameter\"\n}\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	scanner := bufio.NewScanner(&out)
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %d is not JSON: %q: %v", lines, scanner.Text(), err)
+		}
+	}
+	if lines != 3 {
+		t.Errorf("got %d records, want 3", lines)
+	}
+}
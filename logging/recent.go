@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"sync"
+)
+
+// errorRing keeps the most recent error-level entries for the diagnostics endpoint
+type errorRing struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+var recentErrors = newErrorRing(defaultErrorBufferSize)
+
+const defaultErrorBufferSize = 100
+
+func newErrorRing(size int) *errorRing {
+	if size <= 0 {
+		size = defaultErrorBufferSize
+	}
+	return &errorRing{entries: make([]Entry, size)}
+}
+
+func (r *errorRing) add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// latest returns up to n entries, newest first
+func (r *errorRing) latest(n int) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+	out := make([]Entry, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return out
+}
+
+// RecentErrors returns up to n of the most recent error-level log entries, newest first.
+// n <= 0 returns everything in the buffer.
+func RecentErrors(n int) []Entry {
+	return recentErrors.latest(n)
+}
+
+// recordIfError keeps e in the recent errors buffer when it is error level
+func recordIfError(e Entry) {
+	if e.Level == "error" {
+		recentErrors.add(e)
+	}
+}
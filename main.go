@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"idongivaflyinfa/ai"
 	"idongivaflyinfa/cache"
@@ -9,6 +10,7 @@ import (
 	"idongivaflyinfa/db"
 	_ "idongivaflyinfa/docs" // Swagger docs
 	"idongivaflyinfa/handlers"
+	"idongivaflyinfa/logging"
 	"idongivaflyinfa/service"
 
 	"github.com/gin-gonic/gin"
@@ -18,6 +20,7 @@ import (
 
 func main() {
 	cfg := config.GetConfig()
+	logging.Setup(cfg.LogFormat, cfg.ErrorBufferSize)
 
 	// Initialize database
 	database, err := db.New(cfg.DBPath)
@@ -28,6 +31,7 @@ func main() {
 
 	// Initialize cache
 	appCache := cache.New()
+	defer appCache.Close()
 
 	// Initialize Gemini AI client
 	aiService, err := ai.New(cfg.GeminiAPIKey, cfg.ModelName, appCache)
@@ -59,10 +63,36 @@ func main() {
 	}
 
 	// Initialize handlers
-	h := handlers.New(database, aiService, sqlService, cfg.SQLFilesDir, cfg.VoiceSamplesDir, cfg.ExternalAPIBase)
+	h := handlers.New(database, aiService, sqlService, cfg)
+	if err := h.ReconcileProducts(); err != nil {
+		log.Printf("Warning: Failed to reconcile products index: %v", err)
+	}
+
+	r := newRouter(cfg, h)
+
+	log.Printf("Server starting on port %s", cfg.Port)
+	if err := r.Run(":" + cfg.Port); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+// newRouter builds the Gin engine with all middleware and routes, so the full routing
+// table can be constructed (e.g. with httptest) without starting the server.
+func newRouter(cfg config.Config, h *handlers.Handlers) *gin.Engine {
+	var r *gin.Engine
+	if logging.IsJSON(cfg.LogFormat) {
+		r = gin.New()
+		r.Use(logging.RequestLogger(), gin.Recovery())
+	} else {
+		r = gin.Default()
+		r.Use(logging.RequestLogger())
+	}
 
-	// Setup Gin router
-	r := gin.Default()
+	// c.ClientIP() (rate limits, logs) reads forwarding headers only from trusted proxies
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Printf("Warning: Invalid TRUSTED_PROXIES, trusting no proxy: %v", err)
+		r.SetTrustedProxies(nil)
+	}
 
 	// CORS configuration - Allow ALL origins, headers, and methods
 	// Simplified for nginx proxy compatibility - always allow all origins
@@ -87,24 +117,46 @@ func main() {
 		c.Next()
 	})
 
+	// Per-route request deadlines (504 when exceeded); AI generation routes get the longer budget
+	r.Use(handlers.RouteTimeouts(cfg.RequestTimeout, map[string]time.Duration{
+		"/api/chat":                  cfg.GenerationTimeout,
+		"/api/results/generate-html": cfg.GenerationTimeout,
+	}))
+
+	// Demo mode: reject mutating requests
+	r.Use(handlers.DemoReadOnly(cfg.DemoMode))
+
 	// Swagger documentation
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET("/api/openapi.json", h.OpenAPIHandler)
+
+	// Admin routes (require ADMIN_API_TOKEN)
+	admin := r.Group("/api/admin", handlers.RequireAdmin(cfg.AdminToken))
+	admin.GET("/errors", h.RecentErrorsHandler)
+	admin.GET("/cache-stats", h.CacheStatsHandler)
 
 	// Routes
 	r.GET("/health", h.HealthHandler)
+	r.GET("/api/version", h.VersionHandler)
 	r.GET("/api/chat/sessions", h.ListChatSessionsHandler)
 	r.POST("/api/chat/sessions", h.CreateChatSessionHandler)
 	r.GET("/api/chat/sessions/:id", h.GetChatSessionHandler)
 	r.PUT("/api/chat/sessions/:id", h.UpdateChatSessionHandler)
 	r.DELETE("/api/chat/sessions/:id", h.DeleteChatSessionHandler)
+	r.GET("/api/chat/messages/:id/sql", h.GetMessageSQLHandler)
+	r.GET("/api/chat/export", h.ExportChatHistoryHandler)
 	r.POST("/api/chat", h.ChatHandler)
 	r.POST("/api/sql/upload", h.UploadSQLFileHandler)
 	r.GET("/api/sql/files", h.ListSQLFilesHandler)
+	r.GET("/api/report-heads", h.ListReportHeadsHandler)
 	r.POST("/api/sql/execute", h.ExecuteSQLHandler)
 	
 	// Result file routes
 	r.GET("/api/results/files", h.ListResultFilesHandler)
 	r.GET("/api/results/file/:filename", h.GetResultFileHandler)
+	r.POST("/api/results/diff", h.DiffResultFilesHandler)
+	r.PUT("/api/results/file/:filename/tags", h.SetResultTagsHandler)
+	r.POST("/api/results/delete", handlers.RequireAdmin(cfg.AdminToken), h.DeleteResultFilesHandler)
 	r.POST("/api/results/generate-html", h.GenerateHTMLHandler)
 	r.GET("/api/results/html/:filename", h.ServeHTMLHandler)
 	
@@ -112,10 +164,13 @@ func main() {
 	r.POST("/api/voice/register", h.RegisterVoiceHandler)
 	r.POST("/api/voice/recognize", h.RecognizeVoiceHandler)
 	r.GET("/api/voice/profiles", h.ListVoiceProfilesHandler)
-	r.DELETE("/api/voice/profile/:user_id", h.DeleteVoiceProfileHandler)
+	r.POST("/api/voice/profiles/merge", handlers.RequireAdmin(cfg.AdminToken), h.MergeVoiceProfilesHandler)
+	r.DELETE("/api/voice/profile/:user_id", handlers.RequireAdmin(cfg.AdminToken), h.DeleteVoiceProfileHandler)
+	r.GET("/api/attendance/summary", h.AttendanceSummaryHandler)
 
 	// Products routes
 	r.GET("/api/products/files", h.ListProductsHandler)
+	r.DELETE("/api/products/files/:filename", h.DeleteProductHandler)
 	r.GET("/products/index.html", func(c *gin.Context) {
 		c.File("./products/index.html")
 	})
@@ -130,7 +185,10 @@ func main() {
 	r.GET("/api/forms/templates/:id", h.GetFormTemplateHandler)
 	r.POST("/api/forms/templates", h.CreateFormTemplateHandler)
 	r.PUT("/api/forms/templates/:id", h.UpdateFormTemplateHandler)
+	r.PUT("/api/forms/templates/:id/tags", h.SetFormTemplateTagsHandler)
 	r.DELETE("/api/forms/templates/:id", h.DeleteFormTemplateHandler)
+	r.POST("/api/forms/validate", h.ValidateFormTemplateHandler)
+	r.GET("/api/forms/proposals", h.ListFormProposalsHandler)
 	
 	// Form answers
 	r.GET("/api/forms/answers", h.ListFormAnswersHandler)
@@ -155,8 +213,5 @@ func main() {
 		c.File("./frontend/build/index.html")
 	})
 
-	log.Printf("Server starting on port %s", cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
+	return r
 }
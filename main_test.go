@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"idongivaflyinfa/config"
+	"idongivaflyinfa/db"
+	"idongivaflyinfa/handlers"
+)
+
+const testAdminToken = "test-admin-token"
+
+// newTestRouter builds the full router on a temporary Badger store, without AI or SQL Server.
+// configure, if given, adjusts the test configuration.
+func newTestRouter(t *testing.T, configure ...func(cfg *config.Config)) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	database, err := db.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	cfg := config.Config{
+		SQLFilesDir:     t.TempDir(),
+		ResultsDir:      t.TempDir(),
+		SitesDir:        t.TempDir(),
+		VoiceSamplesDir: t.TempDir(),
+		AdminToken:      testAdminToken,
+		UserIDFallback:  "reject",
+	}
+	for _, f := range configure {
+		f(&cfg)
+	}
+	return newRouter(cfg, handlers.New(database, nil, nil, cfg))
+}
+
+// serve sends a request through r and returns the recorded response
+func serve(r *gin.Engine, method, path string, header map[string]string) *httptest.ResponseRecorder {
+	return serveBody(r, method, path, "", header)
+}
+
+// serveBody is serve with a JSON request body
+func serveBody(r *gin.Engine, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouter(t *testing.T) {
+	r := newTestRouter(t)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		header   map[string]string
+		wantCode int
+	}{
+		{"health", http.MethodGet, "/health", nil, http.StatusOK},
+		{"version", http.MethodGet, "/api/version", nil, http.StatusOK},
+		{"chat sessions", http.MethodGet, "/api/chat/sessions", map[string]string{"X-User-ID": "user-1"}, http.StatusOK},
+		{"chat sessions without user", http.MethodGet, "/api/chat/sessions", nil, http.StatusUnauthorized},
+		{"missing chat session", http.MethodGet, "/api/chat/sessions/missing", map[string]string{"X-User-ID": "user-1"}, http.StatusNotFound},
+		{"admin without token", http.MethodGet, "/api/admin/errors", nil, http.StatusUnauthorized},
+		{"admin with wrong token", http.MethodGet, "/api/admin/errors", map[string]string{handlers.AdminTokenHeader: "wrong"}, http.StatusUnauthorized},
+		{"admin with bearer token", http.MethodGet, "/api/admin/errors", map[string]string{"Authorization": "Bearer " + testAdminToken}, http.StatusOK},
+		{"voice profile delete without token", http.MethodDelete, "/api/voice/profile/user-1", nil, http.StatusUnauthorized},
+		{"voice profile delete with token", http.MethodDelete, "/api/voice/profile/user-1", map[string]string{handlers.AdminTokenHeader: testAdminToken}, http.StatusOK},
+		{"results delete without token", http.MethodPost, "/api/results/delete", nil, http.StatusUnauthorized},
+		{"results delete with token", http.MethodPost, "/api/results/delete", map[string]string{handlers.AdminTokenHeader: testAdminToken}, http.StatusBadRequest},
+		{"voice profile merge without token", http.MethodPost, "/api/voice/profiles/merge", nil, http.StatusUnauthorized},
+		{"sql files", http.MethodGet, "/api/sql/files", nil, http.StatusOK},
+		{"form templates", http.MethodGet, "/api/forms/templates", nil, http.StatusOK},
+		{"missing form template", http.MethodGet, "/api/forms/templates/missing", nil, http.StatusNotFound},
+		{"chat with empty body", http.MethodPost, "/api/chat", map[string]string{"X-User-ID": "user-1"}, http.StatusBadRequest},
+		{"chat without user", http.MethodPost, "/api/chat", nil, http.StatusUnauthorized},
+		{"cors preflight", http.MethodOptions, "/api/chat", nil, http.StatusNoContent},
+		{"unknown route", http.MethodGet, "/api/does-not-exist", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, tt.path, tt.header)
+			if w.Code != tt.wantCode {
+				t.Errorf("%s %s = %d, want %d (body %s)", tt.method, tt.path, w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouterResponses(t *testing.T) {
+	r := newTestRouter(t)
+
+	var health map[string]string
+	if w := serve(r, http.MethodGet, "/health", nil); json.Unmarshal(w.Body.Bytes(), &health) != nil || health["status"] != "healthy" {
+		t.Errorf("GET /health = %s, want status healthy", w.Body.String())
+	}
+
+	var version map[string]string
+	if w := serve(r, http.MethodGet, "/api/version", nil); json.Unmarshal(w.Body.Bytes(), &version) != nil || version["version"] != handlers.Version {
+		t.Errorf("GET /api/version = %s, want version %q", w.Body.String(), handlers.Version)
+	}
+
+	var sessions struct {
+		Items []json.RawMessage `json:"items"`
+		Total int               `json:"total"`
+	}
+	w := serve(r, http.MethodGet, "/api/chat/sessions", map[string]string{"X-User-ID": "user-1"})
+	if err := json.Unmarshal(w.Body.Bytes(), &sessions); err != nil || sessions.Total != 1 || len(sessions.Items) != 1 {
+		t.Errorf("GET /api/chat/sessions = %s, want the default session", w.Body.String())
+	}
+
+	var errBody map[string]string
+	w = serve(r, http.MethodGet, "/api/admin/errors", nil)
+	if json.Unmarshal(w.Body.Bytes(), &errBody) != nil || errBody["error"] == "" {
+		t.Errorf("GET /api/admin/errors without token = %s, want a JSON error", w.Body.String())
+	}
+}
+
+func TestFormTemplateAndAnswerRoutes(t *testing.T) {
+	r := newTestRouter(t)
+	user := map[string]string{"X-User-ID": "user-1"}
+
+	template := `{"id": "trip-1", "name": "Field Trip", "user_type": "student", "fields": [{"name": "phone", "label": "Phone", "type": "tel"}]}`
+	if w := serveBody(r, http.MethodPost, "/api/forms/templates", template, user); w.Code != http.StatusOK {
+		t.Fatalf("POST /api/forms/templates = %d, want 200 (body %s)", w.Code, w.Body.String())
+	}
+	if w := serveBody(r, http.MethodPost, "/api/forms/templates", `{"name": "No Type"}`, user); w.Code != http.StatusBadRequest {
+		t.Errorf("POST /api/forms/templates without user_type = %d, want 400", w.Code)
+	}
+
+	var got map[string]interface{}
+	w := serve(r, http.MethodGet, "/api/forms/templates/trip-1", nil)
+	if json.Unmarshal(w.Body.Bytes(), &got) != nil || got["name"] != "Field Trip" {
+		t.Errorf("GET /api/forms/templates/trip-1 = %d %s, want the template", w.Code, w.Body.String())
+	}
+
+	update := `{"name": "Museum Trip", "user_type": "student", "fields": [{"name": "phone", "label": "Phone", "type": "tel"}]}`
+	if w := serveBody(r, http.MethodPut, "/api/forms/templates/trip-1", update, user); w.Code != http.StatusOK {
+		t.Errorf("PUT /api/forms/templates/trip-1 = %d, want 200 (body %s)", w.Code, w.Body.String())
+	}
+	if w := serveBody(r, http.MethodPut, "/api/forms/templates/missing", update, user); w.Code != http.StatusNotFound {
+		t.Errorf("PUT /api/forms/templates/missing = %d, want 404", w.Code)
+	}
+
+	answer := `{"form_id": "trip-1", "user_id": "s-1", "user_type": "student", "answers": {"phone": "555-0100"}}`
+	w = serveBody(r, http.MethodPost, "/api/forms/answers", answer, user)
+	if json.Unmarshal(w.Body.Bytes(), &got) != nil || got["form_name"] != "Museum Trip" {
+		t.Errorf("POST /api/forms/answers = %d %s, want the answer to the updated form", w.Code, w.Body.String())
+	}
+
+	var answers struct {
+		Items []json.RawMessage `json:"items"`
+		Total int               `json:"total"`
+	}
+	w = serve(r, http.MethodGet, "/api/forms/answers?form_id=trip-1", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &answers); err != nil || answers.Total != 1 || len(answers.Items) != 1 {
+		t.Errorf("GET /api/forms/answers?form_id=trip-1 = %d %s, want one answer", w.Code, w.Body.String())
+	}
+
+	if w := serve(r, http.MethodDelete, "/api/forms/templates/trip-1", user); w.Code != http.StatusOK {
+		t.Errorf("DELETE /api/forms/templates/trip-1 = %d, want 200", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/api/forms/templates/trip-1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET deleted template = %d, want 404", w.Code)
+	}
+}
+
+func TestPublicFormAnswerUserType(t *testing.T) {
+	r := newTestRouter(t)
+
+	survey := `{"id": "survey-1", "name": "Lunch Survey", "user_type": "student", "public": true, "fields": [{"name": "rating", "label": "Rating", "type": "number"}]}`
+	if w := serveBody(r, http.MethodPost, "/api/forms/templates", survey, nil); w.Code != http.StatusOK {
+		t.Fatalf("POST /api/forms/templates = %d, want 200 (body %s)", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"anonymous takes the form's type", `{"form_id": "survey-1", "answers": {"rating": 5}}`, http.StatusOK},
+		{"staff", `{"form_id": "survey-1", "user_type": "staff", "answers": {"rating": 4}}`, http.StatusOK},
+		{"unknown type", `{"form_id": "survey-1", "user_type": "admin", "answers": {"rating": 1}}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serveBody(r, http.MethodPost, "/api/forms/answers", tt.body, nil); w.Code != tt.wantCode {
+				t.Errorf("POST /api/forms/answers = %d, want %d (body %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPISpecListsRoutes(t *testing.T) {
+	r := newTestRouter(t)
+
+	w := serve(r, http.MethodGet, "/api/openapi.json", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/openapi.json = %d, want 200", w.Code)
+	}
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("decode spec: %v", err)
+	}
+	for _, path := range []string{"/api/chat", "/api/openapi.json", "/api/forms/templates", "/health"} {
+		if _, ok := spec.Paths[path]; !ok {
+			t.Errorf("spec has no %s path", path)
+		}
+	}
+
+	// Every API route is documented
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		path := ginPathToSpec(route.Path)
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("spec has no %s %s", route.Method, path)
+		}
+	}
+}
+
+// ginPathToSpec converts ":id" path parameters to the spec's "{id}" form
+func ginPathToSpec(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func TestFormAnswerRateLimitIgnoresForwardedFor(t *testing.T) {
+	r := newTestRouter(t, func(cfg *config.Config) {
+		cfg.FormAnswerRateLimit = 2
+		cfg.FormAnswerRateWindow = time.Minute
+	})
+
+	body := `{"form_id": "survey-1", "answers": {}}`
+	for i := 1; i <= 3; i++ {
+		// A client rotating X-Forwarded-For still counts as one client
+		w := serveBody(r, http.MethodPost, "/api/forms/answers", body, map[string]string{"X-Forwarded-For": fmt.Sprintf("10.0.0.%d", i)})
+		want := http.StatusBadRequest // form not found, but counted
+		if i == 3 {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Fatalf("submission %d = %d, want %d (body %s)", i, w.Code, want, w.Body.String())
+		}
+	}
+}
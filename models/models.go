@@ -4,10 +4,14 @@ package models
 const DefaultChatSessionID = "default"
 
 type ChatRequest struct {
-	Message     string `json:"message,omitempty"`
-	SessionID   string `json:"session_id,omitempty"`   // Optional; empty means default session
-	AudioData   string `json:"audio_data,omitempty"`  // Base64 encoded audio for voice input
-	AudioFormat string `json:"audio_format,omitempty"` // "wav", "mp3", "webm", etc.
+	Message      string `json:"message,omitempty"`
+	SessionID    string `json:"session_id,omitempty"`    // Optional; empty means default session
+	AudioData    string `json:"audio_data,omitempty"`    // Base64 encoded audio for voice input
+	AudioFormat  string `json:"audio_format,omitempty"`  // "wav", "mp3", "webm", etc.
+	Fresh        bool   `json:"fresh,omitempty"`         // Re-run report SQL even if a cached result exists
+	SaveForm     bool   `json:"save_form,omitempty"`     // Save a generated form as a template right away instead of proposing it
+	GenerateHTML *bool  `json:"generate_html,omitempty"` // Generate an HTML page for a report result; unset uses REPORT_AUTO_HTML
+	ReportHead   string `json:"report_head,omitempty"`   // Named SQL head for report SQL (see GET /api/report-heads); empty uses DEFAULT_REPORT_HEAD
 }
 
 // ChatSession is a conversation session (default or user-created).
@@ -19,8 +23,17 @@ type ChatSession struct {
 	UpdatedAt string `json:"updated_at"`
 }
 
+// ChatSummary is the running summary of a session's older messages. Messages counts the leading
+// user/assistant messages it covers, so only messages after those need summarizing next time.
+type ChatSummary struct {
+	Summary   string `json:"summary"`
+	Messages  int    `json:"messages"`
+	UpdatedAt string `json:"updated_at"`
+}
+
 // StoredChatMessage is one message in a session (user or assistant), stored in DB.
 type StoredChatMessage struct {
+	ID              string                       `json:"id,omitempty"` // Set on assistant messages; keys the message's SQL record
 	Role            string                       `json:"role"` // "user" | "assistant" | "error"
 	Content         string                       `json:"content"`
 	SQL             string                       `json:"sql,omitempty"`
@@ -37,6 +50,83 @@ type ChatResponse struct {
 	ConfirmationCard *RegistrationConfirmationCard `json:"confirmation_card,omitempty"`
 	ProposedForm     *ProposedFormCard             `json:"proposed_form,omitempty"`
 	ResearchContent  string                       `json:"research_content,omitempty"`
+	Report           *ReportResponse               `json:"report,omitempty"` // Set for report (SQL) requests
+	Warning          string                        `json:"warning,omitempty"` // Part of the request failed (e.g. the generated page could not be saved)
+	MessageID        string                        `json:"message_id,omitempty"` // ID of the stored assistant message (GET /api/chat/messages/{id}/sql for reports)
+}
+
+// ChatExport is a user's complete chat history
+type ChatExport struct {
+	UserID     string              `json:"user_id"`
+	ExportedAt string              `json:"exported_at"`
+	Sessions   []ChatExportSession `json:"sessions"` // Oldest session first
+}
+
+// ChatExportSession is one session and its messages in order
+type ChatExportSession struct {
+	Session  ChatSession         `json:"session"`
+	Messages []StoredChatMessage `json:"messages"`
+}
+
+// MessageSQL is the SQL behind a report chat message, stored apart from the message text so it
+// can be looked up and re-run later. The report fields are updated when background execution ends.
+type MessageSQL struct {
+	MessageID      string       `json:"message_id"`
+	UserID         string       `json:"user_id"`
+	SessionID      string       `json:"session_id"`
+	Prompt         string       `json:"prompt"`        // User request the SQL was generated for
+	GeneratedSQL   string       `json:"generated_sql"` // SQL as generated by the model
+	FinalSQL       string       `json:"final_sql"`     // SQL actually executed (report head prepended when needed)
+	Metadata       *SQLMetadata `json:"metadata,omitempty"`
+	Status         string       `json:"status,omitempty"`          // Report status (ReportStatus*)
+	ResultFilename string       `json:"result_filename,omitempty"` // Saved result file, once the query has run
+	HTMLPath       string       `json:"html_path,omitempty"`       // URL path of the HTML page generated from the result
+	RowCount       int          `json:"row_count,omitempty"`
+	Truncated      bool         `json:"truncated,omitempty"` // Rows beyond SQL_MAX_ROWS were dropped
+	Error          string       `json:"error,omitempty"`     // Why the report failed, or why it has no HTML page
+	CreatedAt      string       `json:"created_at"`
+	UpdatedAt      string       `json:"updated_at,omitempty"`
+}
+
+// Report execution states
+const (
+	ReportStatusRunning   = "running"   // SQL runs in the background; result and HTML appear under products
+	ReportStatusCompleted = "completed" // Result file (and HTML page, if generated) are available
+	ReportStatusFailed    = "failed"
+	ReportStatusReview    = "needs_review" // Confidence in the SQL was too low to run it automatically; review it and run it via /api/sql/execute
+)
+
+// ReportResponse describes a report produced from a natural-language request. A running report
+// executes in the background; its outcome (status, result file, HTML page) is available from
+// GET /api/chat/messages/{message_id}/sql.
+type ReportResponse struct {
+	SQL      string       `json:"sql"`
+	Status   string       `json:"status"`
+	Metadata *SQLMetadata `json:"metadata,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// SQLMetadata describes how report SQL was generated.
+type SQLMetadata struct {
+	Model          string   `json:"model"`           // AI model that wrote the SQL
+	ReferenceFiles []string `json:"reference_files"` // Reference SQL files included in the prompt
+	HeadPrepended  bool     `json:"head_prepended"`  // The report head (CTEs) was added in front of the generated SQL
+	ReportHead     string   `json:"report_head"`     // Name of the report head used
+	GeneratedAt    string   `json:"generated_at"`
+	Confidence     int      `json:"confidence"`               // 0-100: share of the tables read that the reference SQL also uses
+	UnknownTables  []string `json:"unknown_tables,omitempty"` // Tables read that no reference SQL uses
+}
+
+// ReportHeadInfo names a report SQL head; Source is "file" (REPORT_HEADS_DIR) or "builtin"
+type ReportHeadInfo struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// ReportHeadsResponse lists the available report heads
+type ReportHeadsResponse struct {
+	Default string           `json:"default"`
+	Heads   []ReportHeadInfo `json:"heads"`
 }
 
 // ProposedFormCard is sent when a form is generated from document upload; user must confirm before saving.
@@ -44,6 +134,35 @@ type ProposedFormCard struct {
 	FormTemplate FormTemplate `json:"form_template"`
 }
 
+// DocumentExtraction is a stored reader result for an uploaded document, keyed by content hash.
+type DocumentExtraction struct {
+	Hash          string `json:"hash"`
+	Filename      string `json:"filename"`
+	ContentType   string `json:"content_type"`
+	ExtractedText string `json:"extracted_text"`
+	AIResult      string `json:"ai_result"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// SQLResultCacheEntry points identical SQL (by hash) at the result file of an earlier run.
+type SQLResultCacheEntry struct {
+	Hash     string `json:"hash"`
+	Format   string `json:"format"`
+	Filename string `json:"filename"`
+	CachedAt string `json:"cached_at"`
+}
+
+// FormProposal records a form proposed in chat so it can be revisited if the user didn't confirm it.
+type FormProposal struct {
+	ID          string       `json:"id"`
+	UserID      string       `json:"user_id"`
+	Status      string       `json:"status"`                  // "proposed" | "saved" | "discarded"
+	Form        FormTemplate `json:"form"`                    // latest version of the proposed form
+	SavedFormID string       `json:"saved_form_id,omitempty"` // template ID once saved
+	CreatedAt   string       `json:"created_at"`
+	UpdatedAt   string       `json:"updated_at"`
+}
+
 // RegistrationConfirmationCard is sent so the chat UI can show a review card before submitting.
 type RegistrationConfirmationCard struct {
 	FormName  string                   `json:"form_name"`
@@ -64,10 +183,13 @@ type ChatHistory struct {
 }
 
 type SQLResult struct {
-	Columns  []string        `json:"columns"`
-	Rows     [][]interface{} `json:"rows"`
-	Error    string          `json:"error,omitempty"`
-	Filename string          `json:"filename,omitempty"`
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	Error     string          `json:"error,omitempty"`
+	Filename  string          `json:"filename,omitempty"`
+	Partial   bool            `json:"partial,omitempty"`   // Rows were collected before the query failed
+	Truncated bool            `json:"truncated,omitempty"` // Rows beyond the SQL_MAX_ROWS cap were dropped
+	Cached    bool            `json:"cached,omitempty"`    // Served from a previous run's result file
 }
 
 type ResultFile struct {
@@ -78,29 +200,108 @@ type ResultFile struct {
 	Rows      [][]interface{} `json:"rows"`
 	RowCount  int           `json:"row_count"`
 	Error     string        `json:"error,omitempty"`
+	Truncated bool          `json:"truncated,omitempty"`
+}
+
+// ResultFileObjects is a result file with each row keyed by column name (?shape=objects).
+// Columns keeps the column order, which JSON objects don't.
+type ResultFileObjects struct {
+	Filename  string                   `json:"filename"`
+	Query     string                   `json:"query,omitempty"`
+	Timestamp string                   `json:"timestamp"`
+	Columns   []string                 `json:"columns"`
+	Rows      []map[string]interface{} `json:"rows"`
+	RowCount  int                      `json:"row_count"`
+	Error     string                   `json:"error,omitempty"`
+	Truncated bool                     `json:"truncated,omitempty"`
 }
 
 type ResultFileInfo struct {
+	Filename string   `json:"filename"`
+	Size     int64    `json:"size"`
+	Modified string   `json:"modified"`
+	Format   string   `json:"format"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// TagsRequest replaces the tags of a result file or form template; an empty list clears them
+type TagsRequest struct {
+	Tags []string `json:"tags" example:"finance,monthly"`
+}
+
+type DeleteResultFilesRequest struct {
+	Filenames []string `json:"filenames" binding:"required"`
+}
+
+// ResultDiffRequest names two result files to compare and the column identifying a row
+type ResultDiffRequest struct {
+	Old       string `json:"old" binding:"required"`        // Earlier result file, e.g. last week's report
+	New       string `json:"new" binding:"required"`        // Later result file
+	KeyColumn string `json:"key_column" binding:"required"` // Column whose value identifies a row in both files; must be unique
+}
+
+// ResultDiff is the row-level difference between two result files. Added and Removed rows are
+// {column: value} objects; changed rows list only the columns whose values differ.
+type ResultDiff struct {
+	Old            string                   `json:"old"`
+	New            string                   `json:"new"`
+	KeyColumn      string                   `json:"key_column"`
+	ColumnsAdded   []string                 `json:"columns_added,omitempty"`   // Columns only in New (not compared)
+	ColumnsRemoved []string                 `json:"columns_removed,omitempty"` // Columns only in Old (not compared)
+	Added          []map[string]interface{} `json:"added"`
+	Removed        []map[string]interface{} `json:"removed"`
+	Changed        []ResultRowChange        `json:"changed"`
+	Unchanged      int                      `json:"unchanged"`
+}
+
+// ResultRowChange is a row present in both files whose values differ
+type ResultRowChange struct {
+	Key     interface{}                  `json:"key"`
+	Changes map[string]ResultValueChange `json:"changes"` // Column -> old and new value
+}
+
+type ResultValueChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// DeleteResultFileStatus is the outcome of deleting one file in a batch delete
+type DeleteResultFileStatus struct {
 	Filename string `json:"filename"`
-	Size     int64  `json:"size"`
-	Modified string `json:"modified"`
-	Format   string `json:"format"`
+	Deleted  bool   `json:"deleted"`
+	Error    string `json:"error,omitempty"`
 }
 
 type GenerateHTMLRequest struct {
-	Filename string `json:"filename"`
-	Title    string `json:"title,omitempty"`
+	Filename    string       `json:"filename"`
+	Title       string       `json:"title,omitempty"`
+	Theme       string       `json:"theme,omitempty" example:"dark"` // "light" (default), "dark" or "branded"
+	ThemeColors *ThemeColors `json:"theme_colors,omitempty"`         // Colors for the "branded" theme
+}
+
+// ThemeColors are CSS colors for a branded result page; empty values fall back to light theme defaults
+type ThemeColors struct {
+	Primary    string `json:"primary,omitempty" example:"#0055A4"`
+	Background string `json:"background,omitempty" example:"#FFFFFF"`
+	Text       string `json:"text,omitempty" example:"#222222"`
+}
+
+// HTMLTheme selects the color scheme of a generated result page
+type HTMLTheme struct {
+	Name   string // "light", "dark" or "branded"
+	Colors ThemeColors
 }
 
 // Complaint flow models
 type ComplaintState struct {
-	ConversationID string                 `json:"conversation_id"`
-	Step           string                 `json:"step"` // "start", "dialogue", "waiting_complaint", "executing", "complete"
-	ComplaintText  string                 `json:"complaint_text,omitempty"`
-	DialogueResult map[string]interface{} `json:"dialogue_result,omitempty"`
-	InitialData    map[string]interface{} `json:"initial_data,omitempty"`
-	ExchangeCount  int                    `json:"exchange_count"` // Track number of exchanges
-	LastResponse   string                 `json:"last_response,omitempty"` // Store last AI response
+	ConversationID  string                 `json:"conversation_id"`
+	Step            string                 `json:"step"`                       // "start", "dialogue", "waiting_complaint", "executing", "complete", "failed"
+	ComplaintText   string                 `json:"complaint_text,omitempty"`
+	DialogueResult  map[string]interface{} `json:"dialogue_result,omitempty"`
+	InitialData     map[string]interface{} `json:"initial_data,omitempty"`
+	ExchangeCount   int                    `json:"exchange_count"`             // Track number of exchanges
+	LastResponse    string                 `json:"last_response,omitempty"`    // Store last AI response
+	ExecuteAttempts int                    `json:"execute_attempts,omitempty"` // Failed submissions of the finished dialogue
 }
 
 // Voice recognition models
@@ -118,9 +319,16 @@ type VoiceRegistrationRequest struct {
 	AudioFormat string `json:"audio_format"` // "wav", "mp3", "webm", etc.
 }
 
+// VoiceProfileMergeRequest moves the samples of SourceUserID into TargetUserID's profile
+type VoiceProfileMergeRequest struct {
+	TargetUserID string `json:"target_user_id" binding:"required"` // Profile that is kept
+	SourceUserID string `json:"source_user_id" binding:"required"` // Profile that is merged in and deleted
+}
+
 type VoiceRecognitionRequest struct {
 	AudioData   string `json:"audio_data" binding:"required"` // Base64 encoded audio
 	AudioFormat string `json:"audio_format"` // "wav", "mp3", "webm", etc.
+	DryRun      bool   `json:"dry_run"`      // Recognize only; don't log attendance (for UI testing and calibration)
 }
 
 type VoiceRecognitionResponse struct {
@@ -130,6 +338,42 @@ type VoiceRecognitionResponse struct {
 	Transcript string `json:"transcript,omitempty"`
 	Intent     string `json:"intent,omitempty"` // "attendance", "punch_in", etc.
 	Message    string `json:"message"`
+	Reason     string `json:"reason,omitempty"` // Why recognition failed (VoiceReason*)
+}
+
+// Reasons a voice was not recognized
+const (
+	VoiceReasonNoProfiles = "no_profiles" // Nobody has registered a voice yet
+	VoiceReasonNoSamples  = "no_samples"  // Profiles exist but none of their sample files could be read
+	VoiceReasonNoMatch    = "no_match"    // Samples were compared and none matched
+)
+
+// AttendanceRecord is one recognized voice check-in.
+type AttendanceRecord struct {
+	UserID    string `json:"user_id"`
+	Name      string `json:"name"`
+	Intent    string `json:"intent"`
+	Date      string `json:"date"` // Local calendar day, YYYY-MM-DD
+	Timestamp string `json:"timestamp"`
+}
+
+// AttendanceSummaryEntry is one person present on a day.
+type AttendanceSummaryEntry struct {
+	UserID    string `json:"user_id"`
+	Name      string `json:"name"`
+	FirstSeen string `json:"first_seen"`
+	LastSeen  string `json:"last_seen"`
+	CheckIns  int    `json:"check_ins"`
+}
+
+// AttendanceSummary is the daily attendance roll.
+type AttendanceSummary struct {
+	Date            string                   `json:"date"`
+	Present         []AttendanceSummaryEntry `json:"present"`
+	Absent          []VoiceProfile           `json:"absent"` // Registered voice profiles with no check-in that day
+	PresentCount    int                      `json:"present_count"`
+	AbsentCount     int                      `json:"absent_count"`
+	RegisteredCount int                      `json:"registered_count"`
 }
 
 // Form system models
@@ -147,7 +391,10 @@ type FormTemplate struct {
 	Name        string     `json:"name"`         // Form name (e.g., "Student Registration Form")
 	Description string     `json:"description"`  // Form description
 	UserType    string     `json:"user_type"`    // "student" or "staff"
+	Public      bool       `json:"public"`       // Public surveys accept anonymous answers
 	Fields      []FormField `json:"fields"`      // Form fields
+	Tags        []string   `json:"tags,omitempty"` // Labels for organizing templates
+	IdentityField string   `json:"identity_field,omitempty"` // Field whose answer is the ID of the person registered (e.g. "student_id"); answers otherwise belong to the submitter
 	CreatedAt   string     `json:"created_at"`   // Creation timestamp
 	UpdatedAt   string     `json:"updated_at"`   // Last update timestamp
 	CreatedBy   string     `json:"created_by"`   // User who created the form
@@ -177,9 +424,16 @@ type RegistrationState struct {
 	FormName          string                 `json:"form_name,omitempty"`  // form name for context
 	UserType          string                 `json:"user_type,omitempty"`  // student | staff from form
 	GatheredAnswers   map[string]interface{} `json:"gathered_answers"`    // field name -> value so far
-	ConversationHistory []RegConvTurn        `json:"conversation_history"` // full chat history for this session
+	ConversationHistory []RegConvTurn        `json:"conversation_history"` // recent chat history for this session (trimmed to the configured max)
 	LastAIResponse    string                 `json:"last_ai_response,omitempty"`
 	ExchangeCount     int                    `json:"exchange_count"`
 	CreatedAt         string                 `json:"created_at,omitempty"`
 }
 
+// ProductFileInfo represents information about a product HTML file
+type ProductFileInfo struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	Modified string `json:"modified"`
+	Type     string `json:"type"` // "form" or "result"
+}
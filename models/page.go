@@ -0,0 +1,27 @@
+package models
+
+// Page is the response envelope of paged list endpoints. Total counts the matching items
+// before paging; a Limit of 0 means no limit.
+type Page[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// NewPage returns the page of items starting at offset with at most limit items (all when 0).
+// An offset past the end yields an empty page.
+func NewPage[T any](items []T, limit, offset int) Page[T] {
+	total := len(items)
+	if offset > total {
+		offset = total
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	if items == nil {
+		items = []T{}
+	}
+	return Page[T]{Items: items, Total: total, Limit: limit, Offset: offset}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Stored timestamps are RFC3339 strings in UTC, so they compare the same as strings and as times.
+// Unix time is only used inside Badger keys, where it keeps entries in chronological order.
+
+// Timestamp formats t for storage
+func Timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// NowTimestamp returns the current time formatted for storage
+func NowTimestamp() string {
+	return Timestamp(time.Now())
+}
+
+// ParseTimestamp reads a stored timestamp. RFC3339 with any offset is accepted, as are the
+// unix-seconds strings older chat history used. Unparseable values yield the zero time.
+func ParseTimestamp(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0).UTC()
+	}
+	return time.Time{}
+}
+
+// TimestampAfter reports whether stored timestamp a is later than b
+func TimestampAfter(a, b string) bool {
+	return ParseTimestamp(a).After(ParseTimestamp(b))
+}
+
+// OrderKey returns a fixed-width key component that sorts in chronological order
+func OrderKey(t time.Time) string {
+	return fmt.Sprintf("%020d", t.UnixNano())
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,15 +13,43 @@ import (
 
 const ComplaintAPIBaseURL = "http://192.168.9.136:8000"
 
+// UpstreamStatusError is a non-200 response from the complaint API
+type UpstreamStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *UpstreamStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+// ErrNoConversationID is returned by StartDialogue when the upstream response has no conversation_id
+var ErrNoConversationID = errors.New("conversation_id not found in response")
+
 type ComplaintService struct {
 	httpClient *http.Client
+	nResults   int
 }
 
-func NewComplaintService() *ComplaintService {
+// defaultComplaintNResults is the number of candidate forms the dialogue considers when none is configured
+const defaultComplaintNResults = 3
+
+// NewComplaintService creates the complaint API client. nResults is the n_results sent when a
+// dialogue starts (how many candidate forms it considers); values below 1 use the default of 3.
+func NewComplaintService(nResults int) *ComplaintService {
+	return NewComplaintServiceWithClient(nResults, &http.Client{
+		Timeout: 30 * time.Second,
+	})
+}
+
+// NewComplaintServiceWithClient is NewComplaintService sending its requests through httpClient.
+func NewComplaintServiceWithClient(nResults int, httpClient *http.Client) *ComplaintService {
+	if nResults < 1 {
+		nResults = defaultComplaintNResults
+	}
 	return &ComplaintService{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient: httpClient,
+		nResults:   nResults,
 	}
 }
 
@@ -69,7 +98,7 @@ func (s *ComplaintService) InitializeProcess() (*InitializeResponse, error) {
 	log.Printf("[COMPLAINT STEP 1] Response Body: %s", string(body))
 	
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, &UpstreamStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	
 	// Parse response to extract initial_data
@@ -108,7 +137,7 @@ func (s *ComplaintService) StartDialogue(initialMessage string) (*StartDialogueR
 	
 	reqBody := StartDialogueRequest{
 		InitialMessage: initialMessage,
-		NResults:       3, // As per new API specification
+		NResults:       s.nResults,
 	}
 	
 	jsonData, err := json.Marshal(reqBody)
@@ -142,7 +171,7 @@ func (s *ComplaintService) StartDialogue(initialMessage string) (*StartDialogueR
 	log.Printf("[COMPLAINT STEP 2] Response Body: %s", string(body))
 	
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, &UpstreamStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	
 	// Always parse as raw JSON first to extract all fields
@@ -184,7 +213,7 @@ func (s *ComplaintService) StartDialogue(initialMessage string) (*StartDialogueR
 	log.Printf("[COMPLAINT STEP 2] Parsed - ConversationID: '%s', Response length: %d", result.ConversationID, len(result.Response))
 	if result.ConversationID == "" {
 		log.Printf("[COMPLAINT STEP 2] ERROR: conversationID is still empty after parsing!")
-		return nil, fmt.Errorf("conversation_id not found in response")
+		return nil, ErrNoConversationID
 	}
 	
 	return &result, nil
@@ -261,7 +290,7 @@ func (s *ComplaintService) ContinueDialogue(conversationID, userMessage string)
 	log.Printf("[COMPLAINT CONTINUE] Response Body: %s", string(body))
 	
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, &UpstreamStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	
 	var rawResp map[string]interface{}
@@ -356,7 +385,7 @@ func (s *ComplaintService) GetDialogueInfo() ([]DialogueInfo, error) {
 	log.Printf("[COMPLAINT STEP 5] Response Body: %s", string(body))
 	
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, &UpstreamStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	
 	var dialogues []DialogueInfo
@@ -405,7 +434,7 @@ func (s *ComplaintService) ExecuteWithResponseBody(responseBody map[string]inter
 	log.Printf("[COMPLAINT EXECUTE] Response Body: %s", string(body))
 	
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, &UpstreamStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	
 	var result ExecuteResponse
@@ -482,7 +511,7 @@ func (s *ComplaintService) ExecuteWithDialogueResult(dialogueResult map[string]i
 	log.Printf("[COMPLAINT EXECUTE] Response Body: %s", string(body))
 	
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, &UpstreamStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	
 	var result ExecuteResponse
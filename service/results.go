@@ -4,8 +4,11 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"idongivaflyinfa/models"
@@ -38,19 +41,80 @@ func (r *ResultsStorage) GenerateFileName(format string) string {
 	return fmt.Sprintf("result_%s_%d.%s", timestamp, nanos, format)
 }
 
-// SaveResultAsJSON saves SQL result as JSON file
-func (r *ResultsStorage) SaveResultAsJSON(result *models.SQLResult, query string) (string, error) {
-	filename := r.GenerateFileName("json")
-	filePath := filepath.Join(r.resultsDir, filename)
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// maxResultNameLength caps the user-provided part of a result filename
+const maxResultNameLength = 80
+
+// resultBaseName sanitizes a user-provided result name ("Q1 attendance report" -> "Q1_attendance_report");
+// "" means no usable name was given
+func resultBaseName(name string) string {
+	base := strings.Trim(unsafeNameChars.ReplaceAllString(strings.TrimSpace(name), "_"), "_")
+	if len(base) > maxResultNameLength {
+		base = base[:maxResultNameLength]
+	}
+	return base
+}
+
+// writeResultFile writes a result file atomically: the content goes to a temporary file in the
+// results directory (skipped by ListResultFiles) which is then moved into place, so listings and
+// reads never see a partially written result. Without a name the file gets a generated name;
+// named files never overwrite an existing result and get _2, _3, ... if the name is taken.
+func (r *ResultsStorage) writeResultFile(name, format string, write func(w io.Writer) error) (string, error) {
+	tmp, err := os.CreateTemp(r.resultsDir, ".result-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s file: %w", format, err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s file: %w", format, err)
+	}
 
+	base := resultBaseName(name)
+	if base == "" {
+		filename := r.GenerateFileName(format)
+		if err := os.Rename(tmpPath, filepath.Join(r.resultsDir, filename)); err != nil {
+			return "", fmt.Errorf("failed to save %s file: %w", format, err)
+		}
+		return filename, nil
+	}
+	for n := 1; ; n++ {
+		filename := fmt.Sprintf("%s.%s", base, format)
+		if n > 1 {
+			filename = fmt.Sprintf("%s_%d.%s", base, n, format)
+		}
+		// Link, unlike rename, fails if the target exists, so a taken name is never replaced
+		err := os.Link(tmpPath, filepath.Join(r.resultsDir, filename))
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to save %s file: %w", format, err)
+		}
+		return filename, nil
+	}
+}
+
+// SaveResultAsJSON saves SQL result as JSON file, named after name if given
+func (r *ResultsStorage) SaveResultAsJSON(result *models.SQLResult, query string, name string) (string, error) {
 	// Create result metadata
 	resultData := models.ResultFile{
 		Query:     query,
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: models.NowTimestamp(),
 		Columns:   result.Columns,
 		Rows:      result.Rows,
 		RowCount:  len(result.Rows),
 		Error:     result.Error,
+		Truncated: result.Truncated,
 	}
 
 	data, err := json.MarshalIndent(resultData, "", "  ")
@@ -58,48 +122,41 @@ func (r *ResultsStorage) SaveResultAsJSON(result *models.SQLResult, query string
 		return "", fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to write JSON file: %w", err)
-	}
-
-	return filename, nil
+	return r.writeResultFile(name, "json", func(w io.Writer) error {
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to write JSON file: %w", err)
+		}
+		return nil
+	})
 }
 
-// SaveResultAsCSV saves SQL result as CSV file
-func (r *ResultsStorage) SaveResultAsCSV(result *models.SQLResult, query string) (string, error) {
-	filename := r.GenerateFileName("csv")
-	filePath := filepath.Join(r.resultsDir, filename)
+// SaveResultAsCSV saves SQL result as CSV file, named after name if given
+func (r *ResultsStorage) SaveResultAsCSV(result *models.SQLResult, query string, name string) (string, error) {
+	return r.writeResultFile(name, "csv", func(w io.Writer) error {
+		writer := csv.NewWriter(w)
 
-	file, err := os.Create(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create CSV file: %w", err)
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header
-	if err := writer.Write(result.Columns); err != nil {
-		return "", fmt.Errorf("failed to write CSV header: %w", err)
-	}
+		// Write header
+		if err := writer.Write(result.Columns); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
 
-	// Write rows
-	for _, row := range result.Rows {
-		record := make([]string, len(row))
-		for i, val := range row {
-			if val == nil {
-				record[i] = ""
-			} else {
-				record[i] = fmt.Sprintf("%v", val)
+		// Write rows
+		for _, row := range result.Rows {
+			record := make([]string, len(row))
+			for i, val := range row {
+				record[i] = displayValue(val)
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write CSV row: %w", err)
 			}
 		}
-		if err := writer.Write(record); err != nil {
-			return "", fmt.Errorf("failed to write CSV row: %w", err)
-		}
-	}
 
-	return filename, nil
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("failed to write CSV file: %w", err)
+		}
+		return nil
+	})
 }
 
 // GetResultFile reads a result file
@@ -141,7 +198,7 @@ func (r *ResultsStorage) GetResultFile(filename string) (*models.ResultFile, err
 				Columns:   []string{},
 				Rows:      [][]interface{}{},
 				RowCount:  0,
-				Timestamp: time.Now().Format(time.RFC3339),
+				Timestamp: models.NowTimestamp(),
 			}, nil
 		}
 
@@ -162,7 +219,7 @@ func (r *ResultsStorage) GetResultFile(filename string) (*models.ResultFile, err
 			Columns:   columns,
 			Rows:      rows,
 			RowCount:  len(rows),
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: models.NowTimestamp(),
 		}, nil
 	}
 
@@ -195,7 +252,7 @@ func (r *ResultsStorage) ListResultFiles() ([]models.ResultFileInfo, error) {
 		resultFiles = append(resultFiles, models.ResultFileInfo{
 			Filename:    file.Name(),
 			Size:        info.Size(),
-			Modified:    info.ModTime().Format(time.RFC3339),
+			Modified:    models.Timestamp(info.ModTime()),
 			Format:      ext[1:], // Remove the dot
 		})
 	}
@@ -203,6 +260,25 @@ func (r *ResultsStorage) ListResultFiles() ([]models.ResultFileInfo, error) {
 	return resultFiles, nil
 }
 
+// DeleteResultFile removes a result file and the HTML page generated from it, if any.
+// The filename must be a bare .json or .csv name; a missing result file yields an os.ErrNotExist error.
+func (r *ResultsStorage) DeleteResultFile(filename string) error {
+	ext := filepath.Ext(filename)
+	if filename == "" || filepath.Base(filename) != filename || (ext != ".json" && ext != ".csv") {
+		return fmt.Errorf("invalid result filename")
+	}
+
+	if err := os.Remove(filepath.Join(r.resultsDir, filename)); err != nil {
+		return fmt.Errorf("failed to delete result file: %w", err)
+	}
+
+	htmlPath := r.GetHTMLFilePath(filename[:len(filename)-len(ext)])
+	if err := os.Remove(htmlPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete HTML file: %w", err)
+	}
+	return nil
+}
+
 // GetResultFilePath returns the full path to a result file
 func (r *ResultsStorage) GetResultFilePath(filename string) string {
 	return filepath.Join(r.resultsDir, filename)
@@ -217,7 +293,7 @@ func (r *ResultsStorage) SaveHTMLFile(filename string, content []byte) (string,
 	
 	filePath := filepath.Join(r.sitesDir, filename)
 	
-	if err := os.WriteFile(filePath, content, 0644); err != nil {
+	if err := WriteFileAtomic(filePath, content, 0644); err != nil {
 		return "", fmt.Errorf("failed to write HTML file: %w", err)
 	}
 	
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"idongivaflyinfa/models"
+)
+
+// ErrInvalidResultDiff is returned (wrapped) when two result files can't be diffed as requested:
+// a bad filename, or a key column that is missing or not unique
+var ErrInvalidResultDiff = errors.New("invalid result diff")
+
+// DiffResults compares result file b against result file a, matching rows by keyColumn.
+// Rows only in b are added, rows only in a are removed, and rows whose values differ in a column
+// both files have are changed. Values are compared as displayed, so a JSON result and a CSV
+// export of it compare equal.
+func (r *ResultsStorage) DiffResults(a, b, keyColumn string) (*models.ResultDiff, error) {
+	for _, name := range []string{a, b} {
+		ext := filepath.Ext(name)
+		if name == "" || filepath.Base(name) != name || (ext != ".json" && ext != ".csv") {
+			return nil, fmt.Errorf("%w: invalid result filename %q", ErrInvalidResultDiff, name)
+		}
+	}
+	oldFile, err := r.GetResultFile(a)
+	if err != nil {
+		return nil, err
+	}
+	newFile, err := r.GetResultFile(b)
+	if err != nil {
+		return nil, err
+	}
+
+	oldRows, err := keyedRows(oldFile, a, keyColumn)
+	if err != nil {
+		return nil, err
+	}
+	newRows, err := keyedRows(newFile, b, keyColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.ResultDiff{
+		Old:       a,
+		New:       b,
+		KeyColumn: keyColumn,
+		Added:     []map[string]interface{}{},
+		Removed:   []map[string]interface{}{},
+		Changed:   []models.ResultRowChange{},
+	}
+	var shared []string
+	for _, col := range newFile.Columns {
+		if columnIndex(oldFile.Columns, col) >= 0 {
+			shared = append(shared, col)
+		} else {
+			diff.ColumnsAdded = append(diff.ColumnsAdded, col)
+		}
+	}
+	for _, col := range oldFile.Columns {
+		if columnIndex(newFile.Columns, col) < 0 {
+			diff.ColumnsRemoved = append(diff.ColumnsRemoved, col)
+		}
+	}
+
+	for _, key := range newRows.order {
+		newRow := newRows.rows[key]
+		oldRow, ok := oldRows.rows[key]
+		if !ok {
+			diff.Added = append(diff.Added, newRow)
+			continue
+		}
+		changes := make(map[string]models.ResultValueChange)
+		for _, col := range shared {
+			oldValue, newValue := oldRow[lookupColumn(oldRow, col)], newRow[col]
+			if displayValue(oldValue) != displayValue(newValue) {
+				changes[col] = models.ResultValueChange{Old: oldValue, New: newValue}
+			}
+		}
+		if len(changes) > 0 {
+			diff.Changed = append(diff.Changed, models.ResultRowChange{Key: newRow[lookupColumn(newRow, keyColumn)], Changes: changes})
+		} else {
+			diff.Unchanged++
+		}
+	}
+	for _, key := range oldRows.order {
+		if _, ok := newRows.rows[key]; !ok {
+			diff.Removed = append(diff.Removed, oldRows.rows[key])
+		}
+	}
+	return diff, nil
+}
+
+// rowIndex holds a result file's rows as column -> value maps, by display key, in file order
+type rowIndex struct {
+	order []string
+	rows  map[string]map[string]interface{}
+}
+
+// keyedRows indexes a result file's rows by keyColumn, which must exist and be unique
+func keyedRows(f *models.ResultFile, filename, keyColumn string) (*rowIndex, error) {
+	keyIdx := columnIndex(f.Columns, keyColumn)
+	if keyIdx < 0 {
+		return nil, fmt.Errorf("%w: key column %q not found in %s", ErrInvalidResultDiff, keyColumn, filename)
+	}
+	index := &rowIndex{rows: make(map[string]map[string]interface{}, len(f.Rows))}
+	for _, row := range f.Rows {
+		obj := make(map[string]interface{}, len(f.Columns))
+		for i, col := range f.Columns {
+			if i < len(row) {
+				obj[col] = row[i]
+			} else {
+				obj[col] = nil
+			}
+		}
+		key := displayValue(obj[f.Columns[keyIdx]])
+		if _, dup := index.rows[key]; dup {
+			return nil, fmt.Errorf("%w: key column %q is not unique in %s (value %q repeats)", ErrInvalidResultDiff, keyColumn, filename, key)
+		}
+		index.rows[key] = obj
+		index.order = append(index.order, key)
+	}
+	return index, nil
+}
+
+// columnIndex finds col in columns, preferring an exact match over a case-insensitive one
+// (SQL Server column names are case-insensitive), or returns -1
+func columnIndex(columns []string, col string) int {
+	fold := -1
+	for i, c := range columns {
+		if c == col {
+			return i
+		}
+		if fold < 0 && strings.EqualFold(c, col) {
+			fold = i
+		}
+	}
+	return fold
+}
+
+// lookupColumn returns the key under which row stores col
+func lookupColumn(row map[string]interface{}, col string) string {
+	if _, ok := row[col]; ok {
+		return col
+	}
+	for c := range row {
+		if strings.EqualFold(c, col) {
+			return c
+		}
+	}
+	return col
+}
+
+// displayValue is the value as shown in a CSV export; nil shows as empty. Floats are written
+// without an exponent, so a whole number read back from a JSON result as float64 shows as
+// the integer a CSV export of the same query holds.
+func displayValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprint(v)
+}
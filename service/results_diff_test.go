@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+
+	"idongivaflyinfa/models"
+)
+
+func TestDiffResultsJSONAgainstCSVExport(t *testing.T) {
+	storage, err := NewResultsStorage(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewResultsStorage: %v", err)
+	}
+	result := &models.SQLResult{
+		Columns: []string{"id", "enrolled", "average", "ratio", "note"},
+		Rows: [][]interface{}{
+			{int64(1), int64(12345678), float64(1000000), float64(0.5), "ok"},
+			{int64(20000001), int64(0), float64(87.25), float32(0.25), nil},
+		},
+	}
+	jsonFile, err := storage.SaveResultAsJSON(result, "SELECT 1", "grades")
+	if err != nil {
+		t.Fatalf("SaveResultAsJSON: %v", err)
+	}
+	csvFile, err := storage.SaveResultAsCSV(result, "SELECT 1", "grades")
+	if err != nil {
+		t.Fatalf("SaveResultAsCSV: %v", err)
+	}
+
+	for _, files := range [][2]string{{jsonFile, csvFile}, {csvFile, jsonFile}} {
+		diff, err := storage.DiffResults(files[0], files[1], "id")
+		if err != nil {
+			t.Fatalf("DiffResults(%s, %s): %v", files[0], files[1], err)
+		}
+		if len(diff.Added) != 0 || len(diff.Removed) != 0 || len(diff.Changed) != 0 {
+			t.Errorf("DiffResults(%s, %s) = %d added, %d removed, %+v changed; want no differences",
+				files[0], files[1], len(diff.Added), len(diff.Removed), diff.Changed)
+		}
+	}
+}
+
+func TestDisplayValue(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, ""},
+		{float64(12345678), "12345678"},
+		{float64(1e21), "1000000000000000000000"},
+		{float64(0.1), "0.1"},
+		{float32(0.1), "0.1"},
+		{int64(42), "42"},
+		{"text", "text"},
+		{true, "true"},
+	}
+	for _, tt := range tests {
+		if got := displayValue(tt.in); got != tt.want {
+			t.Errorf("displayValue(%#v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
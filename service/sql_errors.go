@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+// SQLConnectionError is a login or connectivity failure. Error() is a message safe to show users
+// (it never contains credentials); the driver error is logged and kept for errors.Is/As.
+type SQLConnectionError struct {
+	Message string
+	Err     error
+}
+
+func (e *SQLConnectionError) Error() string { return e.Message }
+
+func (e *SQLConnectionError) Unwrap() error { return e.Err }
+
+// SQL Server error numbers for failures to log in or open the database
+const (
+	sqlErrLoginFailed       = 18456
+	sqlErrUntrustedDomain   = 18452
+	sqlErrAccountLocked     = 18486
+	sqlErrPasswordExpired   = 18487
+	sqlErrPasswordMustReset = 18488
+	sqlErrCannotOpenDB      = 4060
+	sqlErrCannotOpenDefault = 4064
+	sqlErrDBNotFound        = 911
+)
+
+// sqlErrorNumber returns the SQL Server error number carried by err, or 0. The driver's error
+// type exposes SQLErrorNumber so callers need not import it.
+func sqlErrorNumber(err error) int32 {
+	var numbered interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &numbered) {
+		return numbered.SQLErrorNumber()
+	}
+	return 0
+}
+
+// connectionError maps login and connectivity failures to a SQLConnectionError with an actionable
+// message, logging the driver detail. Other errors are returned as is, with the configured
+// password removed should the driver have echoed it.
+func (s *SQLServerService) connectionError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var message string
+	var netErr net.Error
+	lower := strings.ToLower(err.Error())
+	switch number := sqlErrorNumber(err); {
+	case number == sqlErrLoginFailed || number == sqlErrUntrustedDomain:
+		message = "SQL Server rejected the login. Check SQL_USER and SQL_PASSWORD."
+	case number == sqlErrAccountLocked:
+		message = "The SQL Server login is locked out. Ask the database administrator to unlock the SQL_USER account."
+	case number == sqlErrPasswordExpired || number == sqlErrPasswordMustReset:
+		message = "The SQL Server login's password has expired or must be changed. Set a new password and update SQL_PASSWORD."
+	case number == sqlErrCannotOpenDB || number == sqlErrCannotOpenDefault || number == sqlErrDBNotFound:
+		message = fmt.Sprintf("Database %q was not found or the login cannot open it. Check SQL_DATABASE and that SQL_USER has access to it.", s.database)
+	case strings.Contains(lower, "tls handshake"):
+		message = "Could not set up an encrypted connection to SQL Server. Check SQL_ENCRYPT and the server's TLS configuration."
+	case errors.As(err, &netErr) && netErr.Timeout(), strings.Contains(lower, "i/o timeout"):
+		message = fmt.Sprintf("Timed out connecting to SQL Server at %s. Check SQL_SERVER and SQL_PORT and that no firewall blocks the connection.", s.address)
+	case errors.As(err, &netErr), strings.Contains(lower, "unable to open tcp connection"),
+		strings.Contains(lower, "connection refused"), strings.Contains(lower, "no such host"):
+		message = fmt.Sprintf("Cannot reach SQL Server at %s. Check SQL_SERVER and SQL_PORT and that the server is running and accepts TCP connections.", s.address)
+	}
+
+	if s.password != "" && strings.Contains(err.Error(), s.password) {
+		err = errors.New(strings.ReplaceAll(err.Error(), s.password, "***"))
+	}
+	if message == "" {
+		return err
+	}
+	log.Printf("[SQL] Connection error: %v", err)
+	return &SQLConnectionError{Message: message, Err: err}
+}
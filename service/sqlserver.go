@@ -1,13 +1,17 @@
 package service
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"idongivaflyinfa/config"
 	"idongivaflyinfa/models"
+	"idongivaflyinfa/validation"
 
 	_ "github.com/microsoft/go-mssqldb"
 )
@@ -15,13 +19,28 @@ import (
 type SQLServerService struct {
 	db            *sql.DB
 	resultsStorage *ResultsStorage
+	partialResults bool
+	maxRows        int
+	allowedStatements []string
+	isolation         string // SET TRANSACTION ISOLATION LEVEL value; empty keeps the server default
+	address           string // server:port, for connection error messages
+	database          string
+	password          string // only used to keep it out of error messages
 }
 
+// ErrStatementNotAllowed is returned (wrapped) when a query uses a statement type outside SQL_ALLOWED_STATEMENTS
+var ErrStatementNotAllowed = errors.New("SQL statement not allowed")
+
 func NewSQLServerService(cfg config.SQLServerConfig, resultsDir string, sitesDir string) (*SQLServerService, error) {
 	if cfg.Server == "" || cfg.Database == "" {
 		return nil, fmt.Errorf("SQL Server configuration is incomplete")
 	}
 
+	isolation, err := isolationLevel(cfg.ReadIsolation)
+	if err != nil {
+		return nil, err
+	}
+
 	connectionString := buildConnectionString(cfg)
 
 	db, err := sql.Open("sqlserver", connectionString)
@@ -49,6 +68,13 @@ func NewSQLServerService(cfg config.SQLServerConfig, resultsDir string, sitesDir
 	return &SQLServerService{
 		db:            db,
 		resultsStorage: resultsStorage,
+		partialResults: cfg.PartialResults,
+		maxRows:        cfg.MaxRows,
+		allowedStatements: cfg.AllowedStatements,
+		isolation:         isolation,
+		address:           cfg.Server + ":" + cfg.Port,
+		database:          cfg.Database,
+		password:          cfg.Password,
 	}, nil
 }
 
@@ -73,6 +99,41 @@ func buildConnectionString(cfg config.SQLServerConfig) string {
 	return connStr
 }
 
+// isolationLevel maps SQL_READ_ISOLATION to a T-SQL isolation level
+func isolationLevel(setting string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(setting)) {
+	case "":
+		return "", nil
+	case "snapshot":
+		return "SNAPSHOT", nil
+	case "read_uncommitted", "nolock":
+		return "READ UNCOMMITTED", nil
+	default:
+		return "", fmt.Errorf("unsupported SQL_READ_ISOLATION %q (use snapshot or read_uncommitted)", setting)
+	}
+}
+
+// sessionConn takes a dedicated connection with the configured isolation level applied.
+// The release func restores READ COMMITTED before the connection goes back to the pool,
+// since session settings survive on pooled connections.
+func (s *SQLServerService) sessionConn(ctx context.Context) (*sql.Conn, func(), error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, s.connectionError(err)
+	}
+	if s.isolation == "" {
+		return conn, func() { conn.Close() }, nil
+	}
+	if _, err := conn.ExecContext(ctx, "SET TRANSACTION ISOLATION LEVEL "+s.isolation); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to set isolation level %s: %w", s.isolation, err)
+	}
+	return conn, func() {
+		conn.ExecContext(ctx, "SET TRANSACTION ISOLATION LEVEL READ COMMITTED")
+		conn.Close()
+	}, nil
+}
+
 func (s *SQLServerService) Close() error {
 	if s.db != nil {
 		return s.db.Close()
@@ -81,15 +142,32 @@ func (s *SQLServerService) Close() error {
 }
 
 func (s *SQLServerService) ExecuteQuery(query string) (*models.SQLResult, error) {
-	return s.ExecuteQueryWithSave(query, "", false)
+	return s.ExecuteQueryWithSave(query, "", "", false)
 }
 
-func (s *SQLServerService) ExecuteQueryWithSave(query string, format string, save bool) (*models.SQLResult, error) {
+// ExecuteQueryWithSave runs a query and, if save is set, writes the result as a JSON or CSV file.
+// name optionally gives the file a meaningful name instead of a generated one.
+func (s *SQLServerService) ExecuteQueryWithSave(query string, format string, name string, save bool) (*models.SQLResult, error) {
 	if s.db == nil {
 		return nil, fmt.Errorf("SQL Server connection is not initialized")
 	}
 
-	rows, err := s.db.Query(query)
+	if err := s.checkStatements(query); err != nil {
+		return &models.SQLResult{
+			Error: err.Error(),
+		}, err
+	}
+
+	ctx := context.Background()
+	conn, release, err := s.sessionConn(ctx)
+	if err != nil {
+		return &models.SQLResult{
+			Error: err.Error(),
+		}, err
+	}
+	defer release()
+
+	rows, err := conn.QueryContext(ctx, query)
 	if err != nil {
 		return &models.SQLResult{
 			Error: err.Error(),
@@ -97,16 +175,119 @@ func (s *SQLServerService) ExecuteQueryWithSave(query string, format string, sav
 	}
 	defer rows.Close()
 
-	columns, err := rows.Columns()
+	columns, resultRows, truncated, err := scanRows(rows, s.maxRows)
 	if err != nil {
+		return s.failedResult(columns, resultRows, err), err
+	}
+	if truncated {
+		log.Printf("Query result truncated at %d rows (SQL_MAX_ROWS)", s.maxRows)
+	}
+
+	result := &models.SQLResult{
+		Columns:   columns,
+		Rows:      resultRows,
+		Truncated: truncated,
+	}
+
+	// Save result if requested
+	if save && s.resultsStorage != nil {
+		result.Filename = ""
+		if format == "csv" {
+			filename, err := s.resultsStorage.SaveResultAsCSV(result, query, name)
+			if err == nil {
+				result.Filename = filename
+			}
+		} else {
+			// Default to JSON
+			filename, err := s.resultsStorage.SaveResultAsJSON(result, query, name)
+			if err == nil {
+				result.Filename = filename
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// PreviewQuery runs a query capped at limit rows on the server (SET ROWCOUNT, which works for
+// any query shape including CTEs) and returns them without saving a result file. limit is clamped
+// to 1..SQL_MAX_ROWS, since ROWCOUNT 0 would lift the cap entirely. Only SELECT-only queries are
+// previewed: a preview must never change data, even when SQL_ALLOWED_STATEMENTS permits writes.
+func (s *SQLServerService) PreviewQuery(query string, limit int) (*models.SQLResult, error) {
+	if limit < 1 {
+		limit = 1
+	}
+	if s.maxRows > 0 && limit > s.maxRows {
+		limit = s.maxRows
+	}
+
+	if err := s.checkStatements(query); err != nil {
+		return &models.SQLResult{
+			Error: err.Error(),
+		}, err
+	}
+	if verbs := validation.SQLStatementTypes(query); len(verbs) != 1 || verbs[0] != "SELECT" {
+		err := fmt.Errorf("%w: preview runs SELECT queries only", ErrStatementNotAllowed)
 		return &models.SQLResult{
 			Error: err.Error(),
 		}, err
 	}
+	if s.db == nil {
+		return nil, fmt.Errorf("SQL Server connection is not initialized")
+	}
 
-	var resultRows [][]interface{}
+	ctx := context.Background()
+	conn, release, err := s.sessionConn(ctx)
+	if err != nil {
+		return &models.SQLResult{
+			Error: err.Error(),
+		}, err
+	}
+	defer release()
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET ROWCOUNT %d", limit)); err != nil {
+		return &models.SQLResult{
+			Error: err.Error(),
+		}, err
+	}
+	// ROWCOUNT is session state; reset it before the connection goes back to the pool
+	defer conn.ExecContext(ctx, "SET ROWCOUNT 0")
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return &models.SQLResult{
+			Error: err.Error(),
+		}, err
+	}
+	defer rows.Close()
+
+	columns, resultRows, _, err := scanRows(rows, limit)
+	if err != nil {
+		return s.failedResult(columns, resultRows, err), err
+	}
 
+	return &models.SQLResult{
+		Columns: columns,
+		Rows:    resultRows,
+	}, nil
+}
+
+// scanRows reads up to maxRows rows (0 = unlimited) as strings, reporting whether more rows were left unread.
+// On error the columns and rows read so far are returned with it.
+func scanRows(rows *sql.Rows, maxRows int) ([]string, [][]interface{}, bool, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	columns = UniqueColumnNames(columns)
+
+	var resultRows [][]interface{}
 	for rows.Next() {
+		// Safety net for runaway queries: stop materializing rows past the cap
+		if maxRows > 0 && len(resultRows) >= maxRows {
+			return columns, resultRows, true, nil
+		}
+
 		// Create a slice of interface{} to hold the values
 		values := make([]interface{}, len(columns))
 		valuePtrs := make([]interface{}, len(columns))
@@ -116,9 +297,7 @@ func (s *SQLServerService) ExecuteQueryWithSave(query string, format string, sav
 		}
 
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return &models.SQLResult{
-				Error: err.Error(),
-			}, err
+			return columns, resultRows, false, err
 		}
 
 		// Convert []interface{} to proper types
@@ -136,34 +315,62 @@ func (s *SQLServerService) ExecuteQueryWithSave(query string, format string, sav
 	}
 
 	if err := rows.Err(); err != nil {
-		return &models.SQLResult{
-			Error: err.Error(),
-		}, err
+		return columns, resultRows, false, err
 	}
+	return columns, resultRows, false, nil
+}
 
-	result := &models.SQLResult{
-		Columns: columns,
-		Rows:    resultRows,
+// UniqueColumnNames renames repeated column names (e.g. from SELECT * over a join) to
+// name_2, name_3, ... so every column has a distinct key. Comparison ignores case, as SQL Server does.
+func UniqueColumnNames(columns []string) []string {
+	seen := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		seen[strings.ToLower(col)] = true
 	}
-
-	// Save result if requested
-	if save && s.resultsStorage != nil {
-		result.Filename = ""
-		if format == "csv" {
-			filename, err := s.resultsStorage.SaveResultAsCSV(result, query)
-			if err == nil {
-				result.Filename = filename
-			}
-		} else {
-			// Default to JSON
-			filename, err := s.resultsStorage.SaveResultAsJSON(result, query)
-			if err == nil {
-				result.Filename = filename
+	counts := make(map[string]int, len(columns))
+	unique := make([]string, len(columns))
+	for i, col := range columns {
+		key := strings.ToLower(col)
+		counts[key]++
+		if counts[key] == 1 {
+			unique[i] = col
+			continue
+		}
+		for n := counts[key]; ; n++ {
+			candidate := fmt.Sprintf("%s_%d", col, n)
+			if !seen[strings.ToLower(candidate)] {
+				seen[strings.ToLower(candidate)] = true
+				counts[key] = n
+				unique[i] = candidate
+				break
 			}
 		}
 	}
+	return unique
+}
 
-	return result, nil
+// failedResult builds the result for a query that errored after rows were scanned,
+// keeping the rows collected so far when partial results are enabled.
+func (s *SQLServerService) failedResult(columns []string, rows [][]interface{}, err error) *models.SQLResult {
+	if !s.partialResults || len(rows) == 0 {
+		return &models.SQLResult{
+			Error: err.Error(),
+		}
+	}
+	return &models.SQLResult{
+		Columns: columns,
+		Rows:    rows,
+		Error:   err.Error(),
+		Partial: true,
+	}
+}
+
+// checkStatements rejects queries using statement types outside the configured allowlist
+func (s *SQLServerService) checkStatements(query string) error {
+	if err := validation.CheckSQLStatements(query, s.allowedStatements); err != nil {
+		return fmt.Errorf("%w: %v", ErrStatementNotAllowed, err)
+	}
+	return nil
 }
 
 func (s *SQLServerService) GetResultsStorage() *ResultsStorage {
@@ -175,9 +382,13 @@ func (s *SQLServerService) ExecuteNonQuery(query string) (int64, error) {
 		return 0, fmt.Errorf("SQL Server connection is not initialized")
 	}
 
+	if err := s.checkStatements(query); err != nil {
+		return 0, err
+	}
+
 	result, err := s.db.Exec(query)
 	if err != nil {
-		return 0, err
+		return 0, s.connectionError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUniqueColumnNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		want    []string
+	}{
+		{
+			name:    "self-join",
+			columns: []string{"id", "name", "parent_id", "id", "name", "parent_id"},
+			want:    []string{"id", "name", "parent_id", "id_2", "name_2", "parent_id_2"},
+		},
+		{
+			name:    "three-way self-join",
+			columns: []string{"id", "id", "id"},
+			want:    []string{"id", "id_2", "id_3"},
+		},
+		{
+			name:    "case-insensitive collision",
+			columns: []string{"Name", "name", "NAME"},
+			want:    []string{"Name", "name_2", "NAME_3"},
+		},
+		{
+			name:    "existing name_2 is skipped",
+			columns: []string{"name", "name_2", "name"},
+			want:    []string{"name", "name_2", "name_3"},
+		},
+		{
+			name:    "existing suffix in other case",
+			columns: []string{"ID", "Id_2", "id"},
+			want:    []string{"ID", "Id_2", "id_3"},
+		},
+		{
+			name:    "existing suffix after the duplicate",
+			columns: []string{"a", "a", "a_2"},
+			want:    []string{"a", "a_3", "a_2"},
+		},
+		{
+			name:    "no duplicates",
+			columns: []string{"id", "first_name", "last_name"},
+			want:    []string{"id", "first_name", "last_name"},
+		},
+		{
+			name:    "empty",
+			columns: []string{},
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UniqueColumnNames(tt.columns)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("UniqueColumnNames(%q) = %q, want %q", tt.columns, got, tt.want)
+			}
+			seen := make(map[string]bool, len(got))
+			for _, col := range got {
+				if seen[strings.ToLower(col)] {
+					t.Errorf("column %q is not unique in %q", col, got)
+				}
+				seen[strings.ToLower(col)] = true
+			}
+		})
+	}
+}
+
+func TestPreviewQueryRefusesWrites(t *testing.T) {
+	// Writes are allowed for execution, but a preview must not run them
+	s := &SQLServerService{allowedStatements: []string{"SELECT", "INSERT", "UPDATE"}}
+	for _, query := range []string{
+		"INSERT INTO log (msg) VALUES ('x')",
+		"UPDATE students SET grade = 11",
+		"SELECT 1; INSERT INTO log (msg) VALUES ('x')",
+	} {
+		result, err := s.PreviewQuery(query, 5)
+		if !errors.Is(err, ErrStatementNotAllowed) || result == nil || result.Error == "" {
+			t.Errorf("PreviewQuery(%q) = %+v, %v; want ErrStatementNotAllowed", query, result, err)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
 
 	"idongivaflyinfa/models"
 )
@@ -37,25 +38,20 @@ func (v *VoiceService) RegisterVoice(userID, name, audioData, audioFormat string
 		return nil, fmt.Errorf("failed to decode audio data: %w", err)
 	}
 	
-	// Generate filename
-	timestamp := time.Now().Format("20060102_150405")
-	filename := fmt.Sprintf("%s_%s_%s.%s", userID, name, timestamp, audioFormat)
-	filePath := filepath.Join(v.voiceSamplesDir, filename)
-	
-	// Save audio file
-	if err := os.WriteFile(filePath, audioBytes, 0644); err != nil {
-		return nil, fmt.Errorf("failed to save audio file: %w", err)
+	filename, err := v.saveSample(userID, name, audioFormat, audioBytes)
+	if err != nil {
+		return nil, err
 	}
 	
-	log.Printf("[VOICE] Saved voice sample to: %s", filePath)
+	log.Printf("[VOICE] Saved voice sample to: %s", filepath.Join(v.voiceSamplesDir, filename))
 	
 	// Create or update voice profile
 	profile := &models.VoiceProfile{
 		UserID:      userID,
 		Name:        name,
 		VoiceSamples: []string{filename}, // Store filename reference
-		CreatedAt:   time.Now().Format(time.RFC3339),
-		UpdatedAt:   time.Now().Format(time.RFC3339),
+		CreatedAt:   models.NowTimestamp(),
+		UpdatedAt:   models.NowTimestamp(),
 	}
 	
 	return profile, nil
@@ -69,24 +65,83 @@ func (v *VoiceService) AddVoiceSample(profile *models.VoiceProfile, audioData, a
 		return fmt.Errorf("failed to decode audio data: %w", err)
 	}
 	
-	// Generate filename
-	timestamp := time.Now().Format("20060102_150405")
-	filename := fmt.Sprintf("%s_%s_%s.%s", profile.UserID, profile.Name, timestamp, audioFormat)
-	filePath := filepath.Join(v.voiceSamplesDir, filename)
-	
-	// Save audio file
-	if err := os.WriteFile(filePath, audioBytes, 0644); err != nil {
-		return fmt.Errorf("failed to save audio file: %w", err)
+	filename, err := v.saveSample(profile.UserID, profile.Name, audioFormat, audioBytes)
+	if err != nil {
+		return err
 	}
 	
 	// Add to profile
 	profile.VoiceSamples = append(profile.VoiceSamples, filename)
-	profile.UpdatedAt = time.Now().Format(time.RFC3339)
+	profile.UpdatedAt = models.NowTimestamp()
 	
 	log.Printf("[VOICE] Added voice sample to profile: %s", filename)
 	return nil
 }
 
+// saveSample writes an audio sample as <user>_<name>_<timestamp>.<format>. The parts are reduced
+// to letters, digits, '-' and '_' so names with spaces or slashes stay inside the samples folder,
+// and the file is created exclusively with a _2, _3, ... suffix when the name is taken (e.g. two
+// samples in the same second), so samples never overwrite each other.
+func (v *VoiceService) saveSample(userID, name, audioFormat string, audioBytes []byte) (string, error) {
+	timestamp := time.Now().Format("20060102_150405")
+	base := fmt.Sprintf("%s_%s_%s", sampleNamePart(userID, "user"), sampleNamePart(name, "sample"), timestamp)
+	ext := sampleNamePart(strings.ToLower(audioFormat), "audio")
+
+	for n := 1; ; n++ {
+		filename := fmt.Sprintf("%s.%s", base, ext)
+		if n > 1 {
+			filename = fmt.Sprintf("%s_%d.%s", base, n, ext)
+		}
+		f, err := os.OpenFile(filepath.Join(v.voiceSamplesDir, filename), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to save audio file: %w", err)
+		}
+		_, err = f.Write(audioBytes)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(f.Name())
+			return "", fmt.Errorf("failed to save audio file: %w", err)
+		}
+		return filename, nil
+	}
+}
+
+// maxSampleNamePart bounds each part of a sample filename, in characters
+const maxSampleNamePart = 40
+
+// sampleNamePart makes s safe for use in a filename: runs of anything other than letters,
+// digits, '-' and '_' become a single '_'. fallback is used when nothing is left.
+func sampleNamePart(s, fallback string) string {
+	var b strings.Builder
+	count := 0
+	underscore := false
+	for _, r := range strings.TrimSpace(s) {
+		if count == maxSampleNamePart {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			b.WriteRune(r)
+			underscore = r == '_'
+		} else if b.Len() > 0 && !underscore {
+			b.WriteByte('_')
+			underscore = true
+		} else {
+			continue
+		}
+		count++
+	}
+	part := strings.Trim(b.String(), "_")
+	if strings.Trim(part, "-") == "" {
+		return fallback
+	}
+	return part
+}
+
 // RecognizeVoice attempts to recognize a speaker from audio input
 // This is a simplified implementation - in production, you'd use a proper speaker verification service
 func (v *VoiceService) RecognizeVoice(audioData string, profiles []models.VoiceProfile) (*models.VoiceRecognitionResponse, error) {
@@ -102,6 +157,15 @@ func (v *VoiceService) RecognizeVoice(audioData string, profiles []models.VoiceP
 	audioHashStr := hex.EncodeToString(audioHash[:])
 	
 	log.Printf("[VOICE] Recognizing voice, audio hash: %s", audioHashStr)
+
+	if len(profiles) == 0 {
+		log.Printf("[VOICE] No voice profiles registered, nothing to compare against")
+		return &models.VoiceRecognitionResponse{
+			Recognized: false,
+			Message:    "No voices are registered yet. Please register your voice first, then try again.",
+			Reason:     models.VoiceReasonNoProfiles,
+		}, nil
+	}
 	
 	// Simple matching: compare audio hash with stored samples
 	// NOTE: This is a simplified approach. Real speaker verification requires:
@@ -112,6 +176,7 @@ func (v *VoiceService) RecognizeVoice(audioData string, profiles []models.VoiceP
 	// For now, we'll do a basic comparison
 	// In production, replace this with actual speaker verification
 	var matchedProfile *models.VoiceProfile
+	compared, unreadable := 0, 0
 	for i := range profiles {
 		// Load and compare voice samples
 		for _, sampleFile := range profiles[i].VoiceSamples {
@@ -119,8 +184,10 @@ func (v *VoiceService) RecognizeVoice(audioData string, profiles []models.VoiceP
 			sampleBytes, err := os.ReadFile(samplePath)
 			if err != nil {
 				log.Printf("[VOICE] Warning: Failed to read sample %s: %v", sampleFile, err)
+				unreadable++
 				continue
 			}
+			compared++
 			
 			sampleHash := md5.Sum(sampleBytes)
 			sampleHashStr := hex.EncodeToString(sampleHash[:])
@@ -136,10 +203,23 @@ func (v *VoiceService) RecognizeVoice(audioData string, profiles []models.VoiceP
 		}
 	}
 	
+	log.Printf("[VOICE] Compared against %d samples from %d profiles (%d unreadable), matched: %v",
+		compared, len(profiles), unreadable, matchedProfile != nil)
+
 	if matchedProfile == nil {
+		if compared == 0 {
+			return &models.VoiceRecognitionResponse{
+				Recognized: false,
+				Message:    "Voice recognition is unavailable right now: the registered voice samples could not be loaded.",
+				Reason:     models.VoiceReasonNoSamples,
+			}, nil
+		}
+		// Matching compares exact recordings for now, so a new recording rarely matches;
+		// don't tell the speaker they are unknown to the school
 		return &models.VoiceRecognitionResponse{
 			Recognized: false,
-			Message:    "Sorry, you're not in our school.",
+			Message:    "Sorry, we couldn't match your voice to a registered profile. Please try again, or register your voice if you haven't yet.",
+			Reason:     models.VoiceReasonNoMatch,
 		}, nil
 	}
 	
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSampleNamePart(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Jane Doe", "Jane_Doe"},
+		{"  Jane   Doe  ", "Jane_Doe"},
+		{"../../etc/passwd", "etc_passwd"},
+		{`a / b \ c`, "a_b_c"},
+		{"Mr. O'Brien", "Mr_O_Brien"},
+		{"学生 名字", "学生_名字"},
+		{"__x__", "x"},
+		{"user-1", "user-1"},
+		{strings.Repeat("a", 50), strings.Repeat("a", maxSampleNamePart)},
+		{"", "sample"},
+		{"   ", "sample"},
+		{"/// ...", "sample"},
+		{"---", "sample"},
+	}
+	for _, tt := range tests {
+		if got := sampleNamePart(tt.in, "sample"); got != tt.want {
+			t.Errorf("sampleNamePart(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSaveSampleStaysInSamplesDir(t *testing.T) {
+	dir := t.TempDir()
+	v := &VoiceService{voiceSamplesDir: dir}
+
+	filename, err := v.saveSample("../user 1", "My Voice/../../secret", "WAV", []byte("audio"))
+	if err != nil {
+		t.Fatalf("saveSample: %v", err)
+	}
+	if strings.ContainsAny(filename, `/\ `) || !strings.HasSuffix(filename, ".wav") {
+		t.Fatalf("filename %q is not filesystem-safe", filename)
+	}
+	if !strings.HasPrefix(filename, "user_1_My_Voice_secret_") {
+		t.Errorf("filename %q does not start with the sanitized user and name", filename)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filename))
+	if err != nil || string(data) != "audio" {
+		t.Errorf("sample file: %q, %v", data, err)
+	}
+}
+
+func TestSaveSampleConcurrentFilenamesAreUnique(t *testing.T) {
+	dir := t.TempDir()
+	v := &VoiceService{voiceSamplesDir: dir}
+
+	const samples = 25
+	filenames := make([]string, samples)
+	errs := make([]error, samples)
+	var wg sync.WaitGroup
+	for i := 0; i < samples; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			filenames[i], errs[i] = v.saveSample("user-1", "Jane Doe", "wav", []byte(fmt.Sprintf("sample %d", i)))
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, samples)
+	for i, filename := range filenames {
+		if errs[i] != nil {
+			t.Fatalf("saveSample %d: %v", i, errs[i])
+		}
+		if seen[filename] {
+			t.Fatalf("filename %q was returned twice", filename)
+		}
+		seen[filename] = true
+		data, err := os.ReadFile(filepath.Join(dir, filename))
+		if err != nil || string(data) != fmt.Sprintf("sample %d", i) {
+			t.Errorf("%s holds %q, %v; want sample %d", filename, data, err, i)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read samples dir: %v", err)
+	}
+	if len(entries) != samples {
+		t.Errorf("samples dir has %d files, want %d", len(entries), samples)
+	}
+}
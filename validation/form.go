@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FormFieldTypes are the field types the form UI and renderers understand
+var FormFieldTypes = []string{"text", "email", "number", "tel", "date", "select"}
+
+// ValidateFormTemplateJSON checks form template JSON ("name", "description", "user_type", "fields")
+// for structural problems. It returns one message per problem; an empty slice means the form is valid.
+func ValidateFormTemplateJSON(data []byte) []string {
+	var form map[string]interface{}
+	if err := json.Unmarshal(data, &form); err != nil {
+		return []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+
+	var errs []string
+	if name, ok := form["name"].(string); !ok || strings.TrimSpace(name) == "" {
+		errs = append(errs, `"name" is required and must be a non-empty string`)
+	}
+	if desc, ok := form["description"]; ok {
+		if _, isString := desc.(string); !isString {
+			errs = append(errs, `"description" must be a string`)
+		}
+	}
+	if userType, ok := form["user_type"]; ok {
+		s, _ := userType.(string)
+		if s != "student" && s != "staff" && s != "general" {
+			errs = append(errs, `"user_type" must be "student", "staff" or "general"`)
+		}
+	}
+
+	identityField, hasIdentity := form["identity_field"]
+	if hasIdentity {
+		if _, isString := identityField.(string); !isString {
+			errs = append(errs, `"identity_field" must be a string`)
+		}
+	}
+
+	fields, ok := form["fields"].([]interface{})
+	if !ok {
+		return append(errs, `"fields" is required and must be an array`)
+	}
+	if len(fields) == 0 {
+		errs = append(errs, `"fields" must contain at least one field`)
+	}
+
+	seen := make(map[string]bool)
+	for i, raw := range fields {
+		field, ok := raw.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Sprintf("fields[%d] must be an object", i))
+			continue
+		}
+		name, _ := field["name"].(string)
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, fmt.Sprintf(`fields[%d]: "name" is required`, i))
+		} else if seen[name] {
+			errs = append(errs, fmt.Sprintf(`fields[%d]: duplicate field name %q`, i, name))
+		}
+		seen[name] = true
+
+		if label, ok := field["label"].(string); !ok || strings.TrimSpace(label) == "" {
+			errs = append(errs, fmt.Sprintf(`fields[%d]: "label" is required`, i))
+		}
+		fieldType, _ := field["type"].(string)
+		if !isFormFieldType(fieldType) {
+			errs = append(errs, fmt.Sprintf(`fields[%d]: invalid type %q (allowed: %s)`, i, fieldType, strings.Join(FormFieldTypes, ", ")))
+		}
+		if required, ok := field["required"]; ok {
+			if _, isBool := required.(bool); !isBool {
+				errs = append(errs, fmt.Sprintf(`fields[%d]: "required" must be true or false`, i))
+			}
+		}
+		if options, ok := field["options"]; ok && options != nil {
+			if _, isArray := options.([]interface{}); !isArray {
+				errs = append(errs, fmt.Sprintf(`fields[%d]: "options" must be an array`, i))
+			}
+		}
+		if fieldType == "select" {
+			if options, _ := field["options"].([]interface{}); len(options) == 0 {
+				errs = append(errs, fmt.Sprintf(`fields[%d]: select fields need at least one option`, i))
+			}
+		}
+	}
+
+	if s, _ := identityField.(string); s != "" && !seen[s] {
+		errs = append(errs, fmt.Sprintf(`"identity_field" %q is not one of the form's fields`, s))
+	}
+
+	return errs
+}
+
+// isFormFieldType reports whether t is one of FormFieldTypes
+func isFormFieldType(t string) bool {
+	for _, allowed := range FormFieldTypes {
+		if t == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// maxIDLength bounds IDs used as storage keys; UUIDs are 36 characters
+const maxIDLength = 64
+
+// IsValidID reports whether id is safe to use as a storage key: 1-64 characters of
+// letters, digits, '-' or '_'. This keeps path and body IDs from containing the ':' key
+// delimiter or otherwise reaching into other key namespaces.
+func IsValidID(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
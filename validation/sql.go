@@ -0,0 +1,269 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// sqlStatementKeywords are reserved T-SQL keywords that begin a statement. Being reserved, they
+// can't be bare identifiers, so wherever one appears (T-SQL does not require ';' between
+// statements) it starts a statement, apart from the clause uses sqlStatementTypes skips.
+var sqlStatementKeywords = map[string]bool{
+	"SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true,
+	"EXEC": true, "EXECUTE": true, "GRANT": true, "REVOKE": true, "DENY": true,
+	"BACKUP": true, "RESTORE": true, "BULK": true, "DBCC": true, "KILL": true,
+	"SHUTDOWN": true, "USE": true, "DECLARE": true, "SET": true, "WAITFOR": true,
+	"WRITETEXT": true, "UPDATETEXT": true, "READTEXT": true, "RECONFIGURE": true,
+	"BEGIN": true, "COMMIT": true, "ROLLBACK": true, "SAVE": true, "PRINT": true,
+	"RAISERROR": true, "IF": true, "WHILE": true, "GOTO": true, "RETURN": true,
+	"BREAK": true, "CONTINUE": true, "OPEN": true, "FETCH": true, "CLOSE": true,
+	"DEALLOCATE": true, "CHECKPOINT": true, "SETUSER": true, "REVERT": true,
+}
+
+// SQLStatementTypes returns the statement verbs used in a query (upper-case, in order of first use).
+// Each ';'-separated statement is classified by its leading keyword, whatever it is, and reserved
+// statement keywords elsewhere (e.g. "SELECT 1 WAITFOR DELAY '1:00'") add their own statement.
+// Comments, string literals and quoted identifiers are ignored. A leading WITH (common table
+// expressions) is classified by the statements that follow it. SELECT ... INTO is reported as
+// CREATE since it creates a table; EXECUTE is reported as EXEC.
+func SQLStatementTypes(query string) []string {
+	var verbs []string
+	seen := make(map[string]bool)
+	add := func(verb string) {
+		if verb == "EXECUTE" {
+			verb = "EXEC"
+		}
+		if !seen[verb] {
+			seen[verb] = true
+			verbs = append(verbs, verb)
+		}
+	}
+
+	statementStart := true
+	current := "" // verb of the statement being scanned
+	prev := ""
+	toks := sqlTokens(query)
+	for i, tok := range toks {
+		if tok.text == ";" {
+			statementStart, current, prev = true, "", ""
+			continue
+		}
+		word := !tok.quoted && isSQLWord(tok.text)
+		if statementStart {
+			if tok.text == "(" && !tok.quoted {
+				continue
+			}
+			statementStart = false
+			if !word {
+				// Not a statement at all (e.g. a bare literal); classify it so it is never allowed
+				add(tok.text)
+				prev = tok.text
+				continue
+			}
+			upper := strings.ToUpper(tok.text)
+			if upper != "WITH" {
+				add(upper)
+				current = upper
+			}
+			prev = upper
+			continue
+		}
+		if !word {
+			prev = tok.text
+			continue
+		}
+		upper := strings.ToUpper(tok.text)
+		next := ""
+		if i+1 < len(toks) {
+			next = strings.ToUpper(toks[i+1].text)
+		}
+		switch {
+		case upper == "INTO" && prev != "INSERT" && prev != "MERGE":
+			add("CREATE")
+		case (upper == "DISABLE" || upper == "ENABLE") && next == "TRIGGER":
+			add(upper)
+			current = upper
+		case !sqlStatementKeywords[upper]:
+		case prev == "ON" && (upper == "DELETE" || upper == "UPDATE"):
+			// ON DELETE / ON UPDATE referential actions of CREATE/ALTER TABLE
+		case upper == "FETCH" && (prev == "ROWS" || prev == "ROW"):
+			// OFFSET ... ROWS FETCH NEXT ... ROWS ONLY
+		case upper == "SET" && (current == "UPDATE" || current == "MERGE" || current == "ALTER" || current == "CREATE"):
+			// UPDATE ... SET, ALTER ... SET, ON DELETE SET NULL
+		default:
+			add(upper)
+			current = upper
+		}
+		prev = upper
+	}
+	return verbs
+}
+
+// isSQLWord reports whether a token is a bare keyword or identifier (not a number, literal or symbol)
+func isSQLWord(text string) bool {
+	for _, r := range text {
+		return unicode.IsLetter(r) || r == '_'
+	}
+	return false
+}
+
+// CheckSQLStatements returns an error if the query uses a statement type not in allowed
+// (case-insensitive, e.g. "SELECT", "INSERT"), or contains no recognizable statement.
+// Statements of any other type, known or not, are rejected.
+func CheckSQLStatements(query string, allowed []string) error {
+	verbs := SQLStatementTypes(query)
+	if len(verbs) == 0 {
+		return fmt.Errorf("no recognizable SQL statement found")
+	}
+	permitted := make(map[string]bool, len(allowed))
+	for _, a := range allowed {
+		a = strings.ToUpper(strings.TrimSpace(a))
+		if a == "EXECUTE" {
+			a = "EXEC"
+		}
+		permitted[a] = true
+	}
+	for _, verb := range verbs {
+		if !permitted[verb] {
+			return fmt.Errorf("%s statements are not allowed", verb)
+		}
+	}
+	return nil
+}
+
+// sqlToken is a word, identifier or single punctuation character of a query
+type sqlToken struct {
+	text   string
+	quoted bool // [bracketed] or "quoted" identifier, never a keyword
+}
+
+// sqlTokens splits a query into tokens, skipping comments. String literals become a single "'" token.
+func sqlTokens(query string) []sqlToken {
+	var toks []sqlToken
+	runes := []rune(query)
+	n := len(runes)
+	for i := 0; i < n; {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < n && runes[i+1] == '-':
+			for i < n && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < n && runes[i+1] == '*':
+			i += 2
+			for i < n && !(runes[i] == '*' && i+1 < n && runes[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '\'' || r == '"' || r == '[':
+			closing := r
+			if r == '[' {
+				closing = ']'
+			}
+			var b strings.Builder
+			i++
+			for i < n {
+				if runes[i] == closing {
+					if i+1 < n && runes[i+1] == closing {
+						b.WriteRune(closing)
+						i += 2
+						continue
+					}
+					break
+				}
+				b.WriteRune(runes[i])
+				i++
+			}
+			i++
+			if r == '\'' {
+				toks = append(toks, sqlToken{text: "'"})
+			} else {
+				toks = append(toks, sqlToken{text: b.String(), quoted: true})
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '@' || r == '#':
+			start := i
+			for i < n && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '@' || runes[i] == '#' || runes[i] == '$') {
+				i++
+			}
+			toks = append(toks, sqlToken{text: string(runes[start:i])})
+		default:
+			toks = append(toks, sqlToken{text: string(r)})
+			i++
+		}
+	}
+	return toks
+}
+
+// sqlClauseKeywords end a table reference, so they are never taken for a table alias
+var sqlClauseKeywords = map[string]bool{
+	"WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"CROSS": true, "OUTER": true, "ON": true, "GROUP": true, "ORDER": true, "HAVING": true,
+	"UNION": true, "EXCEPT": true, "INTERSECT": true, "WITH": true, "OPTION": true,
+	"SELECT": true, "SET": true, "APPLY": true, "PIVOT": true, "UNPIVOT": true, "FOR": true,
+}
+
+// SQLTableReferences returns the tables a query reads (names after FROM and JOIN, lower-case,
+// without schema or brackets) and the common table expressions it defines. Variables (@t),
+// temporary tables (#t) and subqueries are not reported.
+func SQLTableReferences(query string) (tables []string, ctes []string) {
+	toks := sqlTokens(query)
+	keyword := func(i int, k string) bool {
+		return i >= 0 && i < len(toks) && !toks[i].quoted && strings.EqualFold(toks[i].text, k)
+	}
+	isName := func(i int) bool {
+		if i < 0 || i >= len(toks) {
+			return false
+		}
+		if toks[i].quoted {
+			return true
+		}
+		r := []rune(toks[i].text)[0]
+		return unicode.IsLetter(r) || r == '_' || r == '@' || r == '#'
+	}
+	punct := func(i int, p string) bool {
+		return i >= 0 && i < len(toks) && !toks[i].quoted && toks[i].text == p
+	}
+	seenTable, seenCTE := make(map[string]bool), make(map[string]bool)
+
+	for i := range toks {
+		// name AS ( directly after WITH or a comma defines a CTE
+		if isName(i) && keyword(i+1, "AS") && punct(i+2, "(") && (keyword(i-1, "WITH") || punct(i-1, ",")) {
+			if name := strings.ToLower(toks[i].text); !seenCTE[name] {
+				seenCTE[name] = true
+				ctes = append(ctes, name)
+			}
+			continue
+		}
+		if !keyword(i, "FROM") && !keyword(i, "JOIN") {
+			continue
+		}
+		for j := i + 1; isName(j); {
+			// schema.table or db.schema.table: keep the last part
+			for isName(j) && punct(j+1, ".") && isName(j+2) {
+				j += 2
+			}
+			name := strings.ToLower(toks[j].text)
+			if !strings.HasPrefix(name, "@") && !strings.HasPrefix(name, "#") && !seenTable[name] {
+				seenTable[name] = true
+				tables = append(tables, name)
+			}
+			j++
+			if keyword(j, "AS") {
+				j++
+			}
+			if isName(j) && (toks[j].quoted || !sqlClauseKeywords[strings.ToUpper(toks[j].text)]) {
+				j++
+			}
+			// FROM a, b lists more tables
+			if !keyword(i, "FROM") || !punct(j, ",") {
+				break
+			}
+			j++
+		}
+	}
+	return tables, ctes
+}
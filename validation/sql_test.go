@@ -0,0 +1,78 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSQLStatementTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"select", "SELECT name FROM students WHERE grade = 10", []string{"SELECT"}},
+		{"cte", "WITH s AS (SELECT id FROM students) SELECT * FROM s", []string{"SELECT"}},
+		{"union and subquery", "(SELECT 1) UNION ALL SELECT id FROM (SELECT id FROM t) x WHERE EXISTS (SELECT 1)", []string{"SELECT"}},
+		{"offset fetch", "SELECT id FROM t ORDER BY id OFFSET 10 ROWS FETCH NEXT 10 ROWS ONLY", []string{"SELECT"}},
+		{"table hint", "SELECT id FROM t WITH (NOLOCK)", []string{"SELECT"}},
+		{"keywords in literals and comments", "SELECT 'DROP TABLE t; DELETE' AS [update] -- EXEC x\n/* SET */ FROM t", []string{"SELECT"}},
+		{"select into", "SELECT * INTO students_copy FROM students", []string{"SELECT", "CREATE"}},
+		{"insert select", "INSERT INTO t (a) SELECT a FROM s", []string{"INSERT", "SELECT"}},
+		{"update set", "UPDATE t SET a = 1 WHERE id = 2", []string{"UPDATE"}},
+		{"merge", "MERGE INTO t USING s ON t.id = s.id WHEN MATCHED THEN UPDATE SET a = s.a WHEN NOT MATCHED THEN INSERT (a) VALUES (s.a);", []string{"MERGE", "UPDATE", "INSERT"}},
+		{"referential actions", "CREATE TABLE t (id int REFERENCES p(id) ON DELETE SET NULL ON UPDATE CASCADE)", []string{"CREATE"}},
+		{"execute", "EXECUTE sp_who", []string{"EXEC"}},
+		{"write without semicolon", "SELECT 1 DELETE FROM t", []string{"SELECT", "DELETE"}},
+		{"writetext", "SELECT 1; WRITETEXT t.c @ptr 'x'", []string{"SELECT", "WRITETEXT"}},
+		{"updatetext without semicolon", "SELECT 1 UPDATETEXT t.c @ptr 0 NULL 'x'", []string{"SELECT", "UPDATETEXT"}},
+		{"disable trigger", "SELECT 1 DISABLE TRIGGER trg ON t", []string{"SELECT", "DISABLE"}},
+		{"reconfigure", "SELECT 1; RECONFIGURE", []string{"SELECT", "RECONFIGURE"}},
+		{"waitfor", "SELECT 1 WAITFOR DELAY '00:10:00'", []string{"SELECT", "WAITFOR"}},
+		{"declare and set", "DECLARE @x int SET @x = 1 SELECT @x", []string{"DECLARE", "SET", "SELECT"}},
+		{"set option", "SET NOCOUNT ON; SELECT 1", []string{"SET", "SELECT"}},
+		{"unknown leading verb", "SELECT 1; THROW 50000, 'x', 1", []string{"SELECT", "THROW"}},
+		{"if", "IF 1 = 1 SELECT 1", []string{"IF", "SELECT"}},
+		{"comments only", "-- SELECT 1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SQLStatementTypes(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SQLStatementTypes(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSQLStatementsSelectOnly(t *testing.T) {
+	allowed := []string{"SELECT"}
+	valid := []string{
+		"SELECT * FROM students",
+		"WITH s AS (SELECT id FROM students) SELECT * FROM s ORDER BY id OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY",
+		"select 1; select 2;",
+	}
+	for _, q := range valid {
+		if err := CheckSQLStatements(q, allowed); err != nil {
+			t.Errorf("CheckSQLStatements(%q) = %v, want nil", q, err)
+		}
+	}
+	invalid := []string{
+		"",
+		"SELECT 1; WRITETEXT t.c @ptr 'x'",
+		"SELECT 1 UPDATETEXT t.c @ptr 0 NULL 'x'",
+		"SELECT 1 DISABLE TRIGGER trg ON t",
+		"SELECT 1; RECONFIGURE",
+		"SELECT 1 WAITFOR DELAY '00:10:00'",
+		"DECLARE @x int; SELECT 1",
+		"SELECT 1; SET ROWCOUNT 0",
+		"SELECT 1; THROW 50000, 'x', 1",
+		"SELECT * INTO copy FROM students",
+		"SELECT 1 EXEC xp_cmdshell 'dir'",
+		"SELECT 1; 'literal'",
+	}
+	for _, q := range invalid {
+		if err := CheckSQLStatements(q, allowed); err == nil {
+			t.Errorf("CheckSQLStatements(%q) = nil, want an error", q)
+		}
+	}
+}
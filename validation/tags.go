@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tag limits; tags are short labels, not descriptions
+const (
+	MaxTags      = 20
+	maxTagLength = 32
+)
+
+// NormalizeTags trims and lower-cases tags, drops blanks and duplicates and sorts the rest,
+// so "Finance", " finance " and "FINANCE" are one tag. It errors on too many or too long tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	if len(out) > MaxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", MaxTags)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// HasTag reports whether tags contains tag (compared the way NormalizeTags stores them)
+func HasTag(tags []string, tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
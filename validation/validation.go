@@ -163,70 +163,68 @@ func IsValidPrompt(prompt string) bool {
 
 // isRepeatedCharacters checks if a string is just repeated characters
 func isRepeatedCharacters(s string) bool {
-	if len(s) < 3 {
+	runes := []rune(s)
+	if len(runes) < 3 {
 		return false
 	}
-	firstChar := s[0]
-	for i := 1; i < len(s); i++ {
-		if s[i] != firstChar {
+	for _, r := range runes[1:] {
+		if r != runes[0] {
 			return false
 		}
 	}
 	return true
 }
 
-// hasExcessiveRepetition checks for patterns like "aaaa", "1111", "ababab"
+// hasExcessiveRepetition checks for patterns like "aaaa", "1111", "ababab".
+// It works on runes, so a multi-byte character counts once and patterns never split one.
 func hasExcessiveRepetition(s string) bool {
+	runes := []rune(s)
 	// Check for 4+ consecutive identical characters using a simpler approach
 	// Instead of backreferences, check character by character
-	if len(s) < 4 {
+	if len(runes) < 4 {
 		return false
 	}
 	
 	// Check for 4+ consecutive identical characters
-	for i := 0; i <= len(s)-4; i++ {
-		char := s[i]
-		count := 1
-		for j := i + 1; j < len(s) && s[j] == char; j++ {
+	count := 1
+	for i := 1; i < len(runes); i++ {
+		if runes[i] == runes[i-1] {
 			count++
-		}
-		if count >= 4 {
-			return true
-		}
-	}
-	
-	// Check for simple repeating patterns (2-3 char patterns repeated 4+ times)
-	// Check 2-char patterns
-	for i := 0; i <= len(s)-8; i++ {
-		if len(s)-i >= 8 {
-			pattern := s[i : i+2]
-			repeats := 1
-			for j := i + 2; j <= len(s)-2 && s[j:j+2] == pattern; j += 2 {
-				repeats++
-			}
-			if repeats >= 4 {
+			if count >= 4 {
 				return true
 			}
+		} else {
+			count = 1
 		}
 	}
 	
-	// Check 3-char patterns
-	for i := 0; i <= len(s)-12; i++ {
-		if len(s)-i >= 12 {
-			pattern := s[i : i+3]
-			repeats := 1
-			for j := i + 3; j <= len(s)-3 && s[j:j+3] == pattern; j += 3 {
-				repeats++
-			}
-			if repeats >= 4 {
-				return true
-			}
+	// Check for simple repeating patterns (2-3 char patterns repeated 4+ times)
+	return hasRepeatedPattern(runes, 2) || hasRepeatedPattern(runes, 3)
+}
+
+// hasRepeatedPattern reports whether some run of size runes repeats 4+ times back to back
+func hasRepeatedPattern(runes []rune, size int) bool {
+	for i := 0; i+4*size <= len(runes); i++ {
+		repeats := 1
+		for j := i + size; j+size <= len(runes) && equalRunes(runes[j:j+size], runes[i:i+size]); j += size {
+			repeats++
+		}
+		if repeats >= 4 {
+			return true
 		}
 	}
-	
 	return false
 }
 
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // hasTooManySpecialChars checks if more than 50% of characters are special
 func hasTooManySpecialChars(s string) bool {
 	specialCount := 0
@@ -263,7 +261,7 @@ func isGibberishPattern(s string) bool {
 	}
 	
 	for _, pattern := range keyboardPatterns {
-		if strings.Contains(lower, pattern) && len(s) < 20 {
+		if strings.Contains(lower, pattern) && len(lower) < 20 {
 			// If the string is mostly this pattern, it's gibberish
 			if strings.Count(lower, pattern)*len(pattern) > len(lower)/2 {
 				return true
@@ -287,7 +285,7 @@ func hasKeyboardMashing(s string) bool {
 	for _, pattern := range mashingPatterns {
 		if strings.Contains(lower, pattern) {
 			// If the string is short and contains these patterns, likely mashing
-			if len(s) < 30 {
+			if len(lower) < 30 {
 				return true
 			}
 		}
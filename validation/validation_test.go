@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzIsValidPrompt(f *testing.F) {
+	seeds := []string{
+		"",
+		"   ",
+		"hi",
+		"Show me all students in grade 10",
+		"asdfghjkl qwerty",
+		"aaaaaaaaaaaa",
+		"!!!???!!!",
+		"ééé",
+		"éaéaéaéa",
+		"显示所有学生的成绩",
+		"学生学生学生学生",
+		"Показать всех студентов",
+		"🙂🙂🙂🙂",
+		"👨‍👩‍👧 family report for 👩🏽‍🏫 teachers",
+		"ééé combining marks",
+		"​​ zero width",
+		"\xff\xfe\xfd invalid utf-8",
+		"a\x00b\x00c",
+		strings.Repeat("ab", 5001),
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, prompt string) {
+		first := IsValidPrompt(prompt)
+		if again := IsValidPrompt(prompt); again != first {
+			t.Fatalf("IsValidPrompt(%q) is not deterministic: %v then %v", prompt, first, again)
+		}
+		if strings.TrimSpace(prompt) == "" && first {
+			t.Fatalf("IsValidPrompt(%q) accepted a blank prompt", prompt)
+		}
+	})
+}
+
+func TestRepetitionChecksAreRuneAware(t *testing.T) {
+	tests := []struct {
+		in             string
+		wantRepetition bool
+		wantRepeated   bool
+	}{
+		{"ééé", false, true},
+		{"éééé", true, true},
+		{"学生学生学生学生", true, false},
+		{"学生成绩报告表格", false, false},
+		{"🙂🙂🙂🙂", true, true},
+		{"🙂🙃🙂🙃", false, false},
+		{"显示 🙂 学生的成绩", false, false},
+	}
+	for _, tt := range tests {
+		if got := hasExcessiveRepetition(tt.in); got != tt.wantRepetition {
+			t.Errorf("hasExcessiveRepetition(%q) = %v, want %v", tt.in, got, tt.wantRepetition)
+		}
+		if got := isRepeatedCharacters(tt.in); got != tt.wantRepeated {
+			t.Errorf("isRepeatedCharacters(%q) = %v, want %v", tt.in, got, tt.wantRepeated)
+		}
+	}
+}